@@ -4,8 +4,19 @@ kafka-topics --bootstrap-server kafka:9092 --list
 
 echo -e 'Creating kafka topics (if necessary)'
 topics='user.registration.req user.login.req user.info.req user.registrations user.logins user.infos
-ingredients.new ingredients.req ingredients
-recipes.new recipes.req recipes
+user.role.req user.roles
+user.deletion.req user.deletion.acks user.deleted user.deletions
+user.export.req user.export.parts user.export.started user.exports
+user.profile.req user.profile.update.req user.profiles
+ingredients.new ingredients.update ingredients.req ingredients
+ingredients.suggestions.new ingredients.suggestions.review ingredients.suggestions.req ingredients.suggestions
+recipes.new recipes.update recipes.revert recipes.fork recipes.scale recipes.req recipes
+recipes.revisions.req recipes.revisions
+recipes.ratings.new recipes.ratings.req recipes.ratings
+recipes.comments.new recipes.comments.update recipes.comments.delete recipes.comments.req recipes.comments recipes.comments.created
+recipes.favorites.update recipes.favorites.req recipes.favorites
+recipes.collections.new recipes.collections.update recipes.collections.items recipes.collections.delete recipes.collections.req recipes.collections
+recipes.categories.new recipes.categories.delete recipes.categories.req recipes.categories
 nutritionfacts'
 for topic in $topics; do
     kafka-topics --bootstrap-server kafka:9092 --create --if-not-exists --topic "$topic" --replication-factor 1 --partitions 1
@@ -11,9 +11,12 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/config"
+	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/controller/http"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/controller/kafka"
+	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/health"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient/service"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient/storage/mongodb"
+	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/tracing"
 )
 
 func main() {
@@ -30,6 +33,11 @@ func main() {
 	}
 	log.Logger = log.Logger.Level(logLevel)
 
+	shutdownTracing, err := tracing.Init(config.Config.Tracing.Exporter, config.Config.Tracing.OTLPEndpoint, config.Config.Tracing.File)
+	if err != nil {
+		log.Fatal().Err(err).Msg("could not initialize tracing")
+	}
+
 	stor, err := mongodb.NewStorage(config.Config.Mongo.DSN, config.Config.Mongo.DB)
 	if err != nil {
 		log.Fatal().Err(err).Msg("could not initialize storage")
@@ -38,7 +46,7 @@ func main() {
 
 	ingredientService := service.NewService(stor)
 
-	controller, err := kafka.NewController(ingredientService, config.Config.Kafka.Brokers)
+	controller, err := kafka.NewController(ingredientService, config.Config.Kafka.Brokers, config.Config.Kafka.TrustedServices)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize kafka controller")
 	}
@@ -52,6 +60,20 @@ func main() {
 		}
 	}()
 
+	checker := health.NewChecker()
+	checker.AddLivenessCheck("kafka", controller.Live)
+	checker.AddReadinessCheck("kafka", controller.Ready)
+	checker.AddReadinessCheck("mongodb", stor.Ping)
+
+	httpController := http.NewController(config.Config.HTTP.Address, checker)
+	go func() {
+		err := httpController.Run(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error running http server")
+		}
+	}()
+	log.Info().Msgf("serving metrics and health checks on %s", config.Config.HTTP.Address)
+
 	terminateSignal := make(chan os.Signal, 1)
 	signal.Notify(terminateSignal, syscall.SIGINT, syscall.SIGTERM)
 
@@ -61,6 +83,16 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("controller failed to stop properly")
 	}
+	err = httpController.Stop()
+	if err != nil {
+		log.Fatal().Err(err).Msg("http server failed to stop properly")
+	}
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	err = shutdownTracing(shutdownCtx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to flush traces")
+	}
 
 	log.Info().Msg("ingredient service interrupted via system signal")
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/rs/zerolog/log"
 	"github.com/segmentio/kafka-go"
+	apperror "github.com/tony-spark/recipetor-backend/ingredient-service/internal/errors"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient/service"
 	"io"
@@ -30,6 +31,10 @@ func NewAddIngredientWorker(ingredientService service.Service, brokers []string)
 	}, nil
 }
 
+func (w AddIngredientWorker) Name() string {
+	return workerName(w.newIngredientsReader)
+}
+
 func (w AddIngredientWorker) Process(ctx context.Context) error {
 	for {
 		select {
@@ -39,7 +44,7 @@ func (w AddIngredientWorker) Process(ctx context.Context) error {
 		}
 
 		var dto ingredient.CreateIngredientDTO
-		corID, err := readDTO(ctx, w.newIngredientsReader, &dto)
+		msgCtx, corID, err := readDTO(ctx, w.newIngredientsReader, &dto)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				return err
@@ -48,27 +53,41 @@ func (w AddIngredientWorker) Process(ctx context.Context) error {
 		}
 		log.Info().Msgf("got CreateIngredientDTO: %+v", dto)
 
-		cntx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		id, err := w.ingredientService.Create(cntx, dto)
-		ingredientDTO := ingredient.IngredientDTO{
-			Name: dto.Name,
-			ID:   id,
-		}
-		cancel()
-		if err != nil {
-			log.Error().Err(err).Msg("failed to add ingredient")
-			ingredientDTO.Error = err.Error()
-		} else {
-			ingredientDTO.Ingredient = ingredient.Ingredient{
-				ID:             id,
-				Name:           dto.Name,
-				BaseUnit:       dto.BaseUnit,
-				NutritionFacts: dto.NutritionFacts,
+		handleMessage(msgCtx, w.newIngredientsReader, func() error {
+			var id string
+			var err error
+			if !canCurate(msgCtx) {
+				err = apperror.ErrForbidden
+			} else {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				id, err = w.ingredientService.Create(cntx, dto)
+				cancel()
+			}
+			ingredientDTO := ingredient.IngredientDTO{
+				Name: dto.Name,
+				ID:   id,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to add ingredient")
+				ingredientDTO.Error = err.Error()
+			} else {
+				ingredientDTO.Ingredient = ingredient.Ingredient{
+					ID:             id,
+					Name:           dto.Name,
+					BaseUnit:       dto.BaseUnit,
+					NutritionFacts: dto.NutritionFacts,
+				}
 			}
-		}
 
-		write(w.ingredientsWriter, dto.Name, ingredientDTO, corID)
-		log.Info().Msgf("sent IngredientDTO: %+v", ingredientDTO)
+			write(msgCtx, w.ingredientsWriter, dto.Name, ingredientDTO, corID)
+			log.Info().Msgf("sent IngredientDTO: %+v", ingredientDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.ingredientsWriter, dto.Name, ingredient.IngredientDTO{
+				Name:  dto.Name,
+				Error: err.Error(),
+			}, corID)
+		})
 	}
 }
 
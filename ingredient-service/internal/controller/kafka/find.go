@@ -30,6 +30,10 @@ func NewFindIngredientsWorker(ingredientService service.Service, brokers []strin
 	}, nil
 }
 
+func (w FindIngredientsWorker) Name() string {
+	return workerName(w.ingredientsReqReader)
+}
+
 func (w FindIngredientsWorker) Process(ctx context.Context) error {
 	for {
 		select {
@@ -39,7 +43,7 @@ func (w FindIngredientsWorker) Process(ctx context.Context) error {
 		}
 
 		var dto ingredient.FindIngredientsDTO
-		corID, err := readDTO(ctx, w.ingredientsReqReader, &dto)
+		msgCtx, corID, err := readDTO(ctx, w.ingredientsReqReader, &dto)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				return err
@@ -47,51 +51,73 @@ func (w FindIngredientsWorker) Process(ctx context.Context) error {
 			continue
 		}
 		log.Info().Msgf("got FindIngredientsDTO: %+v", dto)
+		handleMessage(msgCtx, w.ingredientsReqReader, func() error {
+			var handleErr error
 
-		if len(dto.ID) > 0 {
-			cntx, cancel := context.WithTimeout(ctx, 5*time.Second)
-			ingr, err := w.ingredientService.GetByID(cntx, dto.ID)
-			cancel()
-
-			ingredientDTO := ingredient.IngredientDTO{
-				ID: dto.ID,
-			}
-			if err != nil {
-				log.Error().Err(err).Msg("failed to find ingredient")
-				ingredientDTO.Error = err.Error()
-			} else {
-				ingredientDTO.Ingredient = ingr
-			}
-
-			write(w.ingredientsWriter, dto.ID, ingredientDTO, corID)
-			log.Info().Msgf("sent IngredientDTO: %+v", ingredientDTO)
-		}
-
-		if len(dto.NameQuery) > 0 {
-			cntx, cancel := context.WithTimeout(ctx, 5*time.Second)
-			ingredients, err := w.ingredientService.SearchByName(cntx, dto.NameQuery)
-			cancel()
+			if len(dto.ID) > 0 {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				ingr, err := w.ingredientService.GetByID(cntx, dto.ID)
+				cancel()
 
-			if err != nil {
-				log.Error().Err(err).Msg("failed to find ingredients")
 				ingredientDTO := ingredient.IngredientDTO{
-					Error:     err.Error(),
-					NameQuery: dto.NameQuery,
+					ID: dto.ID,
 				}
-				write(w.ingredientsWriter, dto.NameQuery, ingredientDTO, corID)
+				if err != nil {
+					log.Error().Err(err).Msg("failed to find ingredient")
+					handleErr = err
+					ingredientDTO.Error = err.Error()
+				} else {
+					ingredientDTO.Ingredient = ingr
+				}
+
+				write(msgCtx, w.ingredientsWriter, dto.ID, ingredientDTO, corID)
 				log.Info().Msgf("sent IngredientDTO: %+v", ingredientDTO)
 			}
 
-			for _, ingr := range ingredients {
-				ingredientDTO := ingredient.IngredientDTO{
-					Ingredient: ingr,
-					NameQuery:  dto.NameQuery,
+			if len(dto.NameQuery) > 0 {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				ingredients, err := w.ingredientService.SearchByName(cntx, dto.NameQuery)
+				cancel()
+
+				if err != nil {
+					log.Error().Err(err).Msg("failed to find ingredients")
+					handleErr = err
+					ingredientDTO := ingredient.IngredientDTO{
+						Error:     err.Error(),
+						NameQuery: dto.NameQuery,
+						Stream:    streamDone(0),
+					}
+					write(msgCtx, w.ingredientsWriter, dto.NameQuery, ingredientDTO, corID)
+					log.Info().Msgf("sent IngredientDTO: %+v", ingredientDTO)
+				} else {
+					for i, ingr := range ingredients {
+						ingredientDTO := ingredient.IngredientDTO{
+							Ingredient: ingr,
+							NameQuery:  dto.NameQuery,
+							Stream:     streamPart(i, len(ingredients)),
+						}
+						write(msgCtx, w.ingredientsWriter, dto.NameQuery, ingredientDTO, corID)
+						log.Info().Msgf("sent IngredientDTO: %+v", ingredientDTO)
+					}
+					write(msgCtx, w.ingredientsWriter, dto.NameQuery, ingredient.IngredientDTO{
+						NameQuery: dto.NameQuery,
+						Stream:    streamDone(len(ingredients)),
+					}, corID)
 				}
-				write(w.ingredientsWriter, dto.NameQuery, ingredientDTO, corID)
-				log.Info().Msgf("sent IngredientDTO: %+v", ingredientDTO)
 			}
 
-		}
+			return handleErr
+		}, func(err error) {
+			key := dto.ID
+			if len(key) == 0 {
+				key = dto.NameQuery
+			}
+			write(msgCtx, w.ingredientsWriter, key, ingredient.IngredientDTO{
+				ID:        dto.ID,
+				NameQuery: dto.NameQuery,
+				Error:     err.Error(),
+			}, corID)
+		})
 	}
 }
 
@@ -4,22 +4,32 @@ import (
 	"context"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/controller"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient/service"
-	"golang.org/x/sync/errgroup"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/go-multierror"
 )
 
 type kafkaController struct {
 	workers []Worker
+	brokers []string
+	// trustedServices may make requests without user headers
+	trustedServices []string
+	states          *workerStates
+	// stop is closed when controller is stopped, so failed workers are not restarted
+	stop     chan struct{}
+	stopOnce *sync.Once
 }
 
 type Worker interface {
+	Name() string
 	Process(ctx context.Context) error
 	Stop() error
 }
 
-func NewController(ingredientService service.Service, kafkaBrokerURLs string) (controller.Controller, error) {
+// NewController creates controller, trustedServices is comma separated list of services allowed to make requests
+// without user headers
+func NewController(ingredientService service.Service, kafkaBrokerURLs string, trustedServices string) (controller.MonitoredController, error) {
 	brokers := strings.Split(kafkaBrokerURLs, ",")
 
 	var workers []Worker
@@ -35,24 +45,67 @@ func NewController(ingredientService service.Service, kafkaBrokerURLs string) (c
 	}
 	workers = append(workers, findIngredientsWorker)
 
+	updateIngredientWorker, err := NewUpdateIngredientWorker(ingredientService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, updateIngredientWorker)
+
+	suggestIngredientWorker, err := NewSuggestIngredientWorker(ingredientService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, suggestIngredientWorker)
+
+	reviewSuggestionWorker, err := NewReviewSuggestionWorker(ingredientService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, reviewSuggestionWorker)
+
+	findSuggestionsWorker, err := NewFindSuggestionsWorker(ingredientService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, findSuggestionsWorker)
+
+	var trusted []string
+	for _, name := range strings.Split(trustedServices, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) > 0 {
+			trusted = append(trusted, name)
+		}
+	}
+
 	return kafkaController{
-		workers: workers,
+		workers:         workers,
+		brokers:         brokers,
+		trustedServices: trusted,
+		states:          newWorkerStates(workers),
+		stop:            make(chan struct{}),
+		stopOnce:        &sync.Once{},
 	}, nil
 }
 
+// Run runs every worker under supervision, failed workers are restarted without affecting others
 func (k kafkaController) Run(ctx context.Context) error {
-	group, ctx := errgroup.WithContext(ctx)
-
+	ctx = withTrustedServices(ctx, k.trustedServices)
+	var wg sync.WaitGroup
 	for _, w := range k.workers {
-		worker := w
-		group.Go(func() error {
-			return worker.Process(ctx)
-		})
+		wg.Add(1)
+		go func(worker Worker) {
+			defer wg.Done()
+			k.supervise(ctx, worker)
+		}(w)
 	}
-	return group.Wait()
+	wg.Wait()
+	return nil
 }
 
 func (k kafkaController) Stop() error {
+	k.stopOnce.Do(func() {
+		close(k.stop)
+	})
 	var result error
 	for _, w := range k.workers {
 		err := w.Stop()
@@ -62,3 +115,22 @@ func (k kafkaController) Stop() error {
 	}
 	return result
 }
+
+// Live fails if any worker has stopped processing messages and will not be restarted
+func (k kafkaController) Live(ctx context.Context) error {
+	return k.states.check(WorkerStarting, WorkerRunning, WorkerRestarting)
+}
+
+// Ready fails if any worker is not running or none of kafka brokers is reachable
+func (k kafkaController) Ready(ctx context.Context) error {
+	var result error
+	err := k.states.check(WorkerRunning)
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
+	err = pingBrokers(ctx, k.brokers)
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
+	return result
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/controller"
+	apperror "github.com/tony-spark/recipetor-backend/ingredient-service/internal/errors"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient/service"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient/storage"
@@ -21,6 +22,9 @@ import (
 	"time"
 )
 
+// testService is trusted by controller under test to make requests without user headers
+const testService = "test-client"
+
 type ControllerTestSuite struct {
 	suite.Suite
 
@@ -39,7 +43,17 @@ func (suite *ControllerTestSuite) TestController() {
 	suite.Run("create ingredient and find by id", func() {
 		newIngredientDTO := suite.randomCreateIngredient()
 		corID := generateCorrelationID()
-		write(suite.newIngredientWriter, newIngredientDTO.Name, newIngredientDTO, corID)
+		bs, err := json.Marshal(newIngredientDTO)
+		suite.Require().NoError(err)
+		err = suite.newIngredientWriter.WriteMessages(context.Background(), kafka.Message{
+			Key:   []byte(newIngredientDTO.Name),
+			Value: bs,
+			Headers: []kafka.Header{
+				{Key: KeyCorrelationID, Value: []byte(corID)},
+				{Key: KeyService, Value: []byte(testService)},
+			},
+		})
+		suite.Require().NoError(err)
 
 		var createdDTO ingredient.IngredientDTO
 		{
@@ -65,7 +79,7 @@ func (suite *ControllerTestSuite) TestController() {
 		findDTO := ingredient.FindIngredientsDTO{
 			ID: createdDTO.ID,
 		}
-		write(suite.reqIngredientsWriter, findDTO.ID, findDTO, corID)
+		write(context.Background(), suite.reqIngredientsWriter, findDTO.ID, findDTO, corID)
 
 		{
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -86,7 +100,87 @@ func (suite *ControllerTestSuite) TestController() {
 			}
 		}
 	})
+	suite.Run("search by name ends with completion message", func() {
+		findDTO := ingredient.FindIngredientsDTO{
+			NameQuery: suite.rand.RandomString(12),
+		}
+		corID := generateCorrelationID()
+		write(context.Background(), suite.reqIngredientsWriter, findDTO.NameQuery, findDTO, corID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.ingredientsReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
+
+			var gotDTO ingredient.IngredientDTO
+			err = json.Unmarshal(message.Value, &gotDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Empty(suite.T(), gotDTO.Error)
+			require.NotNil(suite.T(), gotDTO.Stream)
+			assert.True(suite.T(), gotDTO.Stream.Done)
+			assert.Equal(suite.T(), 0, gotDTO.Stream.Total)
+			break
+		}
+	})
+	suite.Run("user can not create ingredient", func() {
+		newIngredientDTO := suite.randomCreateIngredient()
+		corID := generateCorrelationID()
+		bs, err := json.Marshal(newIngredientDTO)
+		suite.Require().NoError(err)
+		err = suite.newIngredientWriter.WriteMessages(context.Background(), kafka.Message{
+			Key:   []byte(newIngredientDTO.Name),
+			Value: bs,
+			Headers: []kafka.Header{
+				{Key: KeyCorrelationID, Value: []byte(corID)},
+				{Key: KeyUserID, Value: []byte(suite.rand.RandomObjectID())},
+				{Key: KeyUserRole, Value: []byte("user")},
+			},
+		})
+		suite.Require().NoError(err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.ingredientsReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
+
+			var createdDTO ingredient.IngredientDTO
+			err = json.Unmarshal(message.Value, &createdDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Equal(suite.T(), apperror.ErrForbidden.Error(), createdDTO.Error)
+			assert.Empty(suite.T(), createdDTO.ID)
+			break
+		}
+	})
+	suite.Run("request without role from untrusted client can not create ingredient", func() {
+		newIngredientDTO := suite.randomCreateIngredient()
+		corID := generateCorrelationID()
+		write(context.Background(), suite.newIngredientWriter, newIngredientDTO.Name, newIngredientDTO, corID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.ingredientsReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
 
+			var createdDTO ingredient.IngredientDTO
+			err = json.Unmarshal(message.Value, &createdDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Equal(suite.T(), apperror.ErrForbidden.Error(), createdDTO.Error)
+			assert.Empty(suite.T(), createdDTO.ID)
+			break
+		}
+	})
 }
 
 func (suite *ControllerTestSuite) SetupSuite() {
@@ -102,7 +196,8 @@ func (suite *ControllerTestSuite) SetupSuite() {
 
 	var err error
 
-	err = createTopics(kafkaBroker, TopicIngredients, TopicIngredientsReq, TopicIngredientsNew)
+	err = createTopics(kafkaBroker, TopicIngredients, TopicIngredientsReq, TopicIngredientsNew, TopicIngredientsUpdate,
+		TopicSuggestionsNew, TopicSuggestionsReview, TopicSuggestionsReq, TopicSuggestions)
 	suite.Require().NoError(err)
 
 	{
@@ -110,7 +205,7 @@ func (suite *ControllerTestSuite) SetupSuite() {
 		stor, suite.cleanupFunc, err = mongodb.NewTestStorage(dsn, "test")
 		suite.Require().NoError(err)
 
-		suite.controller, err = NewController(service.NewService(stor), kafkaBroker)
+		suite.controller, err = NewController(service.NewService(stor), kafkaBroker, testService)
 		suite.Require().NoError(err)
 	}
 
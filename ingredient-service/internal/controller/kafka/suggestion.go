@@ -0,0 +1,274 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	apperror "github.com/tony-spark/recipetor-backend/ingredient-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient"
+	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient/service"
+	"io"
+	"time"
+)
+
+type SuggestIngredientWorker struct {
+	ingredientService    service.Service
+	newSuggestionsReader *kafka.Reader
+	suggestionsWriter    *kafka.Writer
+}
+
+func NewSuggestIngredientWorker(ingredientService service.Service, brokers []string) (Worker, error) {
+	newSuggestionsReader, err := newReader(brokers, "ingredients-service-suggest", TopicSuggestionsNew)
+	if err != nil {
+		return nil, err
+	}
+	suggestionsWriter := newWriter(brokers, TopicSuggestions)
+	return SuggestIngredientWorker{
+		ingredientService:    ingredientService,
+		newSuggestionsReader: newSuggestionsReader,
+		suggestionsWriter:    suggestionsWriter,
+	}, nil
+}
+
+func (w SuggestIngredientWorker) Name() string {
+	return workerName(w.newSuggestionsReader)
+}
+
+func (w SuggestIngredientWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto ingredient.SuggestIngredientDTO
+		msgCtx, corID, err := readDTO(ctx, w.newSuggestionsReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got SuggestIngredientDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.newSuggestionsReader, func() error {
+			userID, _ := header(msgCtx, KeyUserID)
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			id, err := w.ingredientService.Suggest(cntx, dto, userID)
+			var suggestion ingredient.Suggestion
+			if err == nil {
+				suggestion, err = w.ingredientService.GetSuggestion(cntx, id)
+			}
+			cancel()
+			suggestionDTO := ingredient.SuggestionDTO{
+				ID: id,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to add suggestion")
+				suggestionDTO.Error = err.Error()
+			} else {
+				suggestionDTO.Suggestion = suggestion
+			}
+
+			write(msgCtx, w.suggestionsWriter, id, suggestionDTO, corID)
+			log.Info().Msgf("sent SuggestionDTO: %+v", suggestionDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.suggestionsWriter, "", ingredient.SuggestionDTO{
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w SuggestIngredientWorker) Stop() error {
+	return closeAll(w.newSuggestionsReader, w.suggestionsWriter)
+}
+
+type ReviewSuggestionWorker struct {
+	ingredientService service.Service
+	reviewReqReader   *kafka.Reader
+	suggestionsWriter *kafka.Writer
+}
+
+func NewReviewSuggestionWorker(ingredientService service.Service, brokers []string) (Worker, error) {
+	reviewReqReader, err := newReader(brokers, "ingredients-service-review", TopicSuggestionsReview)
+	if err != nil {
+		return nil, err
+	}
+	suggestionsWriter := newWriter(brokers, TopicSuggestions)
+	return ReviewSuggestionWorker{
+		ingredientService: ingredientService,
+		reviewReqReader:   reviewReqReader,
+		suggestionsWriter: suggestionsWriter,
+	}, nil
+}
+
+func (w ReviewSuggestionWorker) Name() string {
+	return workerName(w.reviewReqReader)
+}
+
+func (w ReviewSuggestionWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto ingredient.ReviewSuggestionDTO
+		msgCtx, corID, err := readDTO(ctx, w.reviewReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got ReviewSuggestionDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.reviewReqReader, func() error {
+			var suggestion ingredient.Suggestion
+			var err error
+			if !canCurate(msgCtx) {
+				err = apperror.ErrForbidden
+			} else {
+				userID, _ := header(msgCtx, KeyUserID)
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				suggestion, err = w.ingredientService.ReviewSuggestion(cntx, dto, userID)
+				cancel()
+			}
+			suggestionDTO := ingredient.SuggestionDTO{
+				ID: dto.ID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to review suggestion")
+				suggestionDTO.Error = err.Error()
+			} else {
+				suggestionDTO.Suggestion = suggestion
+			}
+
+			write(msgCtx, w.suggestionsWriter, dto.ID, suggestionDTO, corID)
+			log.Info().Msgf("sent SuggestionDTO: %+v", suggestionDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.suggestionsWriter, dto.ID, ingredient.SuggestionDTO{
+				ID:    dto.ID,
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w ReviewSuggestionWorker) Stop() error {
+	return closeAll(w.reviewReqReader, w.suggestionsWriter)
+}
+
+type FindSuggestionsWorker struct {
+	ingredientService    service.Service
+	suggestionsReqReader *kafka.Reader
+	suggestionsWriter    *kafka.Writer
+}
+
+func NewFindSuggestionsWorker(ingredientService service.Service, brokers []string) (Worker, error) {
+	suggestionsReqReader, err := newReader(brokers, "ingredients-service-find-suggestions", TopicSuggestionsReq)
+	if err != nil {
+		return nil, err
+	}
+	suggestionsWriter := newWriter(brokers, TopicSuggestions)
+	return FindSuggestionsWorker{
+		ingredientService:    ingredientService,
+		suggestionsReqReader: suggestionsReqReader,
+		suggestionsWriter:    suggestionsWriter,
+	}, nil
+}
+
+func (w FindSuggestionsWorker) Name() string {
+	return workerName(w.suggestionsReqReader)
+}
+
+func (w FindSuggestionsWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto ingredient.FindSuggestionsDTO
+		msgCtx, corID, err := readDTO(ctx, w.suggestionsReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got FindSuggestionsDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.suggestionsReqReader, func() error {
+			var suggestions []ingredient.Suggestion
+			var err error
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			if len(dto.ID) > 0 {
+				var suggestion ingredient.Suggestion
+				suggestion, err = w.ingredientService.GetSuggestion(cntx, dto.ID)
+				// users see only their own suggestions
+				userID, _ := header(msgCtx, KeyUserID)
+				if err == nil && !canCurate(msgCtx) && (len(userID) == 0 || suggestion.SuggestedBy != userID) {
+					err = apperror.ErrForbidden
+				}
+				suggestions = append(suggestions, suggestion)
+			} else if !canCurate(msgCtx) {
+				err = apperror.ErrForbidden
+			} else {
+				suggestions, err = w.ingredientService.PendingSuggestions(cntx)
+			}
+			cancel()
+
+			// list of pending suggestions is sent as stream
+			var stream *ingredient.StreamPart
+			if len(dto.ID) == 0 {
+				stream = streamDone(0)
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to find suggestions")
+				suggestionDTO := ingredient.SuggestionDTO{
+					ID:     dto.ID,
+					Stream: stream,
+					Error:  err.Error(),
+				}
+				write(msgCtx, w.suggestionsWriter, dto.ID, suggestionDTO, corID)
+				log.Info().Msgf("sent SuggestionDTO: %+v", suggestionDTO)
+				return err
+			}
+
+			for i, suggestion := range suggestions {
+				suggestionDTO := ingredient.SuggestionDTO{
+					Suggestion: suggestion,
+					ID:         suggestion.ID,
+				}
+				if stream != nil {
+					suggestionDTO.Stream = streamPart(i, len(suggestions))
+				}
+				write(msgCtx, w.suggestionsWriter, suggestion.ID, suggestionDTO, corID)
+				log.Info().Msgf("sent SuggestionDTO: %+v", suggestionDTO)
+			}
+			if stream != nil {
+				write(msgCtx, w.suggestionsWriter, "", ingredient.SuggestionDTO{
+					Stream: streamDone(len(suggestions)),
+				}, corID)
+			}
+			return nil
+		}, func(err error) {
+			write(msgCtx, w.suggestionsWriter, dto.ID, ingredient.SuggestionDTO{
+				ID:    dto.ID,
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w FindSuggestionsWorker) Stop() error {
+	return closeAll(w.suggestionsReqReader, w.suggestionsWriter)
+}
@@ -1,7 +1,12 @@
 package kafka
 
 const (
-	TopicIngredientsNew = "ingredients.new"
-	TopicIngredientsReq = "ingredients.req"
-	TopicIngredients    = "ingredients"
+	TopicIngredientsNew    = "ingredients.new"
+	TopicIngredientsUpdate = "ingredients.update"
+	TopicIngredientsReq    = "ingredients.req"
+	TopicIngredients       = "ingredients"
+	TopicSuggestionsNew    = "ingredients.suggestions.new"
+	TopicSuggestionsReview = "ingredients.suggestions.review"
+	TopicSuggestionsReq    = "ingredients.suggestions.req"
+	TopicSuggestions       = "ingredients.suggestions"
 )
@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	apperror "github.com/tony-spark/recipetor-backend/ingredient-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient"
+	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient/service"
+	"io"
+	"time"
+)
+
+type UpdateIngredientWorker struct {
+	ingredientService service.Service
+	updateReqReader   *kafka.Reader
+	ingredientsWriter *kafka.Writer
+}
+
+func NewUpdateIngredientWorker(ingredientService service.Service, brokers []string) (Worker, error) {
+	updateReqReader, err := newReader(brokers, "ingredients-service-update", TopicIngredientsUpdate)
+	if err != nil {
+		return nil, err
+	}
+	ingredientsWriter := newWriter(brokers, TopicIngredients)
+	return UpdateIngredientWorker{
+		ingredientService: ingredientService,
+		updateReqReader:   updateReqReader,
+		ingredientsWriter: ingredientsWriter,
+	}, nil
+}
+
+func (w UpdateIngredientWorker) Name() string {
+	return workerName(w.updateReqReader)
+}
+
+func (w UpdateIngredientWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto ingredient.UpdateIngredientDTO
+		msgCtx, corID, err := readDTO(ctx, w.updateReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got UpdateIngredientDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.updateReqReader, func() error {
+			var ingr ingredient.Ingredient
+			var err error
+			if !canCurate(msgCtx) {
+				err = apperror.ErrForbidden
+			} else {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				ingr, err = w.ingredientService.Update(cntx, dto)
+				cancel()
+			}
+			ingredientDTO := ingredient.IngredientDTO{
+				Name: dto.Name,
+				ID:   dto.ID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to update ingredient")
+				ingredientDTO.Error = err.Error()
+			} else {
+				ingredientDTO.Ingredient = ingr
+			}
+
+			write(msgCtx, w.ingredientsWriter, dto.ID, ingredientDTO, corID)
+			log.Info().Msgf("sent IngredientDTO: %+v", ingredientDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.ingredientsWriter, dto.ID, ingredient.IngredientDTO{
+				Name:  dto.Name,
+				ID:    dto.ID,
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w UpdateIngredientWorker) Stop() error {
+	return closeAll(w.updateReqReader, w.ingredientsWriter)
+}
@@ -3,12 +3,20 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-multierror"
 	"github.com/rs/zerolog/log"
+	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient"
+	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/metrics"
+	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
 	"io"
 	"net"
+	"runtime/debug"
 	"strconv"
 	"time"
 
@@ -17,8 +25,75 @@ import (
 
 const (
 	KeyCorrelationID = "correlation_id"
+	// KeyUserID and KeyUserRole headers are set by gateway for requests made on behalf of user
+	KeyUserID   = "user_id"
+	KeyUserRole = "user_role"
+	// KeyService header is set by internal services to their name for requests not made on behalf of user,
+	// gateway must drop it from user requests
+	KeyService = "service"
 )
 
+// roles of user-service allowed to curate ingredient catalog
+const (
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+type headersKey struct{}
+
+// header returns value of header of the message ctx was returned for by readDTO
+func header(ctx context.Context, key string) (string, bool) {
+	headers, _ := ctx.Value(headersKey{}).([]kafka.Header)
+	for _, h := range headers {
+		if h.Key == key {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
+
+type trustedServicesKey struct{}
+
+// withTrustedServices returns ctx for workers accepting requests without user headers from services
+func withTrustedServices(ctx context.Context, services []string) context.Context {
+	return context.WithValue(ctx, trustedServicesKey{}, services)
+}
+
+// trustedService reports whether request comes from internal service configured as trusted
+func trustedService(ctx context.Context) bool {
+	name, ok := header(ctx, KeyService)
+	if !ok || len(name) == 0 {
+		return false
+	}
+	services, _ := ctx.Value(trustedServicesKey{}).([]string)
+	for _, service := range services {
+		if service == name {
+			return true
+		}
+	}
+	return false
+}
+
+// canCurate reports whether request may change ingredient catalog, requests without role header
+// are allowed only from trusted services
+func canCurate(ctx context.Context) bool {
+	role, ok := header(ctx, KeyUserRole)
+	if !ok {
+		return trustedService(ctx)
+	}
+	return role == RoleEditor || role == RoleAdmin
+}
+
+// streamPart marks i-th of total results of multi-result query
+func streamPart(i int, total int) *ingredient.StreamPart {
+	return &ingredient.StreamPart{Seq: i + 1, Total: total}
+}
+
+// streamDone marks final message of multi-result reply, which follows results or error
+func streamDone(total int) *ingredient.StreamPart {
+	return &ingredient.StreamPart{Total: total, Done: true}
+}
+
 func logdf(msg string, a ...interface{}) {
 	log.Debug().Msgf(msg, a...)
 }
@@ -39,48 +114,123 @@ func newReader(brokers []string, group string, topic string) (*kafka.Reader, err
 	if err != nil {
 		return nil, fmt.Errorf("invalid kafka config: %w", err)
 	}
-	return kafka.NewReader(config), nil
+	reader := kafka.NewReader(config)
+	metrics.RegisterReader(reader)
+	return reader, nil
 }
 
 func newWriter(brokers []string, topic string) *kafka.Writer {
-	return &kafka.Writer{
+	writer := &kafka.Writer{
 		Addr:        kafka.TCP(brokers...),
 		Topic:       topic,
 		Balancer:    &kafka.LeastBytes{},
 		Logger:      kafka.LoggerFunc(logdf),
 		ErrorLogger: kafka.LoggerFunc(logef),
 	}
+	metrics.RegisterWriter(writer)
+	return writer
 }
 
-func readDTO(ctx context.Context, reader *kafka.Reader, obj interface{}) (string, error) {
+// workerName identifies worker reading from reader in metrics
+func workerName(reader *kafka.Reader) string {
+	return reader.Config().GroupID
+}
+
+// readDTO reads next message into obj. Returned context carries trace context of the message and a
+// started consumer span, which must be ended with finishSpan once message is handled.
+func readDTO(ctx context.Context, reader *kafka.Reader, obj interface{}) (context.Context, string, error) {
 	m, err := reader.ReadMessage(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("error receiving message")
-		return "", err
+		return ctx, "", err
 	}
+	metrics.MessageReceived(workerName(reader))
+
+	var correlationID string
+	for _, h := range m.Headers {
+		if h.Key == KeyCorrelationID {
+			correlationID = string(h.Value)
+		}
+	}
+
+	msgCtx, _ := tracing.Tracer().Start(tracing.Extract(ctx, m), m.Topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("kafka"),
+			semconv.MessagingDestinationKindTopic,
+			semconv.MessagingDestinationKey.String(m.Topic),
+			semconv.MessagingOperationProcess,
+			semconv.MessagingKafkaConsumerGroupKey.String(reader.Config().GroupID),
+			semconv.MessagingConversationIDKey.String(correlationID),
+		),
+	)
 
 	err = json.Unmarshal(m.Value, obj)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to unmarshal message")
-		return "", err
+		metrics.DecodeError(workerName(reader))
+		finishSpan(msgCtx, err)
+		return ctx, "", err
 	}
 
-	for _, h := range m.Headers {
-		if h.Key == KeyCorrelationID {
-			return string(h.Value), nil
-		}
+	return context.WithValue(msgCtx, headersKey{}, m.Headers), correlationID, nil
+}
+
+var errPanic = errors.New("panic while handling message")
+
+// handleMessage runs handler of a message read by reader and records the result. Panic in handler is
+// turned into error and passed to reply, so requester gets an error instead of waiting for reply
+// forever. reply may be nil for messages nobody waits a reply to.
+func handleMessage(msgCtx context.Context, reader *kafka.Reader, handler func() error, reply func(err error)) {
+	worker := workerName(reader)
+	start := time.Now()
+	err := recoverPanic(worker, handler)
+	if errors.Is(err, errPanic) && reply != nil {
+		reply(err)
 	}
+	metrics.ObserveHandled(worker, start, err)
+	finishSpan(msgCtx, err)
+}
+
+// recoverPanic calls f turning panic into error
+func recoverPanic(worker string, f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Str("worker", worker).Msgf("recovered from panic: %v\n%s", r, debug.Stack())
+			metrics.Panic(worker)
+			err = fmt.Errorf("%w: %v", errPanic, r)
+		}
+	}()
+	return f()
+}
 
-	return "", nil
+// finishSpan ends span started by readDTO
+func finishSpan(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
 }
 
-func write(writer *kafka.Writer, key string, msg interface{}, correlationID string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func write(ctx context.Context, writer *kafka.Writer, key string, msg interface{}, correlationID string) {
+	ctx, span := tracing.Tracer().Start(ctx, writer.Topic+" send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("kafka"),
+			semconv.MessagingDestinationKindTopic,
+			semconv.MessagingDestinationKey.String(writer.Topic),
+			semconv.MessagingConversationIDKey.String(correlationID),
+		),
+	)
+	defer span.End()
 
 	bs, err := json.Marshal(msg)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to marshal outcoming message")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return
 	}
 
@@ -96,10 +246,16 @@ func write(writer *kafka.Writer, key string, msg interface{}, correlationID stri
 			},
 		}
 	}
-	err = writer.WriteMessages(ctx, kmsg)
+	tracing.Inject(ctx, &kmsg)
+
+	writeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = writer.WriteMessages(writeCtx, kmsg)
 
 	if err != nil {
 		log.Error().Err(err).Msg("failed to write message")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
 }
 
@@ -127,6 +283,19 @@ func closeAll(closers ...io.Closer) error {
 	return result
 }
 
+// pingBrokers checks that at least one of brokers accepts connections
+func pingBrokers(ctx context.Context, brokers []string) error {
+	var err error
+	for _, broker := range brokers {
+		var conn *kafka.Conn
+		conn, err = kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+	}
+	return fmt.Errorf("kafka brokers unreachable: %w", err)
+}
+
 func createTopics(broker string, topics ...string) error {
 	var conn *kafka.Conn
 	var err error
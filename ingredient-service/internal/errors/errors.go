@@ -5,4 +5,8 @@ import "errors"
 var (
 	ErrNotFound  = errors.New("not found")
 	ErrDuplicate = errors.New("duplicate")
+	// ErrForbidden is returned when user role does not allow the request
+	ErrForbidden = errors.New("forbidden")
+	// ErrReviewed is returned on review of suggestion which is not pending
+	ErrReviewed = errors.New("suggestion is already reviewed")
 )
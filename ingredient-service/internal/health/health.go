@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	checkTimeout = 3 * time.Second
+)
+
+type Check func(ctx context.Context) error
+
+type Checker struct {
+	mu    sync.RWMutex
+	live  map[string]Check
+	ready map[string]Check
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func NewChecker() *Checker {
+	return &Checker{
+		live:  make(map[string]Check),
+		ready: make(map[string]Check),
+	}
+}
+
+// AddLivenessCheck adds check which fails when service has to be restarted
+func (c *Checker) AddLivenessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.live[name] = check
+}
+
+// AddReadinessCheck adds check which fails when service is unable to process requests
+func (c *Checker) AddReadinessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ready[name] = check
+}
+
+func (c *Checker) LiveHandler() http.Handler {
+	return c.handler(c.live)
+}
+
+func (c *Checker) ReadyHandler() http.Handler {
+	return c.handler(c.ready)
+}
+
+func (c *Checker) handler(checks map[string]Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		c.mu.RLock()
+		report := run(ctx, checks)
+		c.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+func run(ctx context.Context, checks map[string]Check) Report {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]string, len(checks)),
+	}
+	for name, check := range checks {
+		name, check := name, check
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				report.Status = StatusFail
+				report.Checks[name] = err.Error()
+				return
+			}
+			report.Checks[name] = StatusOK
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
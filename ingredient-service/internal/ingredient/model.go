@@ -1,5 +1,7 @@
 package ingredient
 
+import "time"
+
 type Ingredient struct {
 	ID             string          `json:"id" bson:"_id,omitempty"`
 	Name           string          `json:"name" bson:"name,omitempty"`
@@ -20,15 +22,78 @@ type CreateIngredientDTO struct {
 	NutritionFacts *NutritionFacts `json:"nutrition_facts,omitempty"`
 }
 
+// StreamPart marks reply to multi-result query. Results are numbered by Seq starting from 1 and carry Total
+// number of results, stream ends with message having Done set and no result, which is sent even if nothing is found
+type StreamPart struct {
+	Seq   int  `json:"seq,omitempty"`
+	Total int  `json:"total"`
+	Done  bool `json:"done,omitempty"`
+}
+
 type IngredientDTO struct {
-	Ingredient Ingredient `json:"ingredient,omitempty"`
-	Name       string     `json:"name,omitempty"`
-	ID         string     `json:"ingredient_id,omitempty"`
-	NameQuery  string     `json:"name_query,omitempty"`
-	Error      string     `json:"error,omitempty"`
+	Ingredient Ingredient  `json:"ingredient,omitempty"`
+	Name       string      `json:"name,omitempty"`
+	ID         string      `json:"ingredient_id,omitempty"`
+	NameQuery  string      `json:"name_query,omitempty"`
+	Stream     *StreamPart `json:"stream,omitempty"`
+	Error      string      `json:"error,omitempty"`
 }
 
 type FindIngredientsDTO struct {
 	ID        string `json:"ingredient_id,omitempty"`
 	NameQuery string `json:"name_query,omitempty"`
 }
+
+type UpdateIngredientDTO struct {
+	ID             string          `json:"ingredient_id"`
+	Name           string          `json:"name"`
+	BaseUnit       string          `json:"base_unit"`
+	NutritionFacts *NutritionFacts `json:"nutrition_facts,omitempty"`
+}
+
+const (
+	SuggestionPending  = "pending"
+	SuggestionApproved = "approved"
+	SuggestionRejected = "rejected"
+)
+
+// Suggestion is a new ingredient or a change of existing one proposed by user, it is applied to
+// the catalog only when approved by editor
+type Suggestion struct {
+	ID string `json:"id" bson:"_id,omitempty"`
+	// IngredientID is set for suggested change of existing ingredient and for approved new ingredient
+	IngredientID   string          `json:"ingredient_id,omitempty" bson:"ingredient_id,omitempty"`
+	Name           string          `json:"name" bson:"name"`
+	BaseUnit       string          `json:"base_unit" bson:"base_unit"`
+	NutritionFacts *NutritionFacts `json:"nutrition_facts,omitempty" bson:"nutrition_facts,omitempty"`
+	SuggestedBy    string          `json:"suggested_by,omitempty" bson:"suggested_by,omitempty"`
+	Status         string          `json:"status" bson:"status"`
+	ReviewedBy     string          `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	CreatedAt      time.Time       `json:"created_at" bson:"created_at"`
+	ReviewedAt     time.Time       `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+}
+
+type SuggestIngredientDTO struct {
+	// IngredientID is set to suggest change of existing ingredient
+	IngredientID   string          `json:"ingredient_id,omitempty"`
+	Name           string          `json:"name"`
+	BaseUnit       string          `json:"base_unit"`
+	NutritionFacts *NutritionFacts `json:"nutrition_facts,omitempty"`
+}
+
+type ReviewSuggestionDTO struct {
+	ID      string `json:"suggestion_id"`
+	Approve bool   `json:"approve"`
+}
+
+// FindSuggestionsDTO requests suggestion by ID or, if ID is empty, all pending suggestions
+type FindSuggestionsDTO struct {
+	ID string `json:"suggestion_id,omitempty"`
+}
+
+type SuggestionDTO struct {
+	Suggestion Suggestion  `json:"suggestion,omitempty"`
+	ID         string      `json:"suggestion_id,omitempty"`
+	Stream     *StreamPart `json:"stream,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
@@ -7,12 +7,20 @@ import (
 	apperror "github.com/tony-spark/recipetor-backend/ingredient-service/internal/errors"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient/storage"
+	"time"
 )
 
 type Service interface {
 	Create(ctx context.Context, dto ingredient.CreateIngredientDTO) (string, error)
 	GetByID(ctx context.Context, id string) (ingredient.Ingredient, error)
 	SearchByName(ctx context.Context, nameQuery string) ([]ingredient.Ingredient, error)
+	Update(ctx context.Context, dto ingredient.UpdateIngredientDTO) (ingredient.Ingredient, error)
+	// Suggest saves suggestion of user to be reviewed by editor
+	Suggest(ctx context.Context, dto ingredient.SuggestIngredientDTO, suggestedBy string) (string, error)
+	GetSuggestion(ctx context.Context, id string) (ingredient.Suggestion, error)
+	PendingSuggestions(ctx context.Context) ([]ingredient.Suggestion, error)
+	// ReviewSuggestion approves or rejects pending suggestion, approved suggestion is applied to the catalog
+	ReviewSuggestion(ctx context.Context, dto ingredient.ReviewSuggestionDTO, reviewedBy string) (ingredient.Suggestion, error)
 }
 
 type service struct {
@@ -59,3 +67,118 @@ func (s service) SearchByName(ctx context.Context, nameQuery string) ([]ingredie
 	}
 	return ingrs, nil
 }
+
+func (s service) Update(ctx context.Context, dto ingredient.UpdateIngredientDTO) (ingredient.Ingredient, error) {
+	ingr := ingredient.Ingredient{
+		ID:             dto.ID,
+		Name:           dto.Name,
+		BaseUnit:       dto.BaseUnit,
+		NutritionFacts: dto.NutritionFacts,
+	}
+	err := s.storage.Update(ctx, ingr)
+	if err != nil {
+		if errors.Is(err, apperror.ErrDuplicate) || errors.Is(err, apperror.ErrNotFound) {
+			return ingr, err
+		}
+		return ingr, fmt.Errorf("could not update ingredient: %w", err)
+	}
+	return ingr, nil
+}
+
+func (s service) Suggest(ctx context.Context, dto ingredient.SuggestIngredientDTO, suggestedBy string) (string, error) {
+	if len(dto.IngredientID) > 0 {
+		_, err := s.GetByID(ctx, dto.IngredientID)
+		if err != nil {
+			return "", err
+		}
+	}
+	suggestion := ingredient.Suggestion{
+		IngredientID:   dto.IngredientID,
+		Name:           dto.Name,
+		BaseUnit:       dto.BaseUnit,
+		NutritionFacts: dto.NutritionFacts,
+		SuggestedBy:    suggestedBy,
+		Status:         ingredient.SuggestionPending,
+		CreatedAt:      time.Now(),
+	}
+	id, err := s.storage.CreateSuggestion(ctx, suggestion)
+	if err != nil {
+		return "", fmt.Errorf("could not create suggestion: %w", err)
+	}
+	return id, nil
+}
+
+func (s service) GetSuggestion(ctx context.Context, id string) (suggestion ingredient.Suggestion, err error) {
+	suggestion, err = s.storage.FindSuggestionByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return
+		}
+		return suggestion, fmt.Errorf("could not get suggestion: %w", err)
+	}
+	return
+}
+
+func (s service) PendingSuggestions(ctx context.Context) ([]ingredient.Suggestion, error) {
+	suggestions, err := s.storage.FindPendingSuggestions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get pending suggestions: %w", err)
+	}
+	return suggestions, nil
+}
+
+func (s service) ReviewSuggestion(ctx context.Context, dto ingredient.ReviewSuggestionDTO, reviewedBy string) (ingredient.Suggestion, error) {
+	status := ingredient.SuggestionRejected
+	if dto.Approve {
+		status = ingredient.SuggestionApproved
+	}
+	suggestion, err := s.storage.ReviewSuggestion(ctx, dto.ID, status, reviewedBy, time.Now())
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) || errors.Is(err, apperror.ErrReviewed) {
+			return suggestion, err
+		}
+		return suggestion, fmt.Errorf("could not review suggestion: %w", err)
+	}
+	if !dto.Approve {
+		return suggestion, nil
+	}
+
+	ingredientID, err := s.applySuggestion(ctx, suggestion)
+	if err != nil {
+		// suggestion is returned for review again, so it is not lost
+		suggestion.Status = ingredient.SuggestionPending
+		suggestion.ReviewedBy = ""
+		suggestion.ReviewedAt = time.Time{}
+		revertErr := s.storage.UpdateSuggestion(ctx, suggestion)
+		if revertErr != nil {
+			return suggestion, fmt.Errorf("could not return suggestion %s to review: %w", suggestion.ID, revertErr)
+		}
+		return suggestion, err
+	}
+	if suggestion.IngredientID != ingredientID {
+		suggestion.IngredientID = ingredientID
+		err = s.storage.UpdateSuggestion(ctx, suggestion)
+		if err != nil {
+			return suggestion, fmt.Errorf("could not save ingredient of suggestion: %w", err)
+		}
+	}
+	return suggestion, nil
+}
+
+// applySuggestion creates suggested ingredient or changes existing one and returns its ID
+func (s service) applySuggestion(ctx context.Context, suggestion ingredient.Suggestion) (string, error) {
+	if len(suggestion.IngredientID) > 0 {
+		_, err := s.Update(ctx, ingredient.UpdateIngredientDTO{
+			ID:             suggestion.IngredientID,
+			Name:           suggestion.Name,
+			BaseUnit:       suggestion.BaseUnit,
+			NutritionFacts: suggestion.NutritionFacts,
+		})
+		return suggestion.IngredientID, err
+	}
+	return s.Create(ctx, ingredient.CreateIngredientDTO{
+		Name:           suggestion.Name,
+		BaseUnit:       suggestion.BaseUnit,
+		NutritionFacts: suggestion.NutritionFacts,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	apperror "github.com/tony-spark/recipetor-backend/ingredient-service/internal/errors"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient/storage/mongodb"
 	"os"
@@ -92,4 +93,97 @@ func TestService(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, 2, len(ingrs))
 	})
+	t.Run("update ingredient", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		id, err := serv.Create(ctx, ingredient.CreateIngredientDTO{
+			Name:     "перец",
+			BaseUnit: "г",
+		})
+		require.NoError(t, err)
+
+		dto := ingredient.UpdateIngredientDTO{
+			ID:       id,
+			Name:     "перец чёрный",
+			BaseUnit: "г",
+			NutritionFacts: &ingredient.NutritionFacts{
+				Calories: 251,
+			},
+		}
+		_, err = serv.Update(ctx, dto)
+		require.NoError(t, err)
+
+		ingr, err := serv.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, dto.Name, ingr.Name)
+		assert.Equal(t, dto.NutritionFacts, ingr.NutritionFacts)
+	})
+	t.Run("approve suggested ingredient", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		id, err := serv.Suggest(ctx, ingredient.SuggestIngredientDTO{
+			Name:     "кинза",
+			BaseUnit: "г",
+		}, "user")
+		require.NoError(t, err)
+
+		pending, err := serv.PendingSuggestions(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(pending))
+		assert.Equal(t, id, pending[0].ID)
+		assert.Equal(t, "user", pending[0].SuggestedBy)
+
+		suggestion, err := serv.ReviewSuggestion(ctx, ingredient.ReviewSuggestionDTO{ID: id, Approve: true}, "editor")
+		require.NoError(t, err)
+		assert.Equal(t, ingredient.SuggestionApproved, suggestion.Status)
+		assert.NotEmpty(t, suggestion.IngredientID)
+
+		ingr, err := serv.GetByID(ctx, suggestion.IngredientID)
+		require.NoError(t, err)
+		assert.Equal(t, "кинза", ingr.Name)
+
+		_, err = serv.ReviewSuggestion(ctx, ingredient.ReviewSuggestionDTO{ID: id, Approve: false}, "editor")
+		assert.ErrorIs(t, err, apperror.ErrReviewed)
+
+		pending, err = serv.PendingSuggestions(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+	t.Run("reject suggested change", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		ingrID, err := serv.Create(ctx, ingredient.CreateIngredientDTO{
+			Name:     "укроп",
+			BaseUnit: "г",
+		})
+		require.NoError(t, err)
+
+		id, err := serv.Suggest(ctx, ingredient.SuggestIngredientDTO{
+			IngredientID: ingrID,
+			Name:         "укроп сушёный",
+			BaseUnit:     "г",
+		}, "user")
+		require.NoError(t, err)
+
+		suggestion, err := serv.ReviewSuggestion(ctx, ingredient.ReviewSuggestionDTO{ID: id, Approve: false}, "editor")
+		require.NoError(t, err)
+		assert.Equal(t, ingredient.SuggestionRejected, suggestion.Status)
+
+		ingr, err := serv.GetByID(ctx, ingrID)
+		require.NoError(t, err)
+		assert.Equal(t, "укроп", ingr.Name)
+	})
+	t.Run("suggest change of unknown ingredient", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, err := serv.Suggest(ctx, ingredient.SuggestIngredientDTO{
+			IngredientID: "63a1b2c3d4e5f6a7b8c9d0e1",
+			Name:         "соль морская",
+		}, "user")
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+	})
 }
@@ -12,16 +12,19 @@ import (
 	apperror "github.com/tony-spark/recipetor-backend/ingredient-service/internal/errors"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient/storage"
+	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/metrics"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 	"time"
 )
 
 type mongoStorage struct {
-	client     *mongo.Client
-	collection *mongo.Collection
+	client      *mongo.Client
+	collection  *mongo.Collection
+	suggestions *mongo.Collection
 }
 
 func NewStorage(dsn string, dbname string) (storage.Storage, error) {
@@ -40,7 +43,7 @@ func NewStorage(dsn string, dbname string) (storage.Storage, error) {
 		return nil, fmt.Errorf("could not execute db migrations: %w", err)
 	}
 
-	client, err := mongo.NewClient(options.Client().ApplyURI(dsn))
+	client, err := mongo.NewClient(options.Client().ApplyURI(dsn).SetMonitor(otelmongo.NewMonitor()))
 	if err != nil {
 		return nil, fmt.Errorf("could not create connection to test DB: %w", err)
 	}
@@ -60,9 +63,11 @@ func NewStorage(dsn string, dbname string) (storage.Storage, error) {
 	db := client.Database(dbname)
 
 	collection := db.Collection("ingredients")
+	suggestions := db.Collection("suggestions")
 	return mongoStorage{
-		client:     client,
-		collection: collection,
+		client:      client,
+		collection:  collection,
+		suggestions: suggestions,
 	}, nil
 }
 
@@ -82,6 +87,8 @@ func NewTestStorage(dsn string, dbname string) (storage.Storage, func(ctx contex
 }
 
 func (m mongoStorage) Create(ctx context.Context, ingredient ingredient.Ingredient) (string, error) {
+	defer metrics.ObserveStorage("Create", time.Now())
+
 	result, err := m.collection.InsertOne(ctx, ingredient)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
@@ -99,6 +106,8 @@ func (m mongoStorage) Create(ctx context.Context, ingredient ingredient.Ingredie
 }
 
 func (m mongoStorage) FindByID(ctx context.Context, id string) (ing ingredient.Ingredient, err error) {
+	defer metrics.ObserveStorage("FindByID", time.Now())
+
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return
@@ -116,6 +125,8 @@ func (m mongoStorage) FindByID(ctx context.Context, id string) (ing ingredient.I
 }
 
 func (m mongoStorage) SearchByName(ctx context.Context, query string) (ings []ingredient.Ingredient, err error) {
+	defer metrics.ObserveStorage("SearchByName", time.Now())
+
 	filter := bson.D{{Key: "$text",
 		Value: bson.D{{Key: "$search", Value: query}}}}
 
@@ -131,3 +142,122 @@ func (m mongoStorage) SearchByName(ctx context.Context, query string) (ings []in
 
 	return
 }
+
+func (m mongoStorage) Update(ctx context.Context, ingredient ingredient.Ingredient) error {
+	defer metrics.ObserveStorage("Update", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(ingredient.ID)
+	if err != nil {
+		return err
+	}
+	ingredient.ID = ""
+	result, err := m.collection.ReplaceOne(ctx, bson.M{"_id": oid}, ingredient)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return apperror.ErrDuplicate
+		}
+		return fmt.Errorf("failed to update ingredient: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) CreateSuggestion(ctx context.Context, suggestion ingredient.Suggestion) (string, error) {
+	defer metrics.ObserveStorage("CreateSuggestion", time.Now())
+
+	result, err := m.suggestions.InsertOne(ctx, suggestion)
+	if err != nil {
+		return "", fmt.Errorf("failed to insert suggestion: %w", err)
+	}
+
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return "", fmt.Errorf("failed to insert suggestion: invalid IntertedID")
+	}
+
+	return id.Hex(), nil
+}
+
+func (m mongoStorage) FindSuggestionByID(ctx context.Context, id string) (suggestion ingredient.Suggestion, err error) {
+	defer metrics.ObserveStorage("FindSuggestionByID", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return
+	}
+	result := m.suggestions.FindOne(ctx, bson.M{"_id": oid})
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return suggestion, apperror.ErrNotFound
+		}
+		err = result.Err()
+		return
+	}
+	err = result.Decode(&suggestion)
+	return
+}
+
+func (m mongoStorage) FindPendingSuggestions(ctx context.Context) (suggestions []ingredient.Suggestion, err error) {
+	defer metrics.ObserveStorage("FindPendingSuggestions", time.Now())
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := m.suggestions.Find(ctx, bson.M{"status": ingredient.SuggestionPending}, opts)
+	if err != nil {
+		return
+	}
+
+	err = cursor.All(ctx, &suggestions)
+	return
+}
+
+func (m mongoStorage) ReviewSuggestion(ctx context.Context, id string, status string, reviewedBy string, at time.Time) (suggestion ingredient.Suggestion, err error) {
+	defer metrics.ObserveStorage("ReviewSuggestion", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return
+	}
+	// only pending suggestion is matched, so concurrent reviews can not both succeed
+	result := m.suggestions.FindOneAndUpdate(ctx,
+		bson.M{"_id": oid, "status": ingredient.SuggestionPending},
+		bson.M{"$set": bson.M{"status": status, "reviewed_by": reviewedBy, "reviewed_at": at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+	if result.Err() != nil {
+		if !errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			err = result.Err()
+			return
+		}
+		_, err = m.FindSuggestionByID(ctx, id)
+		if err != nil {
+			return
+		}
+		return suggestion, apperror.ErrReviewed
+	}
+	err = result.Decode(&suggestion)
+	return
+}
+
+func (m mongoStorage) UpdateSuggestion(ctx context.Context, suggestion ingredient.Suggestion) error {
+	defer metrics.ObserveStorage("UpdateSuggestion", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(suggestion.ID)
+	if err != nil {
+		return err
+	}
+	suggestion.ID = ""
+	result, err := m.suggestions.ReplaceOne(ctx, bson.M{"_id": oid}, suggestion)
+	if err != nil {
+		return fmt.Errorf("failed to update suggestion: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, nil)
+}
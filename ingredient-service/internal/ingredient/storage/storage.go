@@ -3,10 +3,19 @@ package storage
 import (
 	"context"
 	"github.com/tony-spark/recipetor-backend/ingredient-service/internal/ingredient"
+	"time"
 )
 
 type Storage interface {
 	Create(ctx context.Context, ingredient ingredient.Ingredient) (string, error)
 	FindByID(ctx context.Context, id string) (ingredient.Ingredient, error)
 	SearchByName(ctx context.Context, query string) ([]ingredient.Ingredient, error)
+	Update(ctx context.Context, ingredient ingredient.Ingredient) error
+	CreateSuggestion(ctx context.Context, suggestion ingredient.Suggestion) (string, error)
+	FindSuggestionByID(ctx context.Context, id string) (ingredient.Suggestion, error)
+	FindPendingSuggestions(ctx context.Context) ([]ingredient.Suggestion, error)
+	// ReviewSuggestion sets status of pending suggestion and returns it, ErrReviewed is returned if it is not pending
+	ReviewSuggestion(ctx context.Context, id string, status string, reviewedBy string, at time.Time) (ingredient.Suggestion, error)
+	UpdateSuggestion(ctx context.Context, suggestion ingredient.Suggestion) error
+	Ping(ctx context.Context) error
 }
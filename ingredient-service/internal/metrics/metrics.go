@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "recipetor"
+)
+
+var (
+	messagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "messages_received_total",
+		Help:      "Number of messages received by worker",
+	}, []string{"worker"})
+	messagesSucceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "messages_succeeded_total",
+		Help:      "Number of messages successfully handled by worker",
+	}, []string{"worker"})
+	messagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "messages_failed_total",
+		Help:      "Number of messages worker failed to handle",
+	}, []string{"worker"})
+	decodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "decode_errors_total",
+		Help:      "Number of messages worker could not decode",
+	}, []string{"worker"})
+	panics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "panics_total",
+		Help:      "Number of panics recovered in worker",
+	}, []string{"worker"})
+	restarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "restarts_total",
+		Help:      "Number of worker restarts after failure",
+	}, []string{"worker"})
+	workerUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "up",
+		Help:      "Whether worker is running (1) or stopped or waiting for restart (0)",
+	}, []string{"worker"})
+	handlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "handler_duration_seconds",
+		Help:      "Message handling latency",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"worker"})
+	storageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "operation_duration_seconds",
+		Help:      "Storage operation latency",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func MessageReceived(worker string) {
+	messagesReceived.WithLabelValues(worker).Inc()
+}
+
+func DecodeError(worker string) {
+	decodeErrors.WithLabelValues(worker).Inc()
+}
+
+func Panic(worker string) {
+	panics.WithLabelValues(worker).Inc()
+}
+
+func WorkerRestarted(worker string) {
+	restarts.WithLabelValues(worker).Inc()
+}
+
+func SetWorkerUp(worker string, up bool) {
+	if up {
+		workerUp.WithLabelValues(worker).Set(1)
+		return
+	}
+	workerUp.WithLabelValues(worker).Set(0)
+}
+
+// ObserveHandled records handling result and latency of a message received by worker at start
+func ObserveHandled(worker string, start time.Time, err error) {
+	handlerDuration.WithLabelValues(worker).Observe(time.Since(start).Seconds())
+	if err != nil {
+		messagesFailed.WithLabelValues(worker).Inc()
+		return
+	}
+	messagesSucceeded.WithLabelValues(worker).Inc()
+}
+
+// ObserveStorage records latency of a storage method started at start, meant to be deferred
+func ObserveStorage(method string, start time.Time) {
+	storageDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
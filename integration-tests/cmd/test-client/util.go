@@ -17,6 +17,10 @@ import (
 
 const (
 	KeyCorrelationID = "correlation_id"
+	// KeyService is header with name of service making request not on behalf of user
+	KeyService = "service"
+
+	serviceName = "test-client"
 )
 
 func newWriter(brokers []string, topic string) *kafka.Writer {
@@ -83,14 +87,19 @@ func write(t *testing.T, writer *kafka.Writer, key string, msg interface{}, corr
 	kmsg := kafka.Message{
 		Key:   []byte(key),
 		Value: bs,
-	}
-	if len(correlationID) > 0 {
-		kmsg.Headers = []kafka.Header{
+		// services accept requests without user headers only from trusted services
+		Headers: []kafka.Header{
 			{
-				Key:   KeyCorrelationID,
-				Value: []byte(correlationID),
+				Key:   KeyService,
+				Value: []byte(serviceName),
 			},
-		}
+		},
+	}
+	if len(correlationID) > 0 {
+		kmsg.Headers = append(kmsg.Headers, kafka.Header{
+			Key:   KeyCorrelationID,
+			Value: []byte(correlationID),
+		})
 	}
 	err = writer.WriteMessages(ctx, kmsg)
 	require.NoError(t, err, "не удалось записать сообщение")
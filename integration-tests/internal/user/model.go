@@ -14,7 +14,12 @@ type CreateUserDTO struct {
 	Password string `json:"password"`
 }
 
-type LoginDTO = CreateUserDTO
+type LoginDTO struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// ClientID identifies client making login attempts, e.g. its IP address
+	ClientID string `json:"client_id,omitempty"`
+}
 
 type UserRegistrationDTO struct {
 	ID    string `json:"user_id,omitempty"`
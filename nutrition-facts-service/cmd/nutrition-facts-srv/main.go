@@ -6,8 +6,11 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/tony-spark/recipetor-backend/nutrition-facts-service/internal/config"
+	"github.com/tony-spark/recipetor-backend/nutrition-facts-service/internal/controller/http"
 	"github.com/tony-spark/recipetor-backend/nutrition-facts-service/internal/controller/kafka"
+	"github.com/tony-spark/recipetor-backend/nutrition-facts-service/internal/health"
 	"github.com/tony-spark/recipetor-backend/nutrition-facts-service/internal/nutrition/service"
+	"github.com/tony-spark/recipetor-backend/nutrition-facts-service/internal/tracing"
 	"os"
 	"os/signal"
 	"syscall"
@@ -28,9 +31,18 @@ func main() {
 	}
 	log.Logger = log.Logger.Level(logLevel)
 
+	shutdownTracing, err := tracing.Init(config.Config.Tracing.Exporter, config.Config.Tracing.OTLPEndpoint, config.Config.Tracing.File)
+	if err != nil {
+		log.Fatal().Err(err).Msg("could not initialize tracing")
+	}
+
 	nutritionService := service.NewService()
 
-	controller, err := kafka.NewController(nutritionService, config.Config.Kafka.Brokers)
+	controller, err := kafka.NewController(nutritionService, config.Config.Kafka.Brokers, kafka.RecipeWorkerConfig{
+		Concurrency:  config.Config.Recipes.Concurrency,
+		Timeout:      config.Config.Recipes.Timeout,
+		DrainTimeout: config.Config.Recipes.DrainTimeout,
+	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize kafka controller")
 	}
@@ -44,6 +56,19 @@ func main() {
 		}
 	}()
 
+	checker := health.NewChecker()
+	checker.AddLivenessCheck("kafka", controller.Live)
+	checker.AddReadinessCheck("kafka", controller.Ready)
+
+	httpController := http.NewController(config.Config.HTTP.Address, checker)
+	go func() {
+		err := httpController.Run(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error running http server")
+		}
+	}()
+	log.Info().Msgf("serving metrics and health checks on %s", config.Config.HTTP.Address)
+
 	terminateSignal := make(chan os.Signal, 1)
 	signal.Notify(terminateSignal, syscall.SIGINT, syscall.SIGTERM)
 
@@ -53,6 +78,16 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("controller failed to stop properly")
 	}
+	err = httpController.Stop()
+	if err != nil {
+		log.Fatal().Err(err).Msg("http server failed to stop properly")
+	}
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	err = shutdownTracing(shutdownCtx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to flush traces")
+	}
 
 	log.Info().Msg("nutrition facts service interrupted via system signal")
 }
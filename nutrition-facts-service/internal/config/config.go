@@ -2,6 +2,7 @@ package config
 
 import (
 	"flag"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 	"github.com/rs/zerolog/log"
@@ -16,11 +17,31 @@ type config struct {
 	Kafka    struct {
 		Brokers string `env:"KAFKA_BROKERS"`
 	}
+	Recipes struct {
+		Concurrency  int           `env:"RECIPE_CONCURRENCY"`
+		Timeout      time.Duration `env:"RECIPE_TIMEOUT"`
+		DrainTimeout time.Duration `env:"RECIPE_DRAIN_TIMEOUT"`
+	}
+	HTTP struct {
+		Address string `env:"HTTP_ADDRESS"`
+	}
+	Tracing struct {
+		Exporter     string `env:"TRACING_EXPORTER"`
+		OTLPEndpoint string `env:"TRACING_OTLP_ENDPOINT"`
+		File         string `env:"TRACING_FILE"`
+	}
 }
 
 func Parse() error {
 	flag.StringVar(&Config.LogLevel, "log-level", "debug", "application log level")
 	flag.StringVar(&Config.Kafka.Brokers, "kafka-brokers", "", "kafka broker list")
+	flag.IntVar(&Config.Recipes.Concurrency, "recipe-concurrency", 8, "maximum number of recipes processed at once")
+	flag.DurationVar(&Config.Recipes.Timeout, "recipe-timeout", 30*time.Second, "time limit for processing a single recipe")
+	flag.DurationVar(&Config.Recipes.DrainTimeout, "recipe-drain-timeout", 20*time.Second, "time to wait for in-flight recipes on shutdown")
+	flag.StringVar(&Config.HTTP.Address, "http-address", ":8080", "address of HTTP server exposing metrics and health checks")
+	flag.StringVar(&Config.Tracing.Exporter, "tracing-exporter", "", "trace exporter: otlp, file or empty to disable")
+	flag.StringVar(&Config.Tracing.OTLPEndpoint, "tracing-otlp-endpoint", "localhost:4317", "OTLP gRPC collector endpoint")
+	flag.StringVar(&Config.Tracing.File, "tracing-file", "traces.json", "file to write traces to with file exporter")
 	flag.Parse()
 
 	err := env.Parse(&Config)
@@ -6,3 +6,10 @@ type Controller interface {
 	Run(ctx context.Context) error
 	Stop() error
 }
+
+// MonitoredController is a Controller able to report its health
+type MonitoredController interface {
+	Controller
+	Live(ctx context.Context) error
+	Ready(ctx context.Context) error
+}
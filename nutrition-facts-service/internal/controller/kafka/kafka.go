@@ -5,48 +5,63 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/tony-spark/recipetor-backend/nutrition-facts-service/internal/controller"
 	"github.com/tony-spark/recipetor-backend/nutrition-facts-service/internal/nutrition/service"
-	"golang.org/x/sync/errgroup"
 	"strings"
+	"sync"
 )
 
 type kafkaController struct {
 	workers []Worker
+	brokers []string
+	states  *workerStates
+	// stop is closed when controller is stopped, so failed workers are not restarted
+	stop     chan struct{}
+	stopOnce *sync.Once
 }
 
 type Worker interface {
+	Name() string
 	Process(ctx context.Context) error
 	Stop() error
 }
 
-func NewController(nutritionService service.Service, kafkaBrokerURLs string) (controller.Controller, error) {
+func NewController(nutritionService service.Service, kafkaBrokerURLs string, recipeConfig RecipeWorkerConfig) (controller.MonitoredController, error) {
 	brokers := strings.Split(kafkaBrokerURLs, ",")
 
 	var workers []Worker
 
-	recipeWorker, err := NewRecipeWorker(nutritionService, brokers)
+	recipeWorker, err := NewRecipeWorker(nutritionService, brokers, recipeConfig)
 	if err != nil {
 		return nil, err
 	}
 	workers = append(workers, recipeWorker)
 
 	return kafkaController{
-		workers: workers,
+		workers:  workers,
+		brokers:  brokers,
+		states:   newWorkerStates(workers),
+		stop:     make(chan struct{}),
+		stopOnce: &sync.Once{},
 	}, nil
 }
 
+// Run runs every worker under supervision, failed workers are restarted without affecting others
 func (k kafkaController) Run(ctx context.Context) error {
-	group, ctx := errgroup.WithContext(ctx)
-
+	var wg sync.WaitGroup
 	for _, w := range k.workers {
-		worker := w
-		group.Go(func() error {
-			return worker.Process(ctx)
-		})
+		wg.Add(1)
+		go func(worker Worker) {
+			defer wg.Done()
+			k.supervise(ctx, worker)
+		}(w)
 	}
-	return group.Wait()
+	wg.Wait()
+	return nil
 }
 
 func (k kafkaController) Stop() error {
+	k.stopOnce.Do(func() {
+		close(k.stop)
+	})
 	var result error
 	for _, w := range k.workers {
 		err := w.Stop()
@@ -56,3 +71,22 @@ func (k kafkaController) Stop() error {
 	}
 	return result
 }
+
+// Live fails if any worker has stopped processing messages and will not be restarted
+func (k kafkaController) Live(ctx context.Context) error {
+	return k.states.check(WorkerStarting, WorkerRunning, WorkerRestarting)
+}
+
+// Ready fails if any worker is not running or none of kafka brokers is reachable
+func (k kafkaController) Ready(ctx context.Context) error {
+	var result error
+	err := k.states.check(WorkerRunning)
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
+	err = pingBrokers(ctx, k.brokers)
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
+	return result
+}
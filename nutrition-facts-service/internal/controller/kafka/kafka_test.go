@@ -55,7 +55,7 @@ func (suite *ControllerTestSuite) TestController() {
 			ID:     suite.rand.RandomObjectID(),
 			UserID: suite.rand.RandomObjectID(),
 		}
-		write(suite.recipesWriter, recipeDTO.ID, recipeDTO, "")
+		write(context.Background(), suite.recipesWriter, recipeDTO.ID, recipeDTO, "")
 
 		ingredients := map[string]nutrition.Ingredient{
 			"1": {
@@ -103,13 +103,13 @@ func (suite *ControllerTestSuite) TestController() {
 				}
 
 				var dto nutrition.FindIngredientsDTO
-				corID, err := readDTO(ctx, suite.ingredientsReqReader, &dto)
+				_, corID, err := readDTO(ctx, suite.ingredientsReqReader, &dto)
 				suite.Require().NoError(err)
 
 				ingr, ok := ingredients[dto.ID]
 				suite.Assert().True(ok)
 				if ok {
-					write(suite.ingredientsWriter, dto.ID, nutrition.IngredientDTO{
+					write(context.Background(), suite.ingredientsWriter, dto.ID, nutrition.IngredientDTO{
 						Ingredient: ingr,
 						ID:         ingr.ID,
 					}, corID)
@@ -127,7 +127,7 @@ func (suite *ControllerTestSuite) TestController() {
 			defer cancel()
 
 			var dto nutrition.RecipeNutritionsDTO
-			_, err := readDTO(ctx, suite.nutritionFactsReader, &dto)
+			_, _, err := readDTO(ctx, suite.nutritionFactsReader, &dto)
 			suite.Assert().NoError(err)
 
 			suite.Assert().Equal(recipeDTO.Recipe.ID, dto.RecipeID)
@@ -158,7 +158,11 @@ func (suite *ControllerTestSuite) SetupSuite() {
 	err = createTopics(kafkaBroker, TopicIngredients, TopicRecipes, TopicIngredientsReq, TopicNutritionFacts)
 	suite.Require().NoError(err)
 
-	suite.controller, err = NewController(service.NewService(), kafkaBroker)
+	suite.controller, err = NewController(service.NewService(), kafkaBroker, RecipeWorkerConfig{
+		Concurrency:  4,
+		Timeout:      30 * time.Second,
+		DrainTimeout: 5 * time.Second,
+	})
 	suite.Require().NoError(err)
 
 	suite.ingredientsReqReader, err = newReader([]string{kafkaBroker}, "nutrition-facts-test-ingredients-req", TopicIngredientsReq)
@@ -3,23 +3,49 @@ package kafka
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/rs/zerolog/log"
 	"github.com/tony-spark/recipetor-backend/nutrition-facts-service/internal/nutrition"
+	"go.opentelemetry.io/otel/trace"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 	"github.com/tony-spark/recipetor-backend/nutrition-facts-service/internal/nutrition/service"
 )
 
+// RecipeWorkerConfig limits recipe processing
+type RecipeWorkerConfig struct {
+	// Concurrency is the maximum number of recipes processed at once, worker stops consuming when reached
+	Concurrency int
+	// Timeout limits processing of a single recipe
+	Timeout time.Duration
+	// DrainTimeout limits how long Stop waits for in-flight recipes
+	DrainTimeout time.Duration
+}
+
 type RecipeWorker struct {
 	nutritionService     service.Service
 	recipeReader         *kafka.Reader
 	nutritionFactsWriter *kafka.Writer
 	reqIngredientsWriter *kafka.Writer
 	ingredientsReader    *kafka.Reader
+
+	config RecipeWorkerConfig
+	// slots holds a token for every recipe in flight
+	slots      chan struct{}
+	replies    *ingredientReplies
+	routerOnce *sync.Once
+	// ctx outlives context passed to Process, so in-flight recipes can be drained on shutdown
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func NewRecipeWorker(nutritionService service.Service, brokers []string) (Worker, error) {
+func NewRecipeWorker(nutritionService service.Service, brokers []string, config RecipeWorkerConfig) (Worker, error) {
+	if config.Concurrency < 1 {
+		return nil, fmt.Errorf("invalid recipe concurrency: %d", config.Concurrency)
+	}
 	recipeReader, err := newReader(brokers, "nutrition-facts-service-recipes", TopicRecipes)
 	if err != nil {
 		return nil, err
@@ -30,26 +56,46 @@ func NewRecipeWorker(nutritionService service.Service, brokers []string) (Worker
 	}
 	nutritionFactsWriter := newWriter(brokers, TopicNutritionFacts)
 	reqIngredientsWriter := newWriter(brokers, TopicIngredientsReq)
+	ctx, cancel := context.WithCancel(context.Background())
 	return RecipeWorker{
 		nutritionService:     nutritionService,
 		recipeReader:         recipeReader,
 		nutritionFactsWriter: nutritionFactsWriter,
 		reqIngredientsWriter: reqIngredientsWriter,
 		ingredientsReader:    ingredientsReader,
+		config:               config,
+		slots:                make(chan struct{}, config.Concurrency),
+		replies:              newIngredientReplies(),
+		routerOnce:           &sync.Once{},
+		ctx:                  ctx,
+		cancel:               cancel,
 	}, nil
 }
 
+func (w RecipeWorker) Name() string {
+	return workerName(w.recipeReader)
+}
+
 func (w RecipeWorker) Process(ctx context.Context) error {
+	// router outlives Process, so it is started only once when worker is restarted
+	w.routerOnce.Do(func() {
+		go w.routeIngredients()
+	})
+
 	for {
+		// wait for a free slot before consuming next recipe
 		select {
 		case <-ctx.Done():
 			return nil
-		default:
+		case <-w.ctx.Done():
+			return nil
+		case w.slots <- struct{}{}:
 		}
 
 		var dto nutrition.RecipeDTO
-		_, err := readDTO(ctx, w.recipeReader, &dto)
+		msgCtx, corID, err := readDTO(ctx, w.recipeReader, &dto)
 		if err != nil {
+			<-w.slots
 			if errors.Is(err, io.EOF) {
 				return err
 			}
@@ -57,64 +103,142 @@ func (w RecipeWorker) Process(ctx context.Context) error {
 		}
 		log.Info().Msgf("got RecipeDTO: %+v", dto)
 
-		if len(dto.ID) > 0 {
-			go w.processRecipe(ctx, dto.Recipe)
+		if len(dto.ID) == 0 {
+			<-w.slots
+			finishSpan(msgCtx, nil)
+			continue
 		}
 
+		go func(recipe nutrition.Recipe) {
+			defer func() { <-w.slots }()
+			// detach from Process context, keeping the message span
+			recipeCtx, cancel := context.WithTimeout(trace.ContextWithSpan(w.ctx, trace.SpanFromContext(msgCtx)), w.config.Timeout)
+			defer cancel()
+			handleMessage(msgCtx, w.recipeReader, func() error {
+				return w.processRecipe(recipeCtx, corID, recipe)
+			}, nil)
+		}(dto.Recipe)
 	}
 }
 
+// Stop waits up to DrainTimeout for in-flight recipes before closing readers and writers
 func (w RecipeWorker) Stop() error {
+	w.drain()
+	w.cancel()
 	return closeAll(w.recipeReader, w.nutritionFactsWriter, w.reqIngredientsWriter, w.ingredientsReader)
 }
 
-func (w RecipeWorker) processRecipe(ctx context.Context, recipe nutrition.Recipe) {
-	ingredients := make(map[string]nutrition.Ingredient, 0)
-	// TODO: process asynchronously or add bulk get API to ingredients-service
-	for _, ingredient := range recipe.Ingredients {
-		dto := nutrition.FindIngredientsDTO{
-			ID: ingredient.IngredientID,
+// drain takes all slots, so no recipe is in flight and no new one is started once it returns
+func (w RecipeWorker) drain() {
+	timer := time.NewTimer(w.config.DrainTimeout)
+	defer timer.Stop()
+	for i := 0; i < cap(w.slots); i++ {
+		select {
+		case w.slots <- struct{}{}:
+		case <-timer.C:
+			log.Warn().Msgf("%d recipes still in flight after %s, cancelling", cap(w.slots)-i, w.config.DrainTimeout)
+			return
 		}
-		corID := generateCorrelationID()
-
-		write(w.reqIngredientsWriter, dto.ID, dto, corID)
-		log.Info().Msgf("sent FindIngredientsDTO: %+v", dto)
+	}
+}
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-			var ingredientDTO nutrition.IngredientDTO
-			gotCorID, err := readDTO(ctx, w.ingredientsReader, &ingredientDTO)
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					return
-				}
-				continue
-			}
-			log.Info().Msgf("got IngredientDTO: %+v", ingredientDTO)
-			if gotCorID != corID {
-				continue
-			}
-			if len(ingredientDTO.Error) > 0 {
-				log.Error().Msgf("failed to get ingredient: %s", ingredientDTO.Error)
-				// TODO:
+// routeIngredients reads ingredient replies and passes them to recipes waiting for them
+func (w RecipeWorker) routeIngredients() {
+	for {
+		var ingredientDTO nutrition.IngredientDTO
+		ingredientCtx, corID, err := readDTO(w.ctx, w.ingredientsReader, &ingredientDTO)
+		if err != nil {
+			if w.ctx.Err() != nil || errors.Is(err, io.EOF) {
 				return
 			}
-			ingredients[ingredientDTO.ID] = ingredientDTO.Ingredient
-			break
+			continue
 		}
+		log.Info().Msgf("got IngredientDTO: %+v", ingredientDTO)
+		finishSpan(ingredientCtx, nil)
+		w.replies.deliver(corID, ingredientDTO)
+	}
+}
 
+func (w RecipeWorker) processRecipe(ctx context.Context, correlationID string, recipe nutrition.Recipe) error {
+	ingredients := make(map[string]nutrition.Ingredient, 0)
+	// TODO: process asynchronously or add bulk get API to ingredients-service
+	for _, ingredient := range recipe.Ingredients {
+		ingredientDTO, err := w.findIngredient(ctx, ingredient.IngredientID)
+		if err != nil {
+			return err
+		}
+		if len(ingredientDTO.Error) > 0 {
+			log.Error().Msgf("failed to get ingredient: %s", ingredientDTO.Error)
+			// TODO:
+			return fmt.Errorf("failed to get ingredient: %s", ingredientDTO.Error)
+		}
+		ingredients[ingredientDTO.ID] = ingredientDTO.Ingredient
 	}
 
 	recipeNutritionsDTO, err := w.nutritionService.CalcRecipeNutritions(recipe, ingredients)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to calculate recipe nutritions")
-		return
+		return err
 	}
 
-	write(w.nutritionFactsWriter, recipeNutritionsDTO.RecipeID, recipeNutritionsDTO, "")
+	write(ctx, w.nutritionFactsWriter, recipeNutritionsDTO.RecipeID, recipeNutritionsDTO, correlationID)
 	log.Info().Msgf("sent RecipeNutritionsDTO: %+v", recipeNutritionsDTO)
+	return nil
+}
+
+func (w RecipeWorker) findIngredient(ctx context.Context, id string) (nutrition.IngredientDTO, error) {
+	dto := nutrition.FindIngredientsDTO{
+		ID: id,
+	}
+	corID := generateCorrelationID()
+	reply := w.replies.expect(corID)
+	defer w.replies.forget(corID)
+
+	write(ctx, w.reqIngredientsWriter, dto.ID, dto, corID)
+	log.Info().Msgf("sent FindIngredientsDTO: %+v", dto)
+
+	select {
+	case <-ctx.Done():
+		return nutrition.IngredientDTO{}, ctx.Err()
+	case ingredientDTO := <-reply:
+		return ingredientDTO, nil
+	}
+}
+
+// ingredientReplies matches ingredient replies with requests by correlation ID
+type ingredientReplies struct {
+	mu      sync.Mutex
+	waiting map[string]chan nutrition.IngredientDTO
+}
+
+func newIngredientReplies() *ingredientReplies {
+	return &ingredientReplies{
+		waiting: make(map[string]chan nutrition.IngredientDTO),
+	}
+}
+
+func (r *ingredientReplies) expect(correlationID string) <-chan nutrition.IngredientDTO {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reply := make(chan nutrition.IngredientDTO, 1)
+	r.waiting[correlationID] = reply
+	return reply
+}
+
+func (r *ingredientReplies) forget(correlationID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.waiting, correlationID)
+}
+
+// deliver drops replies nobody waits for, e.g. addressed to other instances or timed out
+func (r *ingredientReplies) deliver(correlationID string, dto nutrition.IngredientDTO) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reply, ok := r.waiting[correlationID]
+	if !ok {
+		return
+	}
+	delete(r.waiting, correlationID)
+	reply <- dto
 }
@@ -2,6 +2,7 @@ package nutrition
 
 type Ingredient struct {
 	ID             string          `json:"id"`
+	Name           string          `json:"name"`
 	BaseUnit       string          `json:"base_unit"`
 	NutritionFacts *NutritionFacts `json:"nutrition_facts"`
 }
@@ -30,6 +31,8 @@ type RecipeNutritionsDTO struct {
 	RecipeID       string         `json:"recipe_id"`
 	NutritionFacts NutritionFacts `json:"nutrition_facts"`
 	Inaccurate     bool           `json:"is_inaccurate"`
+	// IngredientNames are names of ingredients of recipe, used by recipe-service for search
+	IngredientNames []string `json:"ingredient_names,omitempty"`
 }
 
 type Recipe struct {
@@ -28,8 +28,12 @@ func (s service) CalcRecipeNutritions(recipe nutrition.Recipe, ingredients map[s
 	inaccurate := false
 
 	unknown := 0.0
+	var names []string
 	for _, ing := range recipe.Ingredients {
 		ingredient, ok := ingredients[ing.IngredientID]
+		if ok && len(ingredient.Name) > 0 {
+			names = append(names, ingredient.Name)
+		}
 		if ok && ingredient.NutritionFacts != nil && ing.Unit == ingredient.BaseUnit { // TODO: unit conversion
 			facts.Calories += ing.Amount * ingredient.NutritionFacts.Calories
 			facts.Fats += ing.Amount * ingredient.NutritionFacts.Fats
@@ -49,9 +53,10 @@ func (s service) CalcRecipeNutritions(recipe nutrition.Recipe, ingredients map[s
 	}
 
 	result = nutrition.RecipeNutritionsDTO{
-		RecipeID:       recipe.ID,
-		NutritionFacts: facts,
-		Inaccurate:     inaccurate,
+		RecipeID:        recipe.ID,
+		NutritionFacts:  facts,
+		Inaccurate:      inaccurate,
+		IngredientNames: names,
 	}
 
 	return
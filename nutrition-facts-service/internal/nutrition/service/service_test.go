@@ -170,6 +170,50 @@ func Test_service_CalcRecipeNutritions(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "ingredient names",
+			ingredients: map[string]nutrition.Ingredient{
+				"1": {
+					ID:       "1",
+					Name:     "Мука",
+					BaseUnit: "г",
+					NutritionFacts: &nutrition.NutritionFacts{
+						Calories: 3,
+					},
+				},
+				"2": {
+					ID:       "2",
+					Name:     "Молоко",
+					BaseUnit: "мл",
+					NutritionFacts: &nutrition.NutritionFacts{
+						Calories: 0.5,
+					},
+				},
+			},
+			recipe: nutrition.Recipe{
+				ID: "1",
+				Ingredients: []nutrition.RecipeIngredient{
+					{
+						IngredientID: "2",
+						Unit:         "мл",
+						Amount:       200,
+					}, {
+						IngredientID: "1",
+						Unit:         "г",
+						Amount:       100,
+					},
+				},
+			},
+			wantResult: nutrition.RecipeNutritionsDTO{
+				RecipeID: "1",
+				NutritionFacts: nutrition.NutritionFacts{
+					Calories: 200*0.5 + 100*3,
+				},
+				Inaccurate:      false,
+				IngredientNames: []string{"Молоко", "Мука"},
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
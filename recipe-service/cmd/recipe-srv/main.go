@@ -9,9 +9,12 @@ import (
 	"time"
 
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/config"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/controller/http"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/controller/kafka"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/health"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/storage/mongodb"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/tracing"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -31,6 +34,11 @@ func main() {
 	}
 	log.Logger = log.Logger.Level(logLevel)
 
+	shutdownTracing, err := tracing.Init(config.Config.Tracing.Exporter, config.Config.Tracing.OTLPEndpoint, config.Config.Tracing.File)
+	if err != nil {
+		log.Fatal().Err(err).Msg("could not initialize tracing")
+	}
+
 	stor, err := mongodb.NewStorage(config.Config.Mongo.DSN, config.Config.Mongo.DB)
 	if err != nil {
 		log.Fatal().Err(err).Msg("could not initialize storage")
@@ -39,7 +47,8 @@ func main() {
 
 	recipeService := service.NewService(stor)
 
-	controller, err := kafka.NewController(recipeService, config.Config.Kafka.Brokers)
+	controller, err := kafka.NewController(recipeService, config.Config.Kafka.Brokers, config.Config.UserDeletion,
+		config.Config.Kafka.TrustedServices)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize kafka controller")
 	}
@@ -53,6 +62,20 @@ func main() {
 		}
 	}()
 
+	checker := health.NewChecker()
+	checker.AddLivenessCheck("kafka", controller.Live)
+	checker.AddReadinessCheck("kafka", controller.Ready)
+	checker.AddReadinessCheck("mongodb", stor.Ping)
+
+	httpController := http.NewController(config.Config.HTTP.Address, checker)
+	go func() {
+		err := httpController.Run(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error running http server")
+		}
+	}()
+	log.Info().Msgf("serving metrics and health checks on %s", config.Config.HTTP.Address)
+
 	terminateSignal := make(chan os.Signal, 1)
 	signal.Notify(terminateSignal, syscall.SIGINT, syscall.SIGTERM)
 
@@ -62,6 +85,16 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("controller failed to stop properly")
 	}
+	err = httpController.Stop()
+	if err != nil {
+		log.Fatal().Err(err).Msg("http server failed to stop properly")
+	}
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	err = shutdownTracing(shutdownCtx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to flush traces")
+	}
 
 	log.Info().Msg("recipe service interrupted via system signal")
 }
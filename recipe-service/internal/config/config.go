@@ -18,7 +18,17 @@ type config struct {
 		DB  string `env:"MONGO_DB"`
 	}
 	Kafka struct {
-		Brokers string `env:"KAFKA_BROKERS"`
+		Brokers         string `env:"KAFKA_BROKERS"`
+		TrustedServices string `env:"KAFKA_TRUSTED_SERVICES"`
+	}
+	UserDeletion string `env:"USER_DELETION"`
+	HTTP         struct {
+		Address string `env:"HTTP_ADDRESS"`
+	}
+	Tracing struct {
+		Exporter     string `env:"TRACING_EXPORTER"`
+		OTLPEndpoint string `env:"TRACING_OTLP_ENDPOINT"`
+		File         string `env:"TRACING_FILE"`
 	}
 }
 
@@ -27,6 +37,12 @@ func Parse() error {
 	flag.StringVar(&Config.Mongo.DSN, "mongo-dsn", "", "mongodb connection string")
 	flag.StringVar(&Config.Mongo.DB, "mongo-db", "", "mongodb database name")
 	flag.StringVar(&Config.Kafka.Brokers, "kafka-brokers", "", "kafka broker list")
+	flag.StringVar(&Config.Kafka.TrustedServices, "kafka-trusted-services", "", "services allowed to make requests without user headers")
+	flag.StringVar(&Config.UserDeletion, "user-deletion", "anonymize", "what to do with recipes of deleted user: anonymize or delete")
+	flag.StringVar(&Config.HTTP.Address, "http-address", ":8080", "address of HTTP server exposing metrics and health checks")
+	flag.StringVar(&Config.Tracing.Exporter, "tracing-exporter", "", "trace exporter: otlp, file or empty to disable")
+	flag.StringVar(&Config.Tracing.OTLPEndpoint, "tracing-otlp-endpoint", "localhost:4317", "OTLP gRPC collector endpoint")
+	flag.StringVar(&Config.Tracing.File, "tracing-file", "traces.json", "file to write traces to with file exporter")
 	flag.Parse()
 
 	err := env.Parse(&Config)
@@ -31,6 +31,10 @@ func NewAddRecipeWorker(recipeService service.Service, brokers []string) (Worker
 	}, nil
 }
 
+func (w AddRecipeWorker) Name() string {
+	return workerName(w.newRecipeReader)
+}
+
 func (w AddRecipeWorker) Process(ctx context.Context) error {
 	for {
 		select {
@@ -40,7 +44,7 @@ func (w AddRecipeWorker) Process(ctx context.Context) error {
 		}
 
 		var dto recipe.CreateRecipeDTO
-		corID, err := readDTO(ctx, w.newRecipeReader, &dto)
+		msgCtx, corID, err := readDTO(ctx, w.newRecipeReader, &dto)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				return err
@@ -49,27 +53,37 @@ func (w AddRecipeWorker) Process(ctx context.Context) error {
 		}
 		log.Info().Msgf("got CreateRecipeDTO: %+v", dto)
 
-		cntx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		id, err := w.recipeService.Create(cntx, dto)
-		cancel()
-		recipeDTO := recipe.RecipeDTO{
-			ID: id,
-		}
-		if err != nil {
-			log.Error().Err(err).Msg("failed to add recipe")
-			recipeDTO.Error = err.Error()
-		} else {
-			recipeDTO.Recipe = recipe.Recipe{
-				ID:          id,
-				Name:        dto.Name,
-				CreatedBy:   dto.CreatedBy,
-				Ingredients: dto.Ingredients,
-				Steps:       dto.Steps,
+		handleMessage(msgCtx, w.newRecipeReader, func() error {
+			var id string
+			var recip recipe.Recipe
+			var err error
+			if err = checkUser(msgCtx, dto.CreatedBy, true); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				id, err = w.recipeService.Create(cntx, dto)
+				if err == nil {
+					// categories and tags are normalized by service
+					recip, err = w.recipeService.GetByID(cntx, id)
+				}
+				cancel()
+			}
+			recipeDTO := recipe.RecipeDTO{
+				ID: id,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to add recipe")
+				recipeDTO.Error = err.Error()
+			} else {
+				recipeDTO.Recipe = recip
 			}
-		}
 
-		write(w.recipesWriter, dto.Name, recipeDTO, corID)
-		log.Info().Msgf("sent RecipeDTO: %+v", recipeDTO)
+			write(msgCtx, w.recipesWriter, dto.Name, recipeDTO, corID)
+			log.Info().Msgf("sent RecipeDTO: %+v", recipeDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.recipesWriter, dto.Name, recipe.RecipeDTO{
+				Error: err.Error(),
+			}, corID)
+		})
 	}
 
 }
@@ -0,0 +1,239 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
+)
+
+type CreateCategoryWorker struct {
+	recipeService     service.Service
+	newCategoryReader *kafka.Reader
+	categoriesWriter  *kafka.Writer
+}
+
+func NewCreateCategoryWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	newCategoryReader, err := newReader(brokers, "recipe-service-category", TopicCategoriesNew)
+	if err != nil {
+		return nil, err
+	}
+	categoriesWriter := newWriter(brokers, TopicCategories)
+	return CreateCategoryWorker{
+		recipeService:     recipeService,
+		newCategoryReader: newCategoryReader,
+		categoriesWriter:  categoriesWriter,
+	}, nil
+}
+
+func (w CreateCategoryWorker) Name() string {
+	return workerName(w.newCategoryReader)
+}
+
+func (w CreateCategoryWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.CreateCategoryDTO
+		msgCtx, corID, err := readDTO(ctx, w.newCategoryReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got CreateCategoryDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.newCategoryReader, func() error {
+			var category recipe.Category
+			var err error
+			if !canManageCategories(msgCtx) {
+				err = apperror.ErrForbidden
+			} else {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				category, err = w.recipeService.CreateCategory(cntx, dto)
+				cancel()
+			}
+			categoryDTO := recipe.CategoryDTO{
+				ID: category.ID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to create category")
+				categoryDTO.Error = err.Error()
+			} else {
+				categoryDTO.Category = category
+			}
+
+			write(msgCtx, w.categoriesWriter, dto.Name, categoryDTO, corID)
+			log.Info().Msgf("sent CategoryDTO: %+v", categoryDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.categoriesWriter, dto.Name, recipe.CategoryDTO{
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w CreateCategoryWorker) Stop() error {
+	return closeAll(w.newCategoryReader, w.categoriesWriter)
+}
+
+type DeleteCategoryWorker struct {
+	recipeService        service.Service
+	deleteCategoryReader *kafka.Reader
+	categoriesWriter     *kafka.Writer
+}
+
+func NewDeleteCategoryWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	deleteCategoryReader, err := newReader(brokers, "recipe-service-category-delete", TopicCategoriesDelete)
+	if err != nil {
+		return nil, err
+	}
+	categoriesWriter := newWriter(brokers, TopicCategories)
+	return DeleteCategoryWorker{
+		recipeService:        recipeService,
+		deleteCategoryReader: deleteCategoryReader,
+		categoriesWriter:     categoriesWriter,
+	}, nil
+}
+
+func (w DeleteCategoryWorker) Name() string {
+	return workerName(w.deleteCategoryReader)
+}
+
+func (w DeleteCategoryWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.DeleteCategoryDTO
+		msgCtx, corID, err := readDTO(ctx, w.deleteCategoryReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got DeleteCategoryDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.deleteCategoryReader, func() error {
+			var err error
+			if !canManageCategories(msgCtx) {
+				err = apperror.ErrForbidden
+			} else {
+				cntx, cancel := context.WithTimeout(msgCtx, 30*time.Second)
+				err = w.recipeService.DeleteCategory(cntx, dto.ID)
+				cancel()
+			}
+			categoryDTO := recipe.CategoryDTO{
+				ID: dto.ID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to delete category")
+				categoryDTO.Error = err.Error()
+			}
+
+			write(msgCtx, w.categoriesWriter, dto.ID, categoryDTO, corID)
+			log.Info().Msgf("sent CategoryDTO: %+v", categoryDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.categoriesWriter, dto.ID, recipe.CategoryDTO{
+				ID:    dto.ID,
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w DeleteCategoryWorker) Stop() error {
+	return closeAll(w.deleteCategoryReader, w.categoriesWriter)
+}
+
+type FindCategoriesWorker struct {
+	recipeService       service.Service
+	reqCategoriesReader *kafka.Reader
+	categoriesWriter    *kafka.Writer
+}
+
+func NewFindCategoriesWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	reqCategoriesReader, err := newReader(brokers, "recipe-service-find-categories", TopicCategoriesReq)
+	if err != nil {
+		return nil, err
+	}
+	categoriesWriter := newWriter(brokers, TopicCategories)
+	return FindCategoriesWorker{
+		recipeService:       recipeService,
+		reqCategoriesReader: reqCategoriesReader,
+		categoriesWriter:    categoriesWriter,
+	}, nil
+}
+
+func (w FindCategoriesWorker) Name() string {
+	return workerName(w.reqCategoriesReader)
+}
+
+func (w FindCategoriesWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.FindCategoriesDTO
+		msgCtx, corID, err := readDTO(ctx, w.reqCategoriesReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got FindCategoriesDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.reqCategoriesReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			categories, err := w.recipeService.GetCategories(cntx, dto.Kind)
+			cancel()
+
+			if err != nil {
+				log.Error().Err(err).Msg("failed to find categories")
+				write(msgCtx, w.categoriesWriter, dto.Kind, recipe.CategoryDTO{
+					Stream: streamDone(0),
+					Error:  err.Error(),
+				}, corID)
+				return err
+			}
+			for i, categoryDTO := range categories {
+				categoryDTO.Stream = streamPart(i, len(categories))
+				write(msgCtx, w.categoriesWriter, dto.Kind, categoryDTO, corID)
+			}
+			write(msgCtx, w.categoriesWriter, dto.Kind, recipe.CategoryDTO{
+				Stream: streamDone(len(categories)),
+			}, corID)
+			log.Info().Msgf("sent %d categories", len(categories))
+			return nil
+		}, func(err error) {
+			write(msgCtx, w.categoriesWriter, dto.Kind, recipe.CategoryDTO{
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w FindCategoriesWorker) Stop() error {
+	return closeAll(w.reqCategoriesReader, w.categoriesWriter)
+}
@@ -0,0 +1,585 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
+)
+
+type UpdateFavoriteWorker struct {
+	recipeService        service.Service
+	updateFavoriteReader *kafka.Reader
+	favoritesWriter      *kafka.Writer
+}
+
+func NewUpdateFavoriteWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	updateFavoriteReader, err := newReader(brokers, "recipe-service-favorite", TopicFavoritesUpdate)
+	if err != nil {
+		return nil, err
+	}
+	favoritesWriter := newWriter(brokers, TopicFavorites)
+	return UpdateFavoriteWorker{
+		recipeService:        recipeService,
+		updateFavoriteReader: updateFavoriteReader,
+		favoritesWriter:      favoritesWriter,
+	}, nil
+}
+
+func (w UpdateFavoriteWorker) Name() string {
+	return workerName(w.updateFavoriteReader)
+}
+
+func (w UpdateFavoriteWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.UpdateFavoriteDTO
+		msgCtx, corID, err := readDTO(ctx, w.updateFavoriteReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got UpdateFavoriteDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.updateFavoriteReader, func() error {
+			var favorite recipe.Favorite
+			var err error
+			// requests without the header come from trusted internal clients
+			if err = checkUser(msgCtx, dto.UserID, false); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				if dto.Remove {
+					err = w.recipeService.RemoveFavorite(cntx, dto.UserID, dto.RecipeID)
+				} else {
+					favorite, err = w.recipeService.AddFavorite(cntx, dto.UserID, dto.RecipeID)
+				}
+				cancel()
+			}
+			favoriteDTO := recipe.FavoriteDTO{
+				Favorite: favorite,
+				UserID:   dto.UserID,
+				RecipeID: dto.RecipeID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to update favorites")
+				favoriteDTO.Error = err.Error()
+			}
+
+			write(msgCtx, w.favoritesWriter, dto.UserID, favoriteDTO, corID)
+			log.Info().Msgf("sent FavoriteDTO: %+v", favoriteDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.favoritesWriter, dto.UserID, recipe.FavoriteDTO{
+				UserID:   dto.UserID,
+				RecipeID: dto.RecipeID,
+				Error:    err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w UpdateFavoriteWorker) Stop() error {
+	return closeAll(w.updateFavoriteReader, w.favoritesWriter)
+}
+
+type FindFavoritesWorker struct {
+	recipeService      service.Service
+	reqFavoritesReader *kafka.Reader
+	favoritesWriter    *kafka.Writer
+}
+
+func NewFindFavoritesWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	reqFavoritesReader, err := newReader(brokers, "recipe-service-find-favorites", TopicFavoritesReq)
+	if err != nil {
+		return nil, err
+	}
+	favoritesWriter := newWriter(brokers, TopicFavorites)
+	return FindFavoritesWorker{
+		recipeService:      recipeService,
+		reqFavoritesReader: reqFavoritesReader,
+		favoritesWriter:    favoritesWriter,
+	}, nil
+}
+
+func (w FindFavoritesWorker) Name() string {
+	return workerName(w.reqFavoritesReader)
+}
+
+func (w FindFavoritesWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.FindFavoritesDTO
+		msgCtx, corID, err := readDTO(ctx, w.reqFavoritesReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got FindFavoritesDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.reqFavoritesReader, func() error {
+			var favorites []recipe.Favorite
+			var err error
+			// favorites are private
+			if err = checkUser(msgCtx, dto.UserID, false); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				favorites, err = w.recipeService.GetFavorites(cntx, dto.UserID)
+				cancel()
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to find favorites")
+				write(msgCtx, w.favoritesWriter, dto.UserID, recipe.FavoriteDTO{
+					UserID: dto.UserID,
+					Stream: streamDone(0),
+					Error:  err.Error(),
+				}, corID)
+				return err
+			}
+			for i, favorite := range favorites {
+				write(msgCtx, w.favoritesWriter, dto.UserID, recipe.FavoriteDTO{
+					Favorite: favorite,
+					UserID:   dto.UserID,
+					RecipeID: favorite.RecipeID,
+					Stream:   streamPart(i, len(favorites)),
+				}, corID)
+			}
+			write(msgCtx, w.favoritesWriter, dto.UserID, recipe.FavoriteDTO{
+				UserID: dto.UserID,
+				Stream: streamDone(len(favorites)),
+			}, corID)
+			log.Info().Msgf("sent %d favorites of user %s", len(favorites), dto.UserID)
+			return nil
+		}, func(err error) {
+			write(msgCtx, w.favoritesWriter, dto.UserID, recipe.FavoriteDTO{
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w FindFavoritesWorker) Stop() error {
+	return closeAll(w.reqFavoritesReader, w.favoritesWriter)
+}
+
+type CreateCollectionWorker struct {
+	recipeService       service.Service
+	newCollectionReader *kafka.Reader
+	collectionsWriter   *kafka.Writer
+}
+
+func NewCreateCollectionWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	newCollectionReader, err := newReader(brokers, "recipe-service-collection", TopicCollectionsNew)
+	if err != nil {
+		return nil, err
+	}
+	collectionsWriter := newWriter(brokers, TopicCollections)
+	return CreateCollectionWorker{
+		recipeService:       recipeService,
+		newCollectionReader: newCollectionReader,
+		collectionsWriter:   collectionsWriter,
+	}, nil
+}
+
+func (w CreateCollectionWorker) Name() string {
+	return workerName(w.newCollectionReader)
+}
+
+func (w CreateCollectionWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.CreateCollectionDTO
+		msgCtx, corID, err := readDTO(ctx, w.newCollectionReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got CreateCollectionDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.newCollectionReader, func() error {
+			var collection recipe.Collection
+			var err error
+			if err = checkUser(msgCtx, dto.UserID, false); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				collection, err = w.recipeService.CreateCollection(cntx, dto)
+				cancel()
+			}
+			collectionDTO := recipe.CollectionDTO{
+				ID:     collection.ID,
+				UserID: dto.UserID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to create collection")
+				collectionDTO.Error = err.Error()
+			} else {
+				collectionDTO.Collection = collection
+			}
+
+			write(msgCtx, w.collectionsWriter, dto.UserID, collectionDTO, corID)
+			log.Info().Msgf("sent CollectionDTO %s of user %s", collectionDTO.ID, dto.UserID)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.collectionsWriter, dto.UserID, recipe.CollectionDTO{
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w CreateCollectionWorker) Stop() error {
+	return closeAll(w.newCollectionReader, w.collectionsWriter)
+}
+
+type UpdateCollectionWorker struct {
+	recipeService          service.Service
+	updateCollectionReader *kafka.Reader
+	collectionsWriter      *kafka.Writer
+}
+
+func NewUpdateCollectionWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	updateCollectionReader, err := newReader(brokers, "recipe-service-collection-update", TopicCollectionsUpdate)
+	if err != nil {
+		return nil, err
+	}
+	collectionsWriter := newWriter(brokers, TopicCollections)
+	return UpdateCollectionWorker{
+		recipeService:          recipeService,
+		updateCollectionReader: updateCollectionReader,
+		collectionsWriter:      collectionsWriter,
+	}, nil
+}
+
+func (w UpdateCollectionWorker) Name() string {
+	return workerName(w.updateCollectionReader)
+}
+
+func (w UpdateCollectionWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.UpdateCollectionDTO
+		msgCtx, corID, err := readDTO(ctx, w.updateCollectionReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got UpdateCollectionDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.updateCollectionReader, func() error {
+			var collection recipe.Collection
+			var err error
+			if err = checkUser(msgCtx, dto.UserID, false); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				collection, err = w.recipeService.UpdateCollection(cntx, dto)
+				cancel()
+			}
+			collectionDTO := recipe.CollectionDTO{
+				ID:     dto.ID,
+				UserID: dto.UserID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to update collection")
+				collectionDTO.Error = err.Error()
+			} else {
+				collectionDTO.Collection = collection
+			}
+
+			write(msgCtx, w.collectionsWriter, dto.UserID, collectionDTO, corID)
+			log.Info().Msgf("sent CollectionDTO %s of user %s", collectionDTO.ID, dto.UserID)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.collectionsWriter, dto.UserID, recipe.CollectionDTO{
+				ID:     dto.ID,
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w UpdateCollectionWorker) Stop() error {
+	return closeAll(w.updateCollectionReader, w.collectionsWriter)
+}
+
+type CollectionItemWorker struct {
+	recipeService        service.Service
+	collectionItemReader *kafka.Reader
+	collectionsWriter    *kafka.Writer
+}
+
+func NewCollectionItemWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	collectionItemReader, err := newReader(brokers, "recipe-service-collection-item", TopicCollectionsItems)
+	if err != nil {
+		return nil, err
+	}
+	collectionsWriter := newWriter(brokers, TopicCollections)
+	return CollectionItemWorker{
+		recipeService:        recipeService,
+		collectionItemReader: collectionItemReader,
+		collectionsWriter:    collectionsWriter,
+	}, nil
+}
+
+func (w CollectionItemWorker) Name() string {
+	return workerName(w.collectionItemReader)
+}
+
+func (w CollectionItemWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.CollectionItemDTO
+		msgCtx, corID, err := readDTO(ctx, w.collectionItemReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got CollectionItemDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.collectionItemReader, func() error {
+			var collection recipe.Collection
+			var err error
+			if err = checkUser(msgCtx, dto.UserID, false); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				collection, err = w.recipeService.UpdateCollectionItem(cntx, dto)
+				cancel()
+			}
+			collectionDTO := recipe.CollectionDTO{
+				ID:     dto.CollectionID,
+				UserID: dto.UserID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to update collection")
+				collectionDTO.Error = err.Error()
+			} else {
+				collectionDTO.Collection = collection
+			}
+
+			write(msgCtx, w.collectionsWriter, dto.UserID, collectionDTO, corID)
+			log.Info().Msgf("sent CollectionDTO %s of user %s", collectionDTO.ID, dto.UserID)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.collectionsWriter, dto.UserID, recipe.CollectionDTO{
+				ID:     dto.CollectionID,
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w CollectionItemWorker) Stop() error {
+	return closeAll(w.collectionItemReader, w.collectionsWriter)
+}
+
+type DeleteCollectionWorker struct {
+	recipeService          service.Service
+	deleteCollectionReader *kafka.Reader
+	collectionsWriter      *kafka.Writer
+}
+
+func NewDeleteCollectionWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	deleteCollectionReader, err := newReader(brokers, "recipe-service-collection-delete", TopicCollectionsDelete)
+	if err != nil {
+		return nil, err
+	}
+	collectionsWriter := newWriter(brokers, TopicCollections)
+	return DeleteCollectionWorker{
+		recipeService:          recipeService,
+		deleteCollectionReader: deleteCollectionReader,
+		collectionsWriter:      collectionsWriter,
+	}, nil
+}
+
+func (w DeleteCollectionWorker) Name() string {
+	return workerName(w.deleteCollectionReader)
+}
+
+func (w DeleteCollectionWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.DeleteCollectionDTO
+		msgCtx, corID, err := readDTO(ctx, w.deleteCollectionReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got DeleteCollectionDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.deleteCollectionReader, func() error {
+			var err error
+			if err = checkUser(msgCtx, dto.UserID, false); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				err = w.recipeService.DeleteCollection(cntx, dto)
+				cancel()
+			}
+			collectionDTO := recipe.CollectionDTO{
+				ID:     dto.ID,
+				UserID: dto.UserID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to delete collection")
+				collectionDTO.Error = err.Error()
+			}
+
+			write(msgCtx, w.collectionsWriter, dto.UserID, collectionDTO, corID)
+			log.Info().Msgf("sent CollectionDTO %s of user %s", collectionDTO.ID, dto.UserID)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.collectionsWriter, dto.UserID, recipe.CollectionDTO{
+				ID:     dto.ID,
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w DeleteCollectionWorker) Stop() error {
+	return closeAll(w.deleteCollectionReader, w.collectionsWriter)
+}
+
+type FindCollectionsWorker struct {
+	recipeService        service.Service
+	reqCollectionsReader *kafka.Reader
+	collectionsWriter    *kafka.Writer
+}
+
+func NewFindCollectionsWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	reqCollectionsReader, err := newReader(brokers, "recipe-service-find-collections", TopicCollectionsReq)
+	if err != nil {
+		return nil, err
+	}
+	collectionsWriter := newWriter(brokers, TopicCollections)
+	return FindCollectionsWorker{
+		recipeService:        recipeService,
+		reqCollectionsReader: reqCollectionsReader,
+		collectionsWriter:    collectionsWriter,
+	}, nil
+}
+
+func (w FindCollectionsWorker) Name() string {
+	return workerName(w.reqCollectionsReader)
+}
+
+func (w FindCollectionsWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.FindCollectionsDTO
+		msgCtx, corID, err := readDTO(ctx, w.reqCollectionsReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got FindCollectionsDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.reqCollectionsReader, func() error {
+			// private collections are visible only to their owner
+			viewerID, _ := header(msgCtx, KeyUserID)
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			defer cancel()
+
+			if len(dto.ID) > 0 {
+				collection, err := w.recipeService.GetCollection(cntx, dto.ID, viewerID)
+				collectionDTO := recipe.CollectionDTO{
+					ID:     dto.ID,
+					UserID: collection.UserID,
+				}
+				if err != nil {
+					log.Error().Err(err).Msg("failed to find collection")
+					collectionDTO.Error = err.Error()
+				} else {
+					collectionDTO.Collection = collection
+				}
+				write(msgCtx, w.collectionsWriter, collection.UserID, collectionDTO, corID)
+				return err
+			}
+
+			collections, err := w.recipeService.GetCollections(cntx, dto.UserID, viewerID)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to find collections")
+				write(msgCtx, w.collectionsWriter, dto.UserID, recipe.CollectionDTO{
+					UserID: dto.UserID,
+					Stream: streamDone(0),
+					Error:  err.Error(),
+				}, corID)
+				return err
+			}
+			for i, collection := range collections {
+				write(msgCtx, w.collectionsWriter, dto.UserID, recipe.CollectionDTO{
+					Collection: collection,
+					ID:         collection.ID,
+					UserID:     dto.UserID,
+					Stream:     streamPart(i, len(collections)),
+				}, corID)
+			}
+			write(msgCtx, w.collectionsWriter, dto.UserID, recipe.CollectionDTO{
+				UserID: dto.UserID,
+				Stream: streamDone(len(collections)),
+			}, corID)
+			log.Info().Msgf("sent %d collections of user %s", len(collections), dto.UserID)
+			return nil
+		}, func(err error) {
+			write(msgCtx, w.collectionsWriter, dto.UserID, recipe.CollectionDTO{
+				ID:     dto.ID,
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w FindCollectionsWorker) Stop() error {
+	return closeAll(w.reqCollectionsReader, w.collectionsWriter)
+}
@@ -0,0 +1,345 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
+)
+
+type AddCommentWorker struct {
+	recipeService    service.Service
+	newCommentReader *kafka.Reader
+	commentsWriter   *kafka.Writer
+	createdWriter    *kafka.Writer
+}
+
+func NewAddCommentWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	newCommentReader, err := newReader(brokers, "recipe-service-comment", TopicCommentsNew)
+	if err != nil {
+		return nil, err
+	}
+	commentsWriter := newWriter(brokers, TopicComments)
+	createdWriter := newWriter(brokers, TopicCommentsCreated)
+	return AddCommentWorker{
+		recipeService:    recipeService,
+		newCommentReader: newCommentReader,
+		commentsWriter:   commentsWriter,
+		createdWriter:    createdWriter,
+	}, nil
+}
+
+func (w AddCommentWorker) Name() string {
+	return workerName(w.newCommentReader)
+}
+
+func (w AddCommentWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.AddCommentDTO
+		msgCtx, corID, err := readDTO(ctx, w.newCommentReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got AddCommentDTO for recipe %s by user %s", dto.RecipeID, dto.UserID)
+
+		handleMessage(msgCtx, w.newCommentReader, func() error {
+			var comment recipe.Comment
+			var event recipe.CommentCreatedDTO
+			var err error
+			if err = checkUser(msgCtx, dto.UserID, true); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				comment, event, err = w.recipeService.AddComment(cntx, dto)
+				cancel()
+			}
+			commentDTO := recipe.CommentDTO{
+				Comment:  comment,
+				ID:       comment.ID,
+				RecipeID: dto.RecipeID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to add comment")
+				commentDTO.Error = err.Error()
+			} else {
+				write(msgCtx, w.createdWriter, dto.RecipeID, event, corID)
+			}
+
+			write(msgCtx, w.commentsWriter, dto.RecipeID, commentDTO, corID)
+			log.Info().Msgf("sent CommentDTO %s for recipe %s", comment.ID, dto.RecipeID)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.commentsWriter, dto.RecipeID, recipe.CommentDTO{
+				RecipeID: dto.RecipeID,
+				Error:    err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w AddCommentWorker) Stop() error {
+	return closeAll(w.newCommentReader, w.commentsWriter, w.createdWriter)
+}
+
+type EditCommentWorker struct {
+	recipeService     service.Service
+	editCommentReader *kafka.Reader
+	commentsWriter    *kafka.Writer
+}
+
+func NewEditCommentWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	editCommentReader, err := newReader(brokers, "recipe-service-comment-edit", TopicCommentsUpdate)
+	if err != nil {
+		return nil, err
+	}
+	commentsWriter := newWriter(brokers, TopicComments)
+	return EditCommentWorker{
+		recipeService:     recipeService,
+		editCommentReader: editCommentReader,
+		commentsWriter:    commentsWriter,
+	}, nil
+}
+
+func (w EditCommentWorker) Name() string {
+	return workerName(w.editCommentReader)
+}
+
+func (w EditCommentWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.EditCommentDTO
+		msgCtx, corID, err := readDTO(ctx, w.editCommentReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got EditCommentDTO for comment %s by user %s", dto.ID, dto.UserID)
+
+		handleMessage(msgCtx, w.editCommentReader, func() error {
+			var comment recipe.Comment
+			var err error
+			if err = checkUser(msgCtx, dto.UserID, false); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				comment, err = w.recipeService.EditComment(cntx, dto)
+				cancel()
+			}
+			commentDTO := recipe.CommentDTO{
+				ID:       dto.ID,
+				RecipeID: comment.RecipeID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to edit comment")
+				commentDTO.Error = err.Error()
+			} else {
+				commentDTO.Comment = comment
+			}
+
+			write(msgCtx, w.commentsWriter, comment.RecipeID, commentDTO, corID)
+			log.Info().Msgf("sent CommentDTO %s for recipe %s", dto.ID, comment.RecipeID)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.commentsWriter, dto.ID, recipe.CommentDTO{
+				ID:    dto.ID,
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w EditCommentWorker) Stop() error {
+	return closeAll(w.editCommentReader, w.commentsWriter)
+}
+
+type DeleteCommentWorker struct {
+	recipeService       service.Service
+	deleteCommentReader *kafka.Reader
+	commentsWriter      *kafka.Writer
+}
+
+func NewDeleteCommentWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	deleteCommentReader, err := newReader(brokers, "recipe-service-comment-delete", TopicCommentsDelete)
+	if err != nil {
+		return nil, err
+	}
+	commentsWriter := newWriter(brokers, TopicComments)
+	return DeleteCommentWorker{
+		recipeService:       recipeService,
+		deleteCommentReader: deleteCommentReader,
+		commentsWriter:      commentsWriter,
+	}, nil
+}
+
+func (w DeleteCommentWorker) Name() string {
+	return workerName(w.deleteCommentReader)
+}
+
+func (w DeleteCommentWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.DeleteCommentDTO
+		msgCtx, corID, err := readDTO(ctx, w.deleteCommentReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got DeleteCommentDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.deleteCommentReader, func() error {
+			var comment recipe.Comment
+			var err error
+			if err = checkUser(msgCtx, dto.UserID, false); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				comment, err = w.recipeService.DeleteComment(cntx, dto)
+				cancel()
+			}
+			commentDTO := recipe.CommentDTO{
+				ID:       dto.ID,
+				RecipeID: comment.RecipeID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to delete comment")
+				commentDTO.Error = err.Error()
+			} else {
+				commentDTO.Comment = comment
+			}
+
+			write(msgCtx, w.commentsWriter, comment.RecipeID, commentDTO, corID)
+			log.Info().Msgf("sent CommentDTO %s for recipe %s", dto.ID, comment.RecipeID)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.commentsWriter, dto.ID, recipe.CommentDTO{
+				ID:    dto.ID,
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w DeleteCommentWorker) Stop() error {
+	return closeAll(w.deleteCommentReader, w.commentsWriter)
+}
+
+type FindCommentsWorker struct {
+	recipeService     service.Service
+	reqCommentsReader *kafka.Reader
+	commentsWriter    *kafka.Writer
+}
+
+func NewFindCommentsWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	reqCommentsReader, err := newReader(brokers, "recipe-service-find-comments", TopicCommentsReq)
+	if err != nil {
+		return nil, err
+	}
+	commentsWriter := newWriter(brokers, TopicComments)
+	return FindCommentsWorker{
+		recipeService:     recipeService,
+		reqCommentsReader: reqCommentsReader,
+		commentsWriter:    commentsWriter,
+	}, nil
+}
+
+func (w FindCommentsWorker) Name() string {
+	return workerName(w.reqCommentsReader)
+}
+
+func (w FindCommentsWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.FindCommentsDTO
+		msgCtx, corID, err := readDTO(ctx, w.reqCommentsReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got FindCommentsDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.reqCommentsReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			defer cancel()
+
+			if len(dto.ID) > 0 {
+				comment, err := w.recipeService.GetComment(cntx, dto.ID)
+				commentDTO := recipe.CommentDTO{
+					ID:       dto.ID,
+					RecipeID: comment.RecipeID,
+				}
+				if err != nil {
+					log.Error().Err(err).Msg("failed to find comment")
+					commentDTO.Error = err.Error()
+				} else {
+					commentDTO.Comment = comment
+				}
+				write(msgCtx, w.commentsWriter, comment.RecipeID, commentDTO, corID)
+				return err
+			}
+
+			comments, err := w.recipeService.GetComments(cntx, dto.RecipeID, dto.ParentID, dto.Start, dto.Limit)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to find comments")
+				write(msgCtx, w.commentsWriter, dto.RecipeID, recipe.CommentDTO{
+					RecipeID: dto.RecipeID,
+					Stream:   streamDone(0),
+					Error:    err.Error(),
+				}, corID)
+				return err
+			}
+			for i, comment := range comments {
+				write(msgCtx, w.commentsWriter, dto.RecipeID, recipe.CommentDTO{
+					Comment:  comment,
+					ID:       comment.ID,
+					RecipeID: dto.RecipeID,
+					Stream:   streamPart(i, len(comments)),
+				}, corID)
+			}
+			write(msgCtx, w.commentsWriter, dto.RecipeID, recipe.CommentDTO{
+				RecipeID: dto.RecipeID,
+				Stream:   streamDone(len(comments)),
+			}, corID)
+			log.Info().Msgf("sent %d comments of recipe %s", len(comments), dto.RecipeID)
+			return nil
+		}, func(err error) {
+			write(msgCtx, w.commentsWriter, dto.RecipeID, recipe.CommentDTO{
+				ID:       dto.ID,
+				RecipeID: dto.RecipeID,
+				Error:    err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w FindCommentsWorker) Stop() error {
+	return closeAll(w.reqCommentsReader, w.commentsWriter)
+}
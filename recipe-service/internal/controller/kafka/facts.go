@@ -28,6 +28,10 @@ func NewRecipeNutritionFactsWorker(recipeService service.Service, brokers []stri
 	}, nil
 }
 
+func (w RecipeNutritionFactsWorker) Name() string {
+	return workerName(w.nutritionFactsReader)
+}
+
 func (w RecipeNutritionFactsWorker) Process(ctx context.Context) error {
 	for {
 		select {
@@ -37,7 +41,7 @@ func (w RecipeNutritionFactsWorker) Process(ctx context.Context) error {
 		}
 
 		var dto recipe.RecipeNutritionsDTO
-		_, err := readDTO(ctx, w.nutritionFactsReader, &dto)
+		msgCtx, _, err := readDTO(ctx, w.nutritionFactsReader, &dto)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				return err
@@ -46,32 +50,18 @@ func (w RecipeNutritionFactsWorker) Process(ctx context.Context) error {
 		}
 		log.Info().Msgf("got RecipeNutritionsDTO: %+v", dto)
 
-		cntx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		recip, err := w.recipeService.GetByID(cntx, dto.RecipeID)
-		if err != nil {
-			cancel()
-			log.Error().Err(err).Msg("could not find recipe to update nutrition facts")
-			continue
-		}
-
-		recip.NutritionFacts = &dto.NutritionFacts
-
-		updateRecipeDTO := recipe.UpdateRecipeDTO{
-			ID:             recip.ID,
-			Name:           recip.Name,
-			Ingredients:    recip.Ingredients,
-			Steps:          recip.Steps,
-			NutritionFacts: recip.NutritionFacts,
-		}
-		err = w.recipeService.Update(cntx, updateRecipeDTO)
-		if err != nil {
-			cancel()
-			log.Error().Err(err).Msg("could not update recipe's nutrition facts")
-			continue
-		}
-		log.Info().Msgf("updated with UpdateRecipeDTO: %+v", updateRecipeDTO)
-
-		cancel()
+		handleMessage(msgCtx, w.nutritionFactsReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			defer cancel()
+			// only nutrition facts are changed, so edits of author made meanwhile are kept
+			err := w.recipeService.UpdateNutritionFacts(cntx, dto)
+			if err != nil {
+				log.Error().Err(err).Msg("could not update recipe's nutrition facts")
+				return err
+			}
+			log.Info().Msgf("updated nutrition facts of recipe %s", dto.RecipeID)
+			return nil
+		}, nil)
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/segmentio/kafka-go"
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
 )
@@ -31,6 +32,10 @@ func NewFindRecipesWorker(recipeService service.Service, brokers []string) (Work
 	}, nil
 }
 
+func (w FindRecipesWorker) Name() string {
+	return workerName(w.reqRecipesReader)
+}
+
 func (w FindRecipesWorker) Process(ctx context.Context) error {
 	for {
 		select {
@@ -40,7 +45,7 @@ func (w FindRecipesWorker) Process(ctx context.Context) error {
 		}
 
 		var dto recipe.FindRecipeDTO
-		corID, err := readDTO(ctx, w.reqRecipesReader, &dto)
+		msgCtx, corID, err := readDTO(ctx, w.reqRecipesReader, &dto)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				return err
@@ -48,50 +53,164 @@ func (w FindRecipesWorker) Process(ctx context.Context) error {
 			continue
 		}
 		log.Info().Msgf("got FindRecipeDTO: %+v", dto)
+		handleMessage(msgCtx, w.reqRecipesReader, func() error {
+			var handleErr error
 
-		if len(dto.ID) > 0 {
-			cntx, cancel := context.WithTimeout(ctx, 5*time.Second)
-			recip, err := w.recipeService.GetByID(cntx, dto.ID)
-			cancel()
+			if len(dto.ID) > 0 {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				recip, err := w.recipeService.GetByID(cntx, dto.ID)
+				cancel()
+				if err == nil && recip.Draft && !ownRequest(msgCtx, recip.CreatedBy) {
+					recip, err = recipe.Recipe{}, apperror.ErrNotFound
+				}
 
-			recipeDTO := recipe.RecipeDTO{
-				ID: dto.ID,
-			}
-			if err != nil {
-				log.Error().Err(err).Msg("failed to find recipe")
-			} else {
-				recipeDTO.Recipe = recip
+				recipeDTO := recipe.RecipeDTO{
+					ID: dto.ID,
+				}
+				if err != nil {
+					log.Error().Err(err).Msg("failed to find recipe")
+					handleErr = err
+				} else {
+					recipeDTO.Recipe = recip
+				}
+
+				write(msgCtx, w.recipeWriter, dto.ID, recipeDTO, corID)
+				log.Info().Msgf("sent RecipeDTO: %+v", recipeDTO)
 			}
 
-			write(w.recipeWriter, dto.ID, recipeDTO, corID)
-			log.Info().Msgf("sent RecipeDTO: %+v", recipeDTO)
-		}
+			if len(dto.UserID) > 0 {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				filter := recipe.Filter{
+					CreatedBy: dto.UserID,
+					Drafts:    ownRequest(msgCtx, dto.UserID),
+				}
+				recipes, next, err := w.recipeService.GetAll(cntx, filter, dto.PageRequest)
+				cancel()
 
-		if len(dto.UserID) > 0 {
-			cntx, cancel := context.WithTimeout(ctx, 5*time.Second)
-			recipes, err := w.recipeService.GetAllByUser(cntx, dto.UserID)
-			cancel()
-
-			if err != nil {
-				log.Error().Err(err).Msg("failed to find recipes")
-				write(w.recipeWriter, dto.UserID, recipe.RecipeDTO{
-					UserID: dto.UserID,
-					Error:  err.Error(),
-				}, corID)
-			} else {
-				for _, recip := range recipes {
-					write(w.recipeWriter, dto.UserID, recipe.RecipeDTO{
-						Recipe: recip,
+				if err != nil {
+					log.Error().Err(err).Msg("failed to find recipes")
+					handleErr = err
+					write(msgCtx, w.recipeWriter, dto.UserID, recipe.RecipeDTO{
 						UserID: dto.UserID,
+						Stream: streamDone(0),
+						Error:  err.Error(),
+					}, corID)
+				} else {
+					for i, recip := range recipes {
+						write(msgCtx, w.recipeWriter, dto.UserID, recipe.RecipeDTO{
+							Recipe:     recip,
+							UserID:     dto.UserID,
+							NextCursor: next,
+							Stream:     streamPart(i, len(recipes)),
+						}, corID)
+					}
+					write(msgCtx, w.recipeWriter, dto.UserID, recipe.RecipeDTO{
+						UserID:     dto.UserID,
+						NextCursor: next,
+						Stream:     streamDone(len(recipes)),
 					}, corID)
 				}
 			}
-		}
 
-		if len(dto.IngredientIDs) > 0 {
-			// TODO: implement
-			panic("Implement me")
-		}
+			if dto.TopRated > 0 {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				recipes, err := w.recipeService.GetTopRated(cntx, dto.TopRated)
+				cancel()
+
+				if err != nil {
+					log.Error().Err(err).Msg("failed to find top rated recipes")
+					handleErr = err
+					write(msgCtx, w.recipeWriter, "", recipe.RecipeDTO{
+						Stream: streamDone(0),
+						Error:  err.Error(),
+					}, corID)
+				} else {
+					for i, recip := range recipes {
+						// listing replies have no recipe id, so nutrition-facts-service does not recalculate listed recipes
+						write(msgCtx, w.recipeWriter, recip.ID, recipe.RecipeDTO{
+							Recipe: recip,
+							Stream: streamPart(i, len(recipes)),
+						}, corID)
+					}
+					write(msgCtx, w.recipeWriter, "", recipe.RecipeDTO{
+						Stream: streamDone(len(recipes)),
+					}, corID)
+				}
+			}
+
+			if len(dto.ForksOf) > 0 {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				recipes, next, err := w.recipeService.GetAll(cntx, recipe.Filter{ForkOf: dto.ForksOf}, dto.PageRequest)
+				cancel()
+
+				if err != nil {
+					log.Error().Err(err).Msg("failed to find forks")
+					handleErr = err
+					write(msgCtx, w.recipeWriter, dto.ForksOf, recipe.RecipeDTO{
+						Stream: streamDone(0),
+						Error:  err.Error(),
+					}, corID)
+				} else {
+					for i, recip := range recipes {
+						write(msgCtx, w.recipeWriter, dto.ForksOf, recipe.RecipeDTO{
+							Recipe:     recip,
+							NextCursor: next,
+							Stream:     streamPart(i, len(recipes)),
+						}, corID)
+					}
+					write(msgCtx, w.recipeWriter, dto.ForksOf, recipe.RecipeDTO{
+						NextCursor: next,
+						Stream:     streamDone(len(recipes)),
+					}, corID)
+				}
+			}
+
+			if dto.Filter != nil {
+				dto.Filter.Drafts = ownRequest(msgCtx, dto.Filter.CreatedBy)
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				recipes, next, err := w.recipeService.GetAll(cntx, *dto.Filter, dto.PageRequest)
+				cancel()
+
+				if err != nil {
+					log.Error().Err(err).Msg("failed to list recipes")
+					handleErr = err
+					write(msgCtx, w.recipeWriter, "", recipe.RecipeDTO{
+						Stream: streamDone(0),
+						Error:  err.Error(),
+					}, corID)
+				} else {
+					for i, recip := range recipes {
+						// listing replies have no recipe id, so nutrition-facts-service does not recalculate listed recipes
+						write(msgCtx, w.recipeWriter, recip.ID, recipe.RecipeDTO{
+							Recipe:     recip,
+							NextCursor: next,
+							Stream:     streamPart(i, len(recipes)),
+						}, corID)
+					}
+					write(msgCtx, w.recipeWriter, "", recipe.RecipeDTO{
+						NextCursor: next,
+						Stream:     streamDone(len(recipes)),
+					}, corID)
+				}
+			}
+
+			if len(dto.IngredientIDs) > 0 {
+				// TODO: implement
+				panic("Implement me")
+			}
+
+			return handleErr
+		}, func(err error) {
+			key := dto.ID
+			if len(key) == 0 {
+				key = dto.UserID
+			}
+			write(msgCtx, w.recipeWriter, key, recipe.RecipeDTO{
+				ID:     dto.ID,
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
 	}
 }
 
@@ -0,0 +1,96 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
+)
+
+type ForkRecipeWorker struct {
+	recipeService    service.Service
+	forkRecipeReader *kafka.Reader
+	recipesWriter    *kafka.Writer
+}
+
+func NewForkRecipeWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	forkRecipeReader, err := newReader(brokers, "recipe-service-fork", TopicRecipesFork)
+	if err != nil {
+		return nil, err
+	}
+	recipesWriter := newWriter(brokers, TopicRecipes)
+	return ForkRecipeWorker{
+		recipeService:    recipeService,
+		forkRecipeReader: forkRecipeReader,
+		recipesWriter:    recipesWriter,
+	}, nil
+}
+
+func (w ForkRecipeWorker) Name() string {
+	return workerName(w.forkRecipeReader)
+}
+
+func (w ForkRecipeWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.ForkRecipeDTO
+		msgCtx, corID, err := readDTO(ctx, w.forkRecipeReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got ForkRecipeDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.forkRecipeReader, func() error {
+			var id string
+			var recip recipe.Recipe
+			var err error
+			// forks are drafts, so unverified users may make them too
+			if !ownRequest(msgCtx, dto.UserID) {
+				err = apperror.ErrForbidden
+			} else {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				id, err = w.recipeService.Fork(cntx, dto)
+				if err == nil {
+					recip, err = w.recipeService.GetByID(cntx, id)
+				}
+				cancel()
+			}
+			// reply with recipe id makes nutrition-facts-service calculate nutrition facts of fork
+			recipeDTO := recipe.RecipeDTO{
+				ID: id,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to fork recipe")
+				recipeDTO.Error = err.Error()
+			} else {
+				recipeDTO.Recipe = recip
+			}
+
+			write(msgCtx, w.recipesWriter, dto.RecipeID, recipeDTO, corID)
+			log.Info().Msgf("sent RecipeDTO: %+v", recipeDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.recipesWriter, dto.RecipeID, recipe.RecipeDTO{
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w ForkRecipeWorker) Stop() error {
+	return closeAll(w.forkRecipeReader, w.recipesWriter)
+}
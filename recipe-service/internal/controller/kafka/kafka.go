@@ -3,23 +3,34 @@ package kafka
 import (
 	"context"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/controller"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
-	"golang.org/x/sync/errgroup"
 )
 
 type kafkaController struct {
 	workers []Worker
+	brokers []string
+	// trustedServices may make requests without user headers
+	trustedServices []string
+	states          *workerStates
+	// stop is closed when controller is stopped, so failed workers are not restarted
+	stop     chan struct{}
+	stopOnce *sync.Once
 }
 
 type Worker interface {
+	Name() string
 	Process(ctx context.Context) error
 	Stop() error
 }
 
-func NewController(recipeService service.Service, kafkaBrokerURLs string) (controller.Controller, error) {
+// NewController creates controller, userDeletion is one of UserDeletion* modes, trustedServices is comma separated
+// list of services allowed to make requests without user headers
+func NewController(recipeService service.Service, kafkaBrokerURLs string, userDeletion string,
+	trustedServices string) (controller.MonitoredController, error) {
 	brokers := strings.Split(kafkaBrokerURLs, ",")
 
 	var workers []Worker
@@ -42,24 +53,181 @@ func NewController(recipeService service.Service, kafkaBrokerURLs string) (contr
 	}
 	workers = append(workers, findRecipesWorker)
 
+	rateRecipeWorker, err := NewRateRecipeWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, rateRecipeWorker)
+
+	findRatingsWorker, err := NewFindRatingsWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, findRatingsWorker)
+
+	addCommentWorker, err := NewAddCommentWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, addCommentWorker)
+
+	editCommentWorker, err := NewEditCommentWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, editCommentWorker)
+
+	deleteCommentWorker, err := NewDeleteCommentWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, deleteCommentWorker)
+
+	findCommentsWorker, err := NewFindCommentsWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, findCommentsWorker)
+
+	updateFavoriteWorker, err := NewUpdateFavoriteWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, updateFavoriteWorker)
+
+	findFavoritesWorker, err := NewFindFavoritesWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, findFavoritesWorker)
+
+	createCollectionWorker, err := NewCreateCollectionWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, createCollectionWorker)
+
+	updateCollectionWorker, err := NewUpdateCollectionWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, updateCollectionWorker)
+
+	collectionItemWorker, err := NewCollectionItemWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, collectionItemWorker)
+
+	deleteCollectionWorker, err := NewDeleteCollectionWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, deleteCollectionWorker)
+
+	findCollectionsWorker, err := NewFindCollectionsWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, findCollectionsWorker)
+
+	createCategoryWorker, err := NewCreateCategoryWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, createCategoryWorker)
+
+	deleteCategoryWorker, err := NewDeleteCategoryWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, deleteCategoryWorker)
+
+	findCategoriesWorker, err := NewFindCategoriesWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, findCategoriesWorker)
+
+	updateRecipeWorker, err := NewUpdateRecipeWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, updateRecipeWorker)
+
+	forkRecipeWorker, err := NewForkRecipeWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, forkRecipeWorker)
+
+	scaleRecipeWorker, err := NewScaleRecipeWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, scaleRecipeWorker)
+
+	revertRecipeWorker, err := NewRevertRecipeWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, revertRecipeWorker)
+
+	findRevisionsWorker, err := NewFindRevisionsWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, findRevisionsWorker)
+
+	userDeletedWorker, err := NewUserDeletedWorker(recipeService, brokers, userDeletion)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, userDeletedWorker)
+
+	userExportWorker, err := NewUserExportWorker(recipeService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, userExportWorker)
+
+	var trusted []string
+	for _, name := range strings.Split(trustedServices, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) > 0 {
+			trusted = append(trusted, name)
+		}
+	}
+
 	return kafkaController{
-		workers: workers,
+		workers:         workers,
+		brokers:         brokers,
+		trustedServices: trusted,
+		states:          newWorkerStates(workers),
+		stop:            make(chan struct{}),
+		stopOnce:        &sync.Once{},
 	}, nil
 }
 
+// Run runs every worker under supervision, failed workers are restarted without affecting others
 func (k kafkaController) Run(ctx context.Context) error {
-	group, ctx := errgroup.WithContext(ctx)
-
+	ctx = withTrustedServices(ctx, k.trustedServices)
+	var wg sync.WaitGroup
 	for _, w := range k.workers {
-		worker := w
-		group.Go(func() error {
-			return worker.Process(ctx)
-		})
+		wg.Add(1)
+		go func(worker Worker) {
+			defer wg.Done()
+			k.supervise(ctx, worker)
+		}(w)
 	}
-	return group.Wait()
+	wg.Wait()
+	return nil
 }
 
 func (k kafkaController) Stop() error {
+	k.stopOnce.Do(func() {
+		close(k.stop)
+	})
 	var result error
 	for _, w := range k.workers {
 		err := w.Stop()
@@ -69,3 +237,22 @@ func (k kafkaController) Stop() error {
 	}
 	return result
 }
+
+// Live fails if any worker has stopped processing messages and will not be restarted
+func (k kafkaController) Live(ctx context.Context) error {
+	return k.states.check(WorkerStarting, WorkerRunning, WorkerRestarting)
+}
+
+// Ready fails if any worker is not running or none of kafka brokers is reachable
+func (k kafkaController) Ready(ctx context.Context) error {
+	var result error
+	err := k.states.check(WorkerRunning)
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
+	err = pingBrokers(ctx, k.brokers)
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
+	return result
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/controller"
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/random"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
@@ -21,6 +22,9 @@ import (
 	"time"
 )
 
+// testService is trusted by controller under test to make requests without user headers
+const testService = "test-client"
+
 type ControllerTestSuite struct {
 	suite.Suite
 
@@ -30,6 +34,18 @@ type ControllerTestSuite struct {
 	newRecipeWriter      *kafka.Writer
 	reqRecipeWriter      *kafka.Writer
 	nutritionFactsWriter *kafka.Writer
+	userDeletedWriter    *kafka.Writer
+	deletionAcksReader   *kafka.Reader
+	exportStartedWriter  *kafka.Writer
+	exportPartsReader    *kafka.Reader
+	newRatingWriter      *kafka.Writer
+	ratingsReader        *kafka.Reader
+	newCommentWriter     *kafka.Writer
+	commentsReader       *kafka.Reader
+	newCollectionWriter  *kafka.Writer
+	collectionsReader    *kafka.Reader
+	newCategoryWriter    *kafka.Writer
+	categoriesReader     *kafka.Reader
 
 	rand random.Generator
 
@@ -40,7 +56,7 @@ func (suite *ControllerTestSuite) TestController() {
 	suite.Run("create recipe send nutrition facts and get by id", func() {
 		newRecipeDTO := suite.randomCreateRecipe()
 		corID := generateCorrelationID()
-		write(suite.newRecipeWriter, newRecipeDTO.Name, newRecipeDTO, corID)
+		suite.writeAsService(suite.newRecipeWriter, newRecipeDTO.Name, newRecipeDTO, corID)
 
 		var createdDTO recipe.RecipeDTO
 		{
@@ -70,12 +86,12 @@ func (suite *ControllerTestSuite) TestController() {
 			NutritionFacts: suite.randomNutritionFacts(),
 			Inaccurate:     false,
 		}
-		write(suite.nutritionFactsWriter, recipeNutritionsDTO.RecipeID, recipeNutritionsDTO, "")
+		write(context.Background(), suite.nutritionFactsWriter, recipeNutritionsDTO.RecipeID, recipeNutritionsDTO, "")
 
 		findRecipeDTO := recipe.FindRecipeDTO{
 			ID: createdDTO.ID,
 		}
-		write(suite.reqRecipeWriter, findRecipeDTO.ID, findRecipeDTO, corID)
+		write(context.Background(), suite.reqRecipeWriter, findRecipeDTO.ID, findRecipeDTO, corID)
 
 		var gotDTO recipe.RecipeDTO
 		{
@@ -100,7 +116,264 @@ func (suite *ControllerTestSuite) TestController() {
 			}
 		}
 	})
+	suite.Run("recipes of user end with completion message", func() {
+		findRecipeDTO := recipe.FindRecipeDTO{
+			UserID: suite.rand.RandomObjectID(),
+		}
+		corID := generateCorrelationID()
+		write(context.Background(), suite.reqRecipeWriter, findRecipeDTO.UserID, findRecipeDTO, corID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.recipesReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
+
+			var gotDTO recipe.RecipeDTO
+			err = json.Unmarshal(message.Value, &gotDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Empty(suite.T(), gotDTO.Error)
+			require.NotNil(suite.T(), gotDTO.Stream)
+			assert.True(suite.T(), gotDTO.Stream.Done)
+			assert.Equal(suite.T(), 0, gotDTO.Stream.Total)
+			assert.Empty(suite.T(), gotDTO.NextCursor)
+			break
+		}
+	})
+	suite.Run("unverified user can not create recipe", func() {
+		newRecipeDTO := suite.randomCreateRecipe()
+		corID := generateCorrelationID()
+		bs, err := json.Marshal(newRecipeDTO)
+		suite.Require().NoError(err)
+		err = suite.newRecipeWriter.WriteMessages(context.Background(), kafka.Message{
+			Key:   []byte(newRecipeDTO.Name),
+			Value: bs,
+			Headers: []kafka.Header{
+				{Key: KeyCorrelationID, Value: []byte(corID)},
+				{Key: KeyUserID, Value: []byte(newRecipeDTO.CreatedBy)},
+				{Key: KeyUserVerified, Value: []byte("false")},
+			},
+		})
+		suite.Require().NoError(err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.recipesReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
+
+			var createdDTO recipe.RecipeDTO
+			err = json.Unmarshal(message.Value, &createdDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Equal(suite.T(), apperror.ErrUnverified.Error(), createdDTO.Error)
+			assert.Empty(suite.T(), createdDTO.ID)
+			break
+		}
+	})
+	suite.Run("request without user headers from untrusted client is rejected", func() {
+		newRecipeDTO := suite.randomCreateRecipe()
+		corID := generateCorrelationID()
+		write(context.Background(), suite.newRecipeWriter, newRecipeDTO.Name, newRecipeDTO, corID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.recipesReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
+
+			var createdDTO recipe.RecipeDTO
+			err = json.Unmarshal(message.Value, &createdDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Equal(suite.T(), apperror.ErrForbidden.Error(), createdDTO.Error)
+			assert.Empty(suite.T(), createdDTO.ID)
+			break
+		}
+	})
+	suite.Run("recipes of deleted user are anonymized", func() {
+		userDeletedDTO := recipe.UserDeletedDTO{
+			UserID: suite.rand.RandomObjectID(),
+		}
+		corID := generateCorrelationID()
+		write(context.Background(), suite.userDeletedWriter, userDeletedDTO.UserID, userDeletedDTO, corID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.deletionAcksReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
+
+			var ackDTO recipe.UserDeletionAckDTO
+			err = json.Unmarshal(message.Value, &ackDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Empty(suite.T(), ackDTO.Error)
+			assert.Equal(suite.T(), userDeletedDTO.UserID, ackDTO.UserID)
+			assert.Equal(suite.T(), "recipe-service", ackDTO.Service)
+			break
+		}
+	})
+	suite.Run("export recipes of user", func() {
+		exportStartedDTO := recipe.ExportStartedDTO{
+			ExportID: suite.rand.RandomObjectID(),
+			UserID:   suite.rand.RandomObjectID(),
+		}
+		corID := generateCorrelationID()
+		write(context.Background(), suite.exportStartedWriter, exportStartedDTO.UserID, exportStartedDTO, corID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.exportPartsReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
+
+			var partDTO recipe.ExportPartDTO
+			err = json.Unmarshal(message.Value, &partDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Empty(suite.T(), partDTO.Error)
+			assert.Equal(suite.T(), exportStartedDTO.ExportID, partDTO.ExportID)
+			assert.Equal(suite.T(), "recipe-service", partDTO.Service)
+			assert.JSONEq(suite.T(), `{"recipes":[],"ratings":[],"comments":[],"favorites":[],"collections":[]}`, string(partDTO.Data))
+			break
+		}
+	})
+	suite.Run("user can not rate on behalf of another user", func() {
+		rateDTO := recipe.RateRecipeDTO{
+			RecipeID: suite.rand.RandomObjectID(),
+			UserID:   suite.rand.RandomObjectID(),
+			Score:    5,
+		}
+		corID := generateCorrelationID()
+		bs, err := json.Marshal(rateDTO)
+		suite.Require().NoError(err)
+		err = suite.newRatingWriter.WriteMessages(context.Background(), kafka.Message{
+			Key:   []byte(rateDTO.RecipeID),
+			Value: bs,
+			Headers: []kafka.Header{
+				{Key: KeyCorrelationID, Value: []byte(corID)},
+				{Key: KeyUserID, Value: []byte(suite.rand.RandomObjectID())},
+			},
+		})
+		suite.Require().NoError(err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.ratingsReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
+
+			var ratingDTO recipe.RatingDTO
+			err = json.Unmarshal(message.Value, &ratingDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Equal(suite.T(), apperror.ErrForbidden.Error(), ratingDTO.Error)
+			assert.Equal(suite.T(), rateDTO.RecipeID, ratingDTO.RecipeID)
+			break
+		}
+	})
+	suite.Run("comment on unknown recipe", func() {
+		addCommentDTO := recipe.AddCommentDTO{
+			RecipeID: suite.rand.RandomObjectID(),
+			UserID:   suite.rand.RandomObjectID(),
+			Text:     suite.rand.RandomString(50),
+		}
+		corID := generateCorrelationID()
+		suite.writeAsUser(suite.newCommentWriter, addCommentDTO.RecipeID, addCommentDTO, corID, addCommentDTO.UserID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.commentsReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
+
+			var commentDTO recipe.CommentDTO
+			err = json.Unmarshal(message.Value, &commentDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Equal(suite.T(), apperror.ErrNotFound.Error(), commentDTO.Error)
+			assert.Equal(suite.T(), addCommentDTO.RecipeID, commentDTO.RecipeID)
+			break
+		}
+	})
+	suite.Run("create collection", func() {
+		createDTO := recipe.CreateCollectionDTO{
+			UserID: suite.rand.RandomObjectID(),
+			Name:   suite.rand.RandomString(10),
+		}
+		corID := generateCorrelationID()
+		suite.writeAsUser(suite.newCollectionWriter, createDTO.UserID, createDTO, corID, createDTO.UserID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.collectionsReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
+
+			var collectionDTO recipe.CollectionDTO
+			err = json.Unmarshal(message.Value, &collectionDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Empty(suite.T(), collectionDTO.Error)
+			assert.NotEmpty(suite.T(), collectionDTO.ID)
+			assert.Equal(suite.T(), createDTO.Name, collectionDTO.Collection.Name)
+			assert.Equal(suite.T(), recipe.VisibilityPrivate, collectionDTO.Collection.Visibility)
+			break
+		}
+	})
+	suite.Run("user can not create category", func() {
+		createDTO := recipe.CreateCategoryDTO{
+			Kind:  recipe.CategoryCuisine,
+			Name:  "italian",
+			Title: "Итальянская",
+		}
+		corID := generateCorrelationID()
+		bs, err := json.Marshal(createDTO)
+		suite.Require().NoError(err)
+		err = suite.newCategoryWriter.WriteMessages(context.Background(), kafka.Message{
+			Key:   []byte(createDTO.Name),
+			Value: bs,
+			Headers: []kafka.Header{
+				{Key: KeyCorrelationID, Value: []byte(corID)},
+				{Key: KeyUserRole, Value: []byte("user")},
+			},
+		})
+		suite.Require().NoError(err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for {
+			message, err := suite.categoriesReader.ReadMessage(ctx)
+			require.NoError(suite.T(), err, "ошибка при чтении сообщения")
+			if !checkCorrelationID(message, corID) {
+				continue
+			}
 
+			var categoryDTO recipe.CategoryDTO
+			err = json.Unmarshal(message.Value, &categoryDTO)
+			require.NoError(suite.T(), err, "ошибка при раскодировании сообщения")
+			assert.Equal(suite.T(), apperror.ErrForbidden.Error(), categoryDTO.Error)
+			break
+		}
+	})
 }
 
 func (suite *ControllerTestSuite) SetupSuite() {
@@ -116,7 +389,14 @@ func (suite *ControllerTestSuite) SetupSuite() {
 
 	var err error
 
-	err = createTopics(kafkaBroker, TopicRecipesNew, TopicRecipesReq, TopicRecipes, TopicNutritionFacts)
+	err = createTopics(kafkaBroker, TopicRecipesNew, TopicRecipesReq, TopicRecipes, TopicNutritionFacts,
+		TopicRecipesUpdate, TopicRecipesRevert, TopicRecipesFork, TopicRecipesScale, TopicRevisionsReq, TopicRevisions,
+		TopicUserDeleted, TopicUserDeletionAcks, TopicExportStarted, TopicExportParts,
+		TopicRatingsNew, TopicRatingsReq, TopicRatings,
+		TopicCommentsNew, TopicCommentsUpdate, TopicCommentsDelete, TopicCommentsReq, TopicComments, TopicCommentsCreated,
+		TopicFavoritesUpdate, TopicFavoritesReq, TopicFavorites,
+		TopicCollectionsNew, TopicCollectionsUpdate, TopicCollectionsItems, TopicCollectionsDelete, TopicCollectionsReq, TopicCollections,
+		TopicCategoriesNew, TopicCategoriesDelete, TopicCategoriesReq, TopicCategories)
 	suite.Require().NoError(err)
 
 	{
@@ -124,7 +404,7 @@ func (suite *ControllerTestSuite) SetupSuite() {
 		stor, suite.cleanupFunc, err = mongodb.NewTestStorage(dsn, "test")
 		suite.Require().NoError(err)
 
-		suite.controller, err = NewController(service.NewService(stor), kafkaBroker)
+		suite.controller, err = NewController(service.NewService(stor), kafkaBroker, UserDeletionAnonymize, testService)
 		suite.Require().NoError(err)
 	}
 
@@ -134,6 +414,30 @@ func (suite *ControllerTestSuite) SetupSuite() {
 	suite.newRecipeWriter = newWriter([]string{kafkaBroker}, TopicRecipesNew)
 	suite.reqRecipeWriter = newWriter([]string{kafkaBroker}, TopicRecipesReq)
 	suite.nutritionFactsWriter = newWriter([]string{kafkaBroker}, TopicNutritionFacts)
+	suite.userDeletedWriter = newWriter([]string{kafkaBroker}, TopicUserDeleted)
+
+	suite.deletionAcksReader, err = newReader([]string{kafkaBroker}, "recipe-service-test-deletion-acks", TopicUserDeletionAcks)
+	suite.Require().NoError(err)
+
+	suite.exportStartedWriter = newWriter([]string{kafkaBroker}, TopicExportStarted)
+	suite.exportPartsReader, err = newReader([]string{kafkaBroker}, "recipe-service-test-export-parts", TopicExportParts)
+	suite.Require().NoError(err)
+
+	suite.newRatingWriter = newWriter([]string{kafkaBroker}, TopicRatingsNew)
+	suite.ratingsReader, err = newReader([]string{kafkaBroker}, "recipe-service-test-ratings", TopicRatings)
+	suite.Require().NoError(err)
+
+	suite.newCommentWriter = newWriter([]string{kafkaBroker}, TopicCommentsNew)
+	suite.commentsReader, err = newReader([]string{kafkaBroker}, "recipe-service-test-comments", TopicComments)
+	suite.Require().NoError(err)
+
+	suite.newCollectionWriter = newWriter([]string{kafkaBroker}, TopicCollectionsNew)
+	suite.collectionsReader, err = newReader([]string{kafkaBroker}, "recipe-service-test-collections", TopicCollections)
+	suite.Require().NoError(err)
+
+	suite.newCategoryWriter = newWriter([]string{kafkaBroker}, TopicCategoriesNew)
+	suite.categoriesReader, err = newReader([]string{kafkaBroker}, "recipe-service-test-categories", TopicCategories)
+	suite.Require().NoError(err)
 
 	suite.rand = random.NewRandomGenerator()
 
@@ -146,7 +450,10 @@ func (suite *ControllerTestSuite) SetupSuite() {
 }
 
 func (suite *ControllerTestSuite) TearDownSuite() {
-	err := closeAll(suite.recipesReader, suite.reqRecipeWriter, suite.newRecipeWriter, suite.nutritionFactsWriter)
+	err := closeAll(suite.recipesReader, suite.reqRecipeWriter, suite.newRecipeWriter, suite.nutritionFactsWriter,
+		suite.userDeletedWriter, suite.deletionAcksReader, suite.exportStartedWriter, suite.exportPartsReader,
+		suite.newRatingWriter, suite.ratingsReader, suite.newCommentWriter, suite.commentsReader,
+		suite.newCollectionWriter, suite.collectionsReader, suite.newCategoryWriter, suite.categoriesReader)
 	suite.Assert().NoError(err)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -159,6 +466,32 @@ func (suite *ControllerTestSuite) TearDownSuite() {
 	suite.Assert().NoError(err)
 }
 
+// writeAsUser writes message made on behalf of user with verified email
+func (suite *ControllerTestSuite) writeAsUser(writer *kafka.Writer, key string, msg interface{}, corID string, userID string) {
+	suite.writeWithHeaders(writer, key, msg,
+		kafka.Header{Key: KeyCorrelationID, Value: []byte(corID)},
+		kafka.Header{Key: KeyUserID, Value: []byte(userID)},
+		kafka.Header{Key: KeyUserVerified, Value: []byte("true")})
+}
+
+// writeAsService writes message made by trusted internal service
+func (suite *ControllerTestSuite) writeAsService(writer *kafka.Writer, key string, msg interface{}, corID string) {
+	suite.writeWithHeaders(writer, key, msg,
+		kafka.Header{Key: KeyCorrelationID, Value: []byte(corID)},
+		kafka.Header{Key: KeyService, Value: []byte(testService)})
+}
+
+func (suite *ControllerTestSuite) writeWithHeaders(writer *kafka.Writer, key string, msg interface{}, headers ...kafka.Header) {
+	bs, err := json.Marshal(msg)
+	suite.Require().NoError(err)
+	err = writer.WriteMessages(context.Background(), kafka.Message{
+		Key:     []byte(key),
+		Value:   bs,
+		Headers: headers,
+	})
+	suite.Require().NoError(err)
+}
+
 func (suite *ControllerTestSuite) randomCreateRecipe() recipe.CreateRecipeDTO {
 	return recipe.CreateRecipeDTO{
 		Name:      suite.rand.RandomString(8),
@@ -0,0 +1,187 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
+)
+
+type RateRecipeWorker struct {
+	recipeService   service.Service
+	newRatingReader *kafka.Reader
+	ratingsWriter   *kafka.Writer
+}
+
+func NewRateRecipeWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	newRatingReader, err := newReader(brokers, "recipe-service-rate", TopicRatingsNew)
+	if err != nil {
+		return nil, err
+	}
+	ratingsWriter := newWriter(brokers, TopicRatings)
+	return RateRecipeWorker{
+		recipeService:   recipeService,
+		newRatingReader: newRatingReader,
+		ratingsWriter:   ratingsWriter,
+	}, nil
+}
+
+func (w RateRecipeWorker) Name() string {
+	return workerName(w.newRatingReader)
+}
+
+func (w RateRecipeWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.RateRecipeDTO
+		msgCtx, corID, err := readDTO(ctx, w.newRatingReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got RateRecipeDTO for recipe %s by user %s", dto.RecipeID, dto.UserID)
+
+		handleMessage(msgCtx, w.newRatingReader, func() error {
+			var rating recipe.Rating
+			var err error
+			if err = checkUser(msgCtx, dto.UserID, true); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				rating, err = w.recipeService.Rate(cntx, dto)
+				cancel()
+			}
+			ratingDTO := recipe.RatingDTO{
+				Rating:   rating,
+				RecipeID: dto.RecipeID,
+				UserID:   dto.UserID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to rate recipe")
+				ratingDTO.Error = err.Error()
+			}
+
+			write(msgCtx, w.ratingsWriter, dto.RecipeID, ratingDTO, corID)
+			log.Info().Msgf("sent RatingDTO for recipe %s by user %s", dto.RecipeID, dto.UserID)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.ratingsWriter, dto.RecipeID, recipe.RatingDTO{
+				RecipeID: dto.RecipeID,
+				UserID:   dto.UserID,
+				Error:    err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w RateRecipeWorker) Stop() error {
+	return closeAll(w.newRatingReader, w.ratingsWriter)
+}
+
+type FindRatingsWorker struct {
+	recipeService    service.Service
+	reqRatingsReader *kafka.Reader
+	ratingsWriter    *kafka.Writer
+}
+
+func NewFindRatingsWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	reqRatingsReader, err := newReader(brokers, "recipe-service-find-ratings", TopicRatingsReq)
+	if err != nil {
+		return nil, err
+	}
+	ratingsWriter := newWriter(brokers, TopicRatings)
+	return FindRatingsWorker{
+		recipeService:    recipeService,
+		reqRatingsReader: reqRatingsReader,
+		ratingsWriter:    ratingsWriter,
+	}, nil
+}
+
+func (w FindRatingsWorker) Name() string {
+	return workerName(w.reqRatingsReader)
+}
+
+func (w FindRatingsWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.FindRatingsDTO
+		msgCtx, corID, err := readDTO(ctx, w.reqRatingsReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got FindRatingsDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.reqRatingsReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			defer cancel()
+
+			if len(dto.UserID) > 0 {
+				rating, err := w.recipeService.GetRating(cntx, dto.RecipeID, dto.UserID)
+				ratingDTO := recipe.RatingDTO{
+					Rating:   rating,
+					RecipeID: dto.RecipeID,
+					UserID:   dto.UserID,
+				}
+				if err != nil {
+					log.Error().Err(err).Msg("failed to find rating")
+					ratingDTO.Error = err.Error()
+				}
+				write(msgCtx, w.ratingsWriter, dto.RecipeID, ratingDTO, corID)
+				return err
+			}
+
+			ratings, err := w.recipeService.GetRatings(cntx, dto.RecipeID)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to find ratings")
+				write(msgCtx, w.ratingsWriter, dto.RecipeID, recipe.RatingDTO{
+					RecipeID: dto.RecipeID,
+					Stream:   streamDone(0),
+					Error:    err.Error(),
+				}, corID)
+				return err
+			}
+			for i, rating := range ratings {
+				write(msgCtx, w.ratingsWriter, dto.RecipeID, recipe.RatingDTO{
+					Rating:   rating,
+					RecipeID: dto.RecipeID,
+					UserID:   rating.UserID,
+					Stream:   streamPart(i, len(ratings)),
+				}, corID)
+			}
+			write(msgCtx, w.ratingsWriter, dto.RecipeID, recipe.RatingDTO{
+				RecipeID: dto.RecipeID,
+				Stream:   streamDone(len(ratings)),
+			}, corID)
+			log.Info().Msgf("sent %d ratings of recipe %s", len(ratings), dto.RecipeID)
+			return nil
+		}, func(err error) {
+			write(msgCtx, w.ratingsWriter, dto.RecipeID, recipe.RatingDTO{
+				RecipeID: dto.RecipeID,
+				UserID:   dto.UserID,
+				Error:    err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w FindRatingsWorker) Stop() error {
+	return closeAll(w.reqRatingsReader, w.ratingsWriter)
+}
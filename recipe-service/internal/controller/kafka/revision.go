@@ -0,0 +1,184 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
+)
+
+type RevertRecipeWorker struct {
+	recipeService      service.Service
+	revertRecipeReader *kafka.Reader
+	recipesWriter      *kafka.Writer
+}
+
+func NewRevertRecipeWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	revertRecipeReader, err := newReader(brokers, "recipe-service-revert", TopicRecipesRevert)
+	if err != nil {
+		return nil, err
+	}
+	recipesWriter := newWriter(brokers, TopicRecipes)
+	return RevertRecipeWorker{
+		recipeService:      recipeService,
+		revertRecipeReader: revertRecipeReader,
+		recipesWriter:      recipesWriter,
+	}, nil
+}
+
+func (w RevertRecipeWorker) Name() string {
+	return workerName(w.revertRecipeReader)
+}
+
+func (w RevertRecipeWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.RevertRecipeDTO
+		msgCtx, corID, err := readDTO(ctx, w.revertRecipeReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got RevertRecipeDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.revertRecipeReader, func() error {
+			var recip recipe.Recipe
+			var err error
+			if err = checkUser(msgCtx, dto.UserID, true); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				recip, err = w.recipeService.Revert(cntx, dto)
+				cancel()
+			}
+			// reply with recipe id makes nutrition-facts-service recalculate nutrition facts
+			recipeDTO := recipe.RecipeDTO{
+				ID: dto.RecipeID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to revert recipe")
+				recipeDTO.Error = err.Error()
+			} else {
+				recipeDTO.Recipe = recip
+			}
+
+			write(msgCtx, w.recipesWriter, dto.RecipeID, recipeDTO, corID)
+			log.Info().Msgf("sent RecipeDTO: %+v", recipeDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.recipesWriter, dto.RecipeID, recipe.RecipeDTO{
+				ID:    dto.RecipeID,
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w RevertRecipeWorker) Stop() error {
+	return closeAll(w.revertRecipeReader, w.recipesWriter)
+}
+
+type FindRevisionsWorker struct {
+	recipeService      service.Service
+	reqRevisionsReader *kafka.Reader
+	revisionsWriter    *kafka.Writer
+}
+
+func NewFindRevisionsWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	reqRevisionsReader, err := newReader(brokers, "recipe-service-find-revisions", TopicRevisionsReq)
+	if err != nil {
+		return nil, err
+	}
+	revisionsWriter := newWriter(brokers, TopicRevisions)
+	return FindRevisionsWorker{
+		recipeService:      recipeService,
+		reqRevisionsReader: reqRevisionsReader,
+		revisionsWriter:    revisionsWriter,
+	}, nil
+}
+
+func (w FindRevisionsWorker) Name() string {
+	return workerName(w.reqRevisionsReader)
+}
+
+func (w FindRevisionsWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.FindRevisionsDTO
+		msgCtx, corID, err := readDTO(ctx, w.reqRevisionsReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got FindRevisionsDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.reqRevisionsReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			defer cancel()
+
+			if dto.From > 0 && dto.To > 0 {
+				changes, err := w.recipeService.DiffRevisions(cntx, dto.RecipeID, dto.From, dto.To)
+				revisionDTO := recipe.RevisionDTO{
+					RecipeID: dto.RecipeID,
+					Diff:     changes,
+				}
+				if err != nil {
+					log.Error().Err(err).Msg("failed to diff revisions")
+					revisionDTO.Error = err.Error()
+				}
+				write(msgCtx, w.revisionsWriter, dto.RecipeID, revisionDTO, corID)
+				return err
+			}
+
+			revisions, err := w.recipeService.GetRevisions(cntx, dto.RecipeID)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to find revisions")
+				write(msgCtx, w.revisionsWriter, dto.RecipeID, recipe.RevisionDTO{
+					RecipeID: dto.RecipeID,
+					Stream:   streamDone(0),
+					Error:    err.Error(),
+				}, corID)
+				return err
+			}
+			for i, revision := range revisions {
+				write(msgCtx, w.revisionsWriter, dto.RecipeID, recipe.RevisionDTO{
+					Revision: revision,
+					RecipeID: dto.RecipeID,
+					Stream:   streamPart(i, len(revisions)),
+				}, corID)
+			}
+			write(msgCtx, w.revisionsWriter, dto.RecipeID, recipe.RevisionDTO{
+				RecipeID: dto.RecipeID,
+				Stream:   streamDone(len(revisions)),
+			}, corID)
+			log.Info().Msgf("sent %d revisions of recipe %s", len(revisions), dto.RecipeID)
+			return nil
+		}, func(err error) {
+			write(msgCtx, w.revisionsWriter, dto.RecipeID, recipe.RevisionDTO{
+				RecipeID: dto.RecipeID,
+				Error:    err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w FindRevisionsWorker) Stop() error {
+	return closeAll(w.reqRevisionsReader, w.revisionsWriter)
+}
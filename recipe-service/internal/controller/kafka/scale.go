@@ -0,0 +1,91 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
+)
+
+type ScaleRecipeWorker struct {
+	recipeService     service.Service
+	scaleRecipeReader *kafka.Reader
+	recipesWriter     *kafka.Writer
+}
+
+func NewScaleRecipeWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	scaleRecipeReader, err := newReader(brokers, "recipe-service-scale", TopicRecipesScale)
+	if err != nil {
+		return nil, err
+	}
+	recipesWriter := newWriter(brokers, TopicRecipes)
+	return ScaleRecipeWorker{
+		recipeService:     recipeService,
+		scaleRecipeReader: scaleRecipeReader,
+		recipesWriter:     recipesWriter,
+	}, nil
+}
+
+func (w ScaleRecipeWorker) Name() string {
+	return workerName(w.scaleRecipeReader)
+}
+
+func (w ScaleRecipeWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.ScaleRecipeDTO
+		msgCtx, corID, err := readDTO(ctx, w.scaleRecipeReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got ScaleRecipeDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.scaleRecipeReader, func() error {
+			var recip recipe.Recipe
+			var err error
+			// anonymous requests may scale published recipes only, saved scaled recipes are drafts,
+			// so unverified users may save them too
+			if (len(dto.UserID) > 0 || dto.Save) && !ownRequest(msgCtx, dto.UserID) {
+				err = apperror.ErrForbidden
+			} else {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				recip, err = w.recipeService.Scale(cntx, dto)
+				cancel()
+			}
+			// reply has no recipe id, as nutrition-facts-service recalculates nutrition facts of every recipe reply with it
+			recipeDTO := recipe.RecipeDTO{}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to scale recipe")
+				recipeDTO.Error = err.Error()
+			} else {
+				recipeDTO.Recipe = recip
+			}
+
+			write(msgCtx, w.recipesWriter, dto.RecipeID, recipeDTO, corID)
+			log.Info().Msgf("sent RecipeDTO: %+v", recipeDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.recipesWriter, dto.RecipeID, recipe.RecipeDTO{
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w ScaleRecipeWorker) Stop() error {
+	return closeAll(w.scaleRecipeReader, w.recipesWriter)
+}
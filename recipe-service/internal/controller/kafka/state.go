@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/metrics"
+)
+
+const (
+	WorkerStarting   = "starting"
+	WorkerRunning    = "running"
+	WorkerRestarting = "restarting"
+	WorkerStopped    = "stopped"
+)
+
+type workerState struct {
+	state string
+	err   error
+}
+
+// workerStates tracks state of controller's workers
+type workerStates struct {
+	mu     sync.RWMutex
+	states map[string]workerState
+}
+
+func newWorkerStates(workers []Worker) *workerStates {
+	states := make(map[string]workerState, len(workers))
+	for _, w := range workers {
+		states[w.Name()] = workerState{state: WorkerStarting}
+	}
+	return &workerStates{
+		states: states,
+	}
+}
+
+func (s *workerStates) set(name string, state string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[name] = workerState{state: state, err: err}
+	metrics.SetWorkerUp(name, state == WorkerRunning)
+}
+
+// check returns error describing every worker not in one of the allowed states
+func (s *workerStates) check(allowed ...string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var problems []string
+	for name, st := range s.states {
+		ok := false
+		for _, a := range allowed {
+			if st.state == a {
+				ok = true
+				break
+			}
+		}
+		if ok {
+			continue
+		}
+		if st.err != nil {
+			problems = append(problems, fmt.Sprintf("%s is %s: %s", name, st.state, st.err))
+		} else {
+			problems = append(problems, fmt.Sprintf("%s is %s", name, st.state))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("workers not ready: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -3,6 +3,43 @@ package kafka
 const (
 	TopicRecipesNew     = "recipes.new"
 	TopicRecipesReq     = "recipes.req"
+	TopicRecipesUpdate  = "recipes.update"
+	TopicRecipesRevert  = "recipes.revert"
+	TopicRecipesFork    = "recipes.fork"
+	TopicRecipesScale   = "recipes.scale"
+	TopicRevisionsReq   = "recipes.revisions.req"
+	TopicRevisions      = "recipes.revisions"
 	TopicRecipes        = "recipes"
 	TopicNutritionFacts = "nutritionfacts"
+	TopicRatingsNew     = "recipes.ratings.new"
+	TopicRatingsReq     = "recipes.ratings.req"
+	TopicRatings        = "recipes.ratings"
+	TopicCommentsNew    = "recipes.comments.new"
+	TopicCommentsUpdate = "recipes.comments.update"
+	TopicCommentsDelete = "recipes.comments.delete"
+	TopicCommentsReq    = "recipes.comments.req"
+	TopicComments       = "recipes.comments"
+	// TopicCommentsCreated notifies about new comments
+	TopicCommentsCreated = "recipes.comments.created"
+
+	TopicFavoritesUpdate   = "recipes.favorites.update"
+	TopicFavoritesReq      = "recipes.favorites.req"
+	TopicFavorites         = "recipes.favorites"
+	TopicCollectionsNew    = "recipes.collections.new"
+	TopicCollectionsUpdate = "recipes.collections.update"
+	TopicCollectionsItems  = "recipes.collections.items"
+	TopicCollectionsDelete = "recipes.collections.delete"
+	TopicCollectionsReq    = "recipes.collections.req"
+	TopicCollections       = "recipes.collections"
+
+	TopicCategoriesNew    = "recipes.categories.new"
+	TopicCategoriesDelete = "recipes.categories.delete"
+	TopicCategoriesReq    = "recipes.categories.req"
+	TopicCategories       = "recipes.categories"
+	// TopicUserDeleted and TopicUserDeletionAcks are owned by user-service
+	TopicUserDeleted      = "user.deleted"
+	TopicUserDeletionAcks = "user.deletion.acks"
+	// TopicExportStarted and TopicExportParts are owned by user-service
+	TopicExportStarted = "user.export.started"
+	TopicExportParts   = "user.export.parts"
 )
@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
+)
+
+type UpdateRecipeWorker struct {
+	recipeService      service.Service
+	updateRecipeReader *kafka.Reader
+	recipesWriter      *kafka.Writer
+}
+
+func NewUpdateRecipeWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	updateRecipeReader, err := newReader(brokers, "recipe-service-update", TopicRecipesUpdate)
+	if err != nil {
+		return nil, err
+	}
+	recipesWriter := newWriter(brokers, TopicRecipes)
+	return UpdateRecipeWorker{
+		recipeService:      recipeService,
+		updateRecipeReader: updateRecipeReader,
+		recipesWriter:      recipesWriter,
+	}, nil
+}
+
+func (w UpdateRecipeWorker) Name() string {
+	return workerName(w.updateRecipeReader)
+}
+
+func (w UpdateRecipeWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.UpdateRecipeDTO
+		msgCtx, corID, err := readDTO(ctx, w.updateRecipeReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got UpdateRecipeDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.updateRecipeReader, func() error {
+			var recip recipe.Recipe
+			var err error
+			if err = checkUser(msgCtx, dto.UserID, true); err == nil {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				err = w.recipeService.Update(cntx, dto)
+				if err == nil {
+					recip, err = w.recipeService.GetByID(cntx, dto.ID)
+				}
+				cancel()
+			}
+			// reply with recipe id makes nutrition-facts-service recalculate nutrition facts
+			recipeDTO := recipe.RecipeDTO{
+				ID: dto.ID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to update recipe")
+				recipeDTO.Error = err.Error()
+			} else {
+				recipeDTO.Recipe = recip
+			}
+
+			write(msgCtx, w.recipesWriter, dto.ID, recipeDTO, corID)
+			log.Info().Msgf("sent RecipeDTO: %+v", recipeDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.recipesWriter, dto.ID, recipe.RecipeDTO{
+				ID:    dto.ID,
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w UpdateRecipeWorker) Stop() error {
+	return closeAll(w.updateRecipeReader, w.recipesWriter)
+}
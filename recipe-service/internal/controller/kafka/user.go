@@ -0,0 +1,233 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/service"
+)
+
+// what happens to recipes of deleted user
+const (
+	UserDeletionAnonymize = "anonymize"
+	UserDeletionDelete    = "delete"
+)
+
+// serviceName identifies recipe-service in replies to user-service
+const serviceName = "recipe-service"
+
+type UserDeletedWorker struct {
+	recipeService     service.Service
+	mode              string
+	userDeletedReader *kafka.Reader
+	acksWriter        *kafka.Writer
+}
+
+func NewUserDeletedWorker(recipeService service.Service, brokers []string, mode string) (Worker, error) {
+	if mode != UserDeletionAnonymize && mode != UserDeletionDelete {
+		return nil, fmt.Errorf("unknown user deletion mode %q", mode)
+	}
+	userDeletedReader, err := newReader(brokers, "recipe-service-user-deleted", TopicUserDeleted)
+	if err != nil {
+		return nil, err
+	}
+	acksWriter := newWriter(brokers, TopicUserDeletionAcks)
+	return UserDeletedWorker{
+		recipeService:     recipeService,
+		mode:              mode,
+		userDeletedReader: userDeletedReader,
+		acksWriter:        acksWriter,
+	}, nil
+}
+
+func (w UserDeletedWorker) Name() string {
+	return workerName(w.userDeletedReader)
+}
+
+func (w UserDeletedWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.UserDeletedDTO
+		msgCtx, corID, err := readDTO(ctx, w.userDeletedReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got UserDeletedDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.userDeletedReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 30*time.Second)
+			var n int
+			var err error
+			if w.mode == UserDeletionDelete {
+				n, err = w.recipeService.DeleteAllByUser(cntx, dto.UserID)
+			} else {
+				n, err = w.recipeService.AnonymizeAllByUser(cntx, dto.UserID)
+			}
+			var ratings, comments, collections int
+			if err == nil {
+				ratings, err = w.recipeService.DeleteRatingsByUser(cntx, dto.UserID)
+			}
+			if err == nil {
+				comments, err = w.recipeService.AnonymizeCommentsByUser(cntx, dto.UserID)
+			}
+			if err == nil {
+				collections, err = w.recipeService.DeleteCollectionsByUser(cntx, dto.UserID)
+			}
+			cancel()
+			ackDTO := recipe.UserDeletionAckDTO{
+				UserID:  dto.UserID,
+				Service: serviceName,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to remove recipes of deleted user")
+				ackDTO.Error = err.Error()
+			} else {
+				log.Info().Msgf("%s: %d recipes of deleted user %s, deleted %d ratings, %d comments and %d collections",
+					w.mode, n, dto.UserID, ratings, comments, collections)
+			}
+
+			write(msgCtx, w.acksWriter, dto.UserID, ackDTO, corID)
+			log.Info().Msgf("sent UserDeletionAckDTO: %+v", ackDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.acksWriter, dto.UserID, recipe.UserDeletionAckDTO{
+				UserID:  dto.UserID,
+				Service: serviceName,
+				Error:   err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w UserDeletedWorker) Stop() error {
+	return closeAll(w.userDeletedReader, w.acksWriter)
+}
+
+type UserExportWorker struct {
+	recipeService       service.Service
+	exportStartedReader *kafka.Reader
+	partsWriter         *kafka.Writer
+}
+
+func NewUserExportWorker(recipeService service.Service, brokers []string) (Worker, error) {
+	exportStartedReader, err := newReader(brokers, "recipe-service-user-export", TopicExportStarted)
+	if err != nil {
+		return nil, err
+	}
+	partsWriter := newWriter(brokers, TopicExportParts)
+	return UserExportWorker{
+		recipeService:       recipeService,
+		exportStartedReader: exportStartedReader,
+		partsWriter:         partsWriter,
+	}, nil
+}
+
+func (w UserExportWorker) Name() string {
+	return workerName(w.exportStartedReader)
+}
+
+func (w UserExportWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto recipe.ExportStartedDTO
+		msgCtx, corID, err := readDTO(ctx, w.exportStartedReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got ExportStartedDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.exportStartedReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 30*time.Second)
+			rs, err := w.recipeService.GetAllByUser(cntx, dto.UserID)
+			var ratings []recipe.Rating
+			if err == nil {
+				ratings, err = w.recipeService.GetRatingsByUser(cntx, dto.UserID)
+			}
+			var comments []recipe.Comment
+			if err == nil {
+				comments, err = w.recipeService.GetCommentsByUser(cntx, dto.UserID)
+			}
+			var favorites []recipe.Favorite
+			if err == nil {
+				favorites, err = w.recipeService.GetFavorites(cntx, dto.UserID)
+			}
+			var collections []recipe.Collection
+			if err == nil {
+				collections, err = w.recipeService.GetCollections(cntx, dto.UserID, dto.UserID)
+			}
+			cancel()
+			partDTO := recipe.ExportPartDTO{
+				ExportID: dto.ExportID,
+				UserID:   dto.UserID,
+				Service:  serviceName,
+			}
+			if err == nil {
+				if rs == nil {
+					rs = []recipe.Recipe{}
+				}
+				if ratings == nil {
+					ratings = []recipe.Rating{}
+				}
+				if comments == nil {
+					comments = []recipe.Comment{}
+				}
+				if favorites == nil {
+					favorites = []recipe.Favorite{}
+				}
+				if collections == nil {
+					collections = []recipe.Collection{}
+				}
+				partDTO.Data, err = json.Marshal(recipe.UserData{
+					Recipes:     rs,
+					Ratings:     ratings,
+					Comments:    comments,
+					Favorites:   favorites,
+					Collections: collections,
+				})
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to export recipes of user")
+				partDTO.Error = err.Error()
+			}
+
+			write(msgCtx, w.partsWriter, dto.UserID, partDTO, corID)
+			log.Info().Msgf("sent ExportPartDTO of export %s with %d recipes, %d ratings, %d comments, %d favorites and %d collections",
+				dto.ExportID, len(rs), len(ratings), len(comments), len(favorites), len(collections))
+			return err
+		}, func(err error) {
+			write(msgCtx, w.partsWriter, dto.UserID, recipe.ExportPartDTO{
+				ExportID: dto.ExportID,
+				UserID:   dto.UserID,
+				Service:  serviceName,
+				Error:    err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w UserExportWorker) Stop() error {
+	return closeAll(w.exportStartedReader, w.partsWriter)
+}
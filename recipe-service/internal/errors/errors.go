@@ -5,4 +5,32 @@ import "errors"
 var (
 	ErrNotFound  = errors.New("not found")
 	ErrDuplicate = errors.New("duplicate")
+	// ErrUnverified is returned when user who has not verified email tries to publish
+	ErrUnverified = errors.New("email is not verified")
+	// ErrForbidden is returned when request made on behalf of one user concerns another one
+	ErrForbidden = errors.New("forbidden")
+	// ErrInvalidRating is returned for score out of range or too long review
+	ErrInvalidRating = errors.New("invalid rating")
+	// ErrOwnRecipe is returned when author tries to rate own recipe
+	ErrOwnRecipe = errors.New("can not rate own recipe")
+	// ErrInvalidComment is returned for empty or too long comment, or reply to comment of another recipe
+	ErrInvalidComment = errors.New("invalid comment")
+	// ErrCommentDeleted is returned on attempt to edit or reply to deleted comment
+	ErrCommentDeleted = errors.New("comment is deleted")
+	// ErrInvalidCollection is returned for collection with invalid name, visibility or recipes
+	ErrInvalidCollection = errors.New("invalid collection")
+	// ErrInvalidCategory is returned for unknown category of recipe or invalid new category
+	ErrInvalidCategory = errors.New("invalid category")
+	// ErrInvalidTags is returned for too many or too long tags
+	ErrInvalidTags = errors.New("invalid tags")
+	// ErrInvalidQuery is returned for too long search query
+	ErrInvalidQuery = errors.New("invalid query")
+	// ErrInvalidSort is returned for unknown sort order of listing
+	ErrInvalidSort = errors.New("invalid sort order")
+	// ErrInvalidCursor is returned for malformed cursor or cursor of listing in another order
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrConflict is returned when recipe has been changed since version the change is based on
+	ErrConflict = errors.New("version conflict")
+	// ErrInvalidServings is returned for invalid number of servings and for scaling of recipe without servings
+	ErrInvalidServings = errors.New("invalid servings")
 )
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaCollector exposes segmentio reader and writer stats. Stats() returns counters accumulated since
+// previous call, so they are added to prometheus counters on every scrape.
+type kafkaCollector struct {
+	mu      sync.Mutex
+	readers []*kafka.Reader
+	writers []*kafka.Writer
+
+	readerLag        *prometheus.GaugeVec
+	readerMessages   *prometheus.CounterVec
+	readerRebalances *prometheus.CounterVec
+	readerErrors     *prometheus.CounterVec
+	readerTimeouts   *prometheus.CounterVec
+	writerWrites     *prometheus.CounterVec
+	writerMessages   *prometheus.CounterVec
+	writerErrors     *prometheus.CounterVec
+	writerRetries    *prometheus.CounterVec
+}
+
+var collector = newKafkaCollector()
+
+func init() {
+	prometheus.MustRegister(collector)
+}
+
+func newKafkaCollector() *kafkaCollector {
+	readerLabels := []string{"topic", "group"}
+	writerLabels := []string{"topic"}
+	return &kafkaCollector{
+		readerLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "kafka_reader",
+			Name:      "lag",
+			Help:      "Kafka reader lag",
+		}, readerLabels),
+		readerMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka_reader",
+			Name:      "messages_total",
+			Help:      "Number of messages read by kafka reader",
+		}, readerLabels),
+		readerRebalances: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka_reader",
+			Name:      "rebalances_total",
+			Help:      "Number of consumer group rebalances",
+		}, readerLabels),
+		readerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka_reader",
+			Name:      "errors_total",
+			Help:      "Number of kafka reader errors",
+		}, readerLabels),
+		readerTimeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka_reader",
+			Name:      "timeouts_total",
+			Help:      "Number of kafka reader timeouts",
+		}, readerLabels),
+		writerWrites: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka_writer",
+			Name:      "writes_total",
+			Help:      "Number of kafka writer write requests",
+		}, writerLabels),
+		writerMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka_writer",
+			Name:      "messages_total",
+			Help:      "Number of messages written by kafka writer",
+		}, writerLabels),
+		writerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka_writer",
+			Name:      "errors_total",
+			Help:      "Number of kafka writer errors",
+		}, writerLabels),
+		writerRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka_writer",
+			Name:      "retries_total",
+			Help:      "Number of kafka writer retries",
+		}, writerLabels),
+	}
+}
+
+func RegisterReader(reader *kafka.Reader) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	collector.readers = append(collector.readers, reader)
+}
+
+func RegisterWriter(writer *kafka.Writer) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	collector.writers = append(collector.writers, writer)
+}
+
+func (c *kafkaCollector) vectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.readerLag, c.readerMessages, c.readerRebalances, c.readerErrors, c.readerTimeouts,
+		c.writerWrites, c.writerMessages, c.writerErrors, c.writerRetries,
+	}
+}
+
+func (c *kafkaCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, v := range c.vectors() {
+		v.Describe(ch)
+	}
+}
+
+func (c *kafkaCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, r := range c.readers {
+		stats := r.Stats()
+		labels := prometheus.Labels{"topic": stats.Topic, "group": r.Config().GroupID}
+		c.readerLag.With(labels).Set(float64(stats.Lag))
+		c.readerMessages.With(labels).Add(float64(stats.Messages))
+		c.readerRebalances.With(labels).Add(float64(stats.Rebalances))
+		c.readerErrors.With(labels).Add(float64(stats.Errors))
+		c.readerTimeouts.With(labels).Add(float64(stats.Timeouts))
+	}
+	for _, w := range c.writers {
+		stats := w.Stats()
+		labels := prometheus.Labels{"topic": w.Topic}
+		c.writerWrites.With(labels).Add(float64(stats.Writes))
+		c.writerMessages.With(labels).Add(float64(stats.Messages))
+		c.writerErrors.With(labels).Add(float64(stats.Errors))
+		c.writerRetries.With(labels).Add(float64(stats.Retries))
+	}
+
+	for _, v := range c.vectors() {
+		v.Collect(ch)
+	}
+}
@@ -1,5 +1,10 @@
 package recipe
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type Step struct {
 	Description string `json:"description" bson:"description"`
 }
@@ -17,40 +22,434 @@ type Recipe struct {
 	Ingredients    []RecipeIngredient `json:"ingredients,omitempty" bson:"ingredients,omitempty"`
 	Steps          []Step             `json:"steps,omitempty" bson:"steps,omitempty"`
 	NutritionFacts *NutritionFacts    `json:"nutrition_facts,omitempty" bson:"nutrition_facts,omitempty"`
+	// Servings is number of servings recipe makes, recipes without it can not be scaled
+	Servings int64 `json:"servings,omitempty" bson:"servings,omitempty"`
+	// Categories are ids of categories from controlled vocabulary, Tags are free form
+	Categories []string `json:"categories,omitempty" bson:"categories,omitempty"`
+	Tags       []string `json:"tags,omitempty" bson:"tags,omitempty"`
+	// IngredientNames are names of ingredients received from nutrition-facts-service, they are used for search
+	IngredientNames []string `json:"ingredient_names,omitempty" bson:"ingredient_names,omitempty"`
+	// RatingAverage and RatingCount summarize ratings of recipe, they are kept up to date on every rating
+	RatingAverage float64 `json:"rating_average" bson:"rating_average,omitempty"`
+	RatingCount   int64   `json:"rating_count" bson:"rating_count,omitempty"`
+	// SavedCount is number of favorites and collections recipe is saved to
+	SavedCount int64 `json:"saved_count" bson:"saved_count,omitempty"`
+	// TextScore is relevance of recipe to text query, it is set only in listings in order of relevance
+	TextScore float64 `json:"-" bson:"score,omitempty"`
+	// Version is incremented on every change of recipe content by users, changes must be based on the current version
+	Version int64 `json:"version" bson:"version"`
+	// Draft recipes are visible only to their authors until published
+	Draft  bool    `json:"draft,omitempty" bson:"draft,omitempty"`
+	ForkOf *ForkOf `json:"fork_of,omitempty" bson:"fork_of,omitempty"`
+}
+
+// ForkOf links fork to recipe it was copied from. Name and author of original recipe are copied as well,
+// so that the link is kept when original recipe is deleted
+type ForkOf struct {
+	RecipeID string `json:"recipe_id" bson:"recipe_id"`
+	AuthorID string `json:"author_id" bson:"author_id"`
+	Name     string `json:"name" bson:"name"`
+	// Version is version of original recipe which was copied
+	Version int64 `json:"version" bson:"version"`
+}
+
+// Revision is immutable snapshot of recipe saved on every change of recipe. Revisions of recipe are numbered from 1
+type Revision struct {
+	ID       string `json:"id" bson:"_id,omitempty"`
+	RecipeID string `json:"recipe_id" bson:"recipe_id"`
+	Number   int64  `json:"number" bson:"number"`
+	// Author is user who changed recipe, it is empty for changes made by services, e.g. calculated nutrition facts
+	Author    string    `json:"author,omitempty" bson:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	// Changed are names of fields changed by revision
+	Changed []string `json:"changed" bson:"changed"`
+	// RevertedFrom is number of earlier revision restored by this one
+	RevertedFrom int64  `json:"reverted_from,omitempty" bson:"reverted_from,omitempty"`
+	Recipe       Recipe `json:"recipe" bson:"recipe"`
+}
+
+// FieldChange is difference of field of recipe between two revisions, empty values are omitted
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from,omitempty"`
+	To    interface{} `json:"to,omitempty"`
 }
 
+// kinds of categories
+const (
+	CategoryCuisine  = "cuisine"
+	CategoryMealType = "meal_type"
+	CategoryCourse   = "course"
+	CategoryDiet     = "diet"
+)
+
+// Category is entry of controlled vocabulary managed by administrators, e.g. cuisine "italian"
+type Category struct {
+	ID   string `json:"id" bson:"_id,omitempty"`
+	Kind string `json:"kind" bson:"kind"`
+	// Name is unique within kind
+	Name  string `json:"name" bson:"name"`
+	Title string `json:"title" bson:"title"`
+}
+
+// Filter selects recipes matching text query, having all of categories and all of tags.
+// Recipes matching query are ordered by relevance
+type Filter struct {
+	Query      string   `json:"query,omitempty"`
+	CreatedBy  string   `json:"created_by,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	// MinCalories and MaxCalories limit calories of recipe, recipes without nutrition facts do not match them
+	MinCalories *float64 `json:"min_calories,omitempty"`
+	MaxCalories *float64 `json:"max_calories,omitempty"`
+	// ForkOf selects forks of recipe
+	ForkOf string `json:"fork_of,omitempty"`
+	// Drafts includes drafts of CreatedBy, it is set only for requests made by CreatedBy themselves
+	Drafts bool `json:"-"`
+}
+
+// sort orders of recipe listings
+const (
+	SortNewest = "newest"
+	SortName   = "name"
+	// SortRating and SortCalories list only recipes which are rated or have nutrition facts
+	SortRating   = "rating"
+	SortCalories = "calories"
+	// SortRelevance is order of recipes matching text query, it is default for listings with query
+	SortRelevance = "relevance"
+)
+
+// Cursor is position of listing after last recipe of page. Value is sort key of that recipe for
+// name, rating, calories and relevance orders
+type Cursor struct {
+	Sort  string      `json:"s"`
+	ID    string      `json:"id"`
+	Value interface{} `json:"v"`
+}
+
+// PageRequest requests page of listing, Cursor is NextCursor of previous page, empty for first page
+type PageRequest struct {
+	Sort   string `json:"sort,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int64  `json:"limit,omitempty"`
+}
+
+const (
+	MinScore = 1
+	MaxScore = 5
+)
+
+// Rating is score and optional review of recipe by user, user has one rating per recipe
+type Rating struct {
+	ID        string    `json:"id" bson:"_id,omitempty"`
+	RecipeID  string    `json:"recipe_id" bson:"recipe_id"`
+	UserID    string    `json:"user_id" bson:"user_id"`
+	Score     int       `json:"score" bson:"score"`
+	Review    string    `json:"review,omitempty" bson:"review,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Comment is comment on recipe or reply to another comment of the same recipe.
+// Deleted comments keep their place in discussion, but lose text
+type Comment struct {
+	ID       string `json:"id" bson:"_id,omitempty"`
+	RecipeID string `json:"recipe_id" bson:"recipe_id"`
+	// ParentID is id of comment this one replies to, empty for top level comments
+	ParentID   string     `json:"parent_id,omitempty" bson:"parent_id"`
+	UserID     string     `json:"user_id" bson:"user_id"`
+	Text       string     `json:"text" bson:"text"`
+	ReplyCount int64      `json:"reply_count" bson:"reply_count"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" bson:"updated_at"`
+	Deleted    bool       `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	DeletedBy  string     `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+}
+
+// Favorite is recipe saved by user
+type Favorite struct {
+	RecipeID  string    `json:"recipe_id" bson:"recipe_id"`
+	UserID    string    `json:"user_id" bson:"user_id"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// visibility of collection
+const (
+	VisibilityPrivate = "private"
+	VisibilityShared  = "shared"
+)
+
+// Collection is named list of recipes made by user, recipes are kept in order chosen by user
+type Collection struct {
+	ID          string    `json:"id" bson:"_id,omitempty"`
+	UserID      string    `json:"user_id" bson:"user_id"`
+	Name        string    `json:"name" bson:"name"`
+	Description string    `json:"description,omitempty" bson:"description,omitempty"`
+	Visibility  string    `json:"visibility" bson:"visibility"`
+	RecipeIDs   []string  `json:"recipe_ids" bson:"recipe_ids"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// DeletedUser replaces CreatedBy of recipes kept after their author has deleted account
+const DeletedUser = "deleted"
+
 type CreateRecipeDTO struct {
 	Name        string             `json:"name"`
 	CreatedBy   string             `json:"created_by"`
 	Ingredients []RecipeIngredient `json:"ingredients,omitempty"`
 	Steps       []Step             `json:"steps,omitempty"`
+	Servings    int64              `json:"servings,omitempty"`
+	Categories  []string           `json:"categories,omitempty"`
+	Tags        []string           `json:"tags,omitempty"`
 }
 
+// UpdateRecipeDTO changes content of recipe, Version is version of recipe the change is based on
 type UpdateRecipeDTO struct {
-	ID             string             `json:"id"`
-	Name           string             `json:"name"`
-	Ingredients    []RecipeIngredient `json:"ingredients,omitempty"`
-	Steps          []Step             `json:"steps,omitempty"`
-	NutritionFacts *NutritionFacts    `json:"nutrition_facts,omitempty"`
+	ID string `json:"id"`
+	// UserID is author of recipe changing it, it is empty for changes made by services
+	UserID      string             `json:"user_id,omitempty"`
+	Version     int64              `json:"version"`
+	Name        string             `json:"name"`
+	Ingredients []RecipeIngredient `json:"ingredients,omitempty"`
+	Steps       []Step             `json:"steps,omitempty"`
+	Servings    int64              `json:"servings,omitempty"`
+	Categories  []string           `json:"categories,omitempty"`
+	Tags        []string           `json:"tags,omitempty"`
+	// Publish makes draft visible to everyone
+	Publish bool `json:"publish,omitempty"`
+}
+
+// ForkRecipeDTO copies recipe into drafts of user
+type ForkRecipeDTO struct {
+	RecipeID string `json:"recipe_id"`
+	UserID   string `json:"user_id"`
+}
+
+// ScaleRecipeDTO requests recipe scaled to Servings, scaled recipe is saved into drafts of UserID if Save is set
+type ScaleRecipeDTO struct {
+	RecipeID string `json:"recipe_id"`
+	UserID   string `json:"user_id"`
+	Servings int64  `json:"servings"`
+	Save     bool   `json:"save,omitempty"`
 }
 
 type FindRecipeDTO struct {
 	ID            string   `json:"recipe_id"`
 	UserID        string   `json:"user_id"`
 	IngredientIDs []string `json:"ingredient_ids"`
+	// TopRated is number of best rated recipes to find
+	TopRated int64 `json:"top_rated,omitempty"`
+	// ForksOf requests page of forks of recipe
+	ForksOf string `json:"forks_of,omitempty"`
+	// Filter requests page of recipes matching it, empty filter matches all recipes.
+	// Recipes of UserID and recipes matching Filter are listed by pages
+	Filter *Filter `json:"filter,omitempty"`
+	PageRequest
+}
+
+// StreamPart marks reply to multi-result query. Results are numbered by Seq starting from 1 and carry Total
+// number of results, stream ends with message having Done set and no result, which is sent even if nothing is found
+type StreamPart struct {
+	Seq   int  `json:"seq,omitempty"`
+	Total int  `json:"total"`
+	Done  bool `json:"done,omitempty"`
+}
+
+// RevertRecipeDTO restores content of recipe from its earlier revision
+type RevertRecipeDTO struct {
+	RecipeID string `json:"recipe_id"`
+	UserID   string `json:"user_id"`
+	Revision int64  `json:"revision"`
+}
+
+// FindRevisionsDTO requests difference between revisions From and To if both are set, or all revisions of recipe
+type FindRevisionsDTO struct {
+	RecipeID string `json:"recipe_id"`
+	From     int64  `json:"from,omitempty"`
+	To       int64  `json:"to,omitempty"`
+}
+
+type RevisionDTO struct {
+	Revision Revision      `json:"revision,omitempty"`
+	RecipeID string        `json:"recipe_id,omitempty"`
+	Diff     []FieldChange `json:"diff,omitempty"`
+	Stream   *StreamPart   `json:"stream,omitempty"`
+	Error    string        `json:"error,omitempty"`
 }
 
 type RecipeDTO struct {
 	Recipe Recipe `json:"recipe,omitempty"`
 	ID     string `json:"recipe_id,omitempty"`
 	UserID string `json:"user_id,omitempty"`
-	Error  string `json:"error,omitempty"`
+	// NextCursor is cursor of the next page set in every reply of page, it is empty for last page
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Stream     *StreamPart `json:"stream,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// RateRecipeDTO creates rating of user or replaces it
+type RateRecipeDTO struct {
+	RecipeID string `json:"recipe_id"`
+	UserID   string `json:"user_id"`
+	Score    int    `json:"score"`
+	Review   string `json:"review,omitempty"`
+}
+
+// FindRatingsDTO requests rating of user for recipe if both are set, or all ratings of recipe
+type FindRatingsDTO struct {
+	RecipeID string `json:"recipe_id"`
+	UserID   string `json:"user_id,omitempty"`
+}
+
+type RatingDTO struct {
+	Rating   Rating      `json:"rating,omitempty"`
+	RecipeID string      `json:"recipe_id,omitempty"`
+	UserID   string      `json:"user_id,omitempty"`
+	Stream   *StreamPart `json:"stream,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+type AddCommentDTO struct {
+	RecipeID string `json:"recipe_id"`
+	ParentID string `json:"parent_id,omitempty"`
+	UserID   string `json:"user_id"`
+	Text     string `json:"text"`
+}
+
+type EditCommentDTO struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Text   string `json:"text"`
+}
+
+// DeleteCommentDTO deletes comment on behalf of UserID, who is either author of comment or author of recipe
+type DeleteCommentDTO struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+// FindCommentsDTO requests comment by ID or page of comments of recipe replying to ParentID (top level comments if empty)
+type FindCommentsDTO struct {
+	ID       string `json:"id,omitempty"`
+	RecipeID string `json:"recipe_id,omitempty"`
+	ParentID string `json:"parent_id,omitempty"`
+	Start    int64  `json:"start,omitempty"`
+	Limit    int64  `json:"limit,omitempty"`
+}
+
+type CommentDTO struct {
+	Comment  Comment     `json:"comment,omitempty"`
+	ID       string      `json:"id,omitempty"`
+	RecipeID string      `json:"recipe_id,omitempty"`
+	Stream   *StreamPart `json:"stream,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// CommentCreatedDTO is published for notification consumers when comment is added
+type CommentCreatedDTO struct {
+	Comment Comment `json:"comment"`
+	// RecipeAuthorID and ParentAuthorID are users who may want to know about the comment
+	RecipeAuthorID string `json:"recipe_author_id"`
+	ParentAuthorID string `json:"parent_author_id,omitempty"`
+}
+
+// UpdateFavoriteDTO adds recipe to favorites of user, or removes it if Remove is set
+type UpdateFavoriteDTO struct {
+	UserID   string `json:"user_id"`
+	RecipeID string `json:"recipe_id"`
+	Remove   bool   `json:"remove,omitempty"`
+}
+
+type FindFavoritesDTO struct {
+	UserID string `json:"user_id"`
+}
+
+type FavoriteDTO struct {
+	Favorite Favorite    `json:"favorite,omitempty"`
+	UserID   string      `json:"user_id,omitempty"`
+	RecipeID string      `json:"recipe_id,omitempty"`
+	Stream   *StreamPart `json:"stream,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+type CreateCollectionDTO struct {
+	UserID      string   `json:"user_id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Visibility  string   `json:"visibility,omitempty"`
+	RecipeIDs   []string `json:"recipe_ids,omitempty"`
+}
+
+// UpdateCollectionDTO replaces name, description and visibility of collection.
+// RecipeIDs is new order of recipes of collection, recipes missing from it are removed from collection
+type UpdateCollectionDTO struct {
+	ID          string   `json:"id"`
+	UserID      string   `json:"user_id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Visibility  string   `json:"visibility,omitempty"`
+	RecipeIDs   []string `json:"recipe_ids"`
+}
+
+// CollectionItemDTO adds recipe to the end of collection, or removes it if Remove is set
+type CollectionItemDTO struct {
+	CollectionID string `json:"collection_id"`
+	UserID       string `json:"user_id"`
+	RecipeID     string `json:"recipe_id"`
+	Remove       bool   `json:"remove,omitempty"`
+}
+
+type DeleteCollectionDTO struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+// FindCollectionsDTO requests collection by ID or all collections of user
+type FindCollectionsDTO struct {
+	ID     string `json:"id,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+type CollectionDTO struct {
+	Collection Collection  `json:"collection,omitempty"`
+	ID         string      `json:"id,omitempty"`
+	UserID     string      `json:"user_id,omitempty"`
+	Stream     *StreamPart `json:"stream,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+type CreateCategoryDTO struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
+
+type DeleteCategoryDTO struct {
+	ID string `json:"id"`
+}
+
+// FindCategoriesDTO requests categories of kind, or all categories if kind is empty
+type FindCategoriesDTO struct {
+	Kind string `json:"kind,omitempty"`
+}
+
+type CategoryDTO struct {
+	Category Category `json:"category,omitempty"`
+	// RecipeCount is number of recipes in category
+	RecipeCount int64       `json:"recipe_count"`
+	ID          string      `json:"id,omitempty"`
+	Stream      *StreamPart `json:"stream,omitempty"`
+	Error       string      `json:"error,omitempty"`
 }
 
 type RecipeNutritionsDTO struct {
 	RecipeID       string         `json:"recipe_id"`
 	NutritionFacts NutritionFacts `json:"nutrition_facts"`
 	Inaccurate     bool           `json:"is_inaccurate"`
+	// IngredientNames are names of ingredients of recipe
+	IngredientNames []string `json:"ingredient_names,omitempty"`
 }
 
 type NutritionFacts struct {
@@ -59,3 +458,35 @@ type NutritionFacts struct {
 	Fats          float64 `json:"fats" bson:"fats"`
 	Carbohydrates float64 `json:"carbohydrates" bson:"carbohydrates"`
 }
+
+type UserDeletedDTO struct {
+	UserID string `json:"user_id"`
+}
+
+type UserDeletionAckDTO struct {
+	UserID  string `json:"user_id"`
+	Service string `json:"service"`
+	Error   string `json:"error,omitempty"`
+}
+
+type ExportStartedDTO struct {
+	ExportID string `json:"export_id"`
+	UserID   string `json:"user_id"`
+}
+
+type ExportPartDTO struct {
+	ExportID string          `json:"export_id"`
+	UserID   string          `json:"user_id"`
+	Service  string          `json:"service"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// UserData is the part of user data export held by recipe-service
+type UserData struct {
+	Recipes     []Recipe     `json:"recipes"`
+	Ratings     []Rating     `json:"ratings"`
+	Comments    []Comment    `json:"comments"`
+	Favorites   []Favorite   `json:"favorites"`
+	Collections []Collection `json:"collections"`
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+)
+
+const (
+	maxRecipeCategories = 10
+	maxRecipeTags       = 20
+	maxTagLength        = 30
+	maxCategoryName     = 50
+	maxCategoryTitle    = 50
+	// defaultRecipesLimit is used when page size is not set, maxRecipesLimit caps requested page size
+	defaultRecipesLimit = 20
+	maxRecipesLimit     = 100
+)
+
+var categoryNameRe = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func (s service) CreateCategory(ctx context.Context, dto recipe.CreateCategoryDTO) (c recipe.Category, err error) {
+	c = recipe.Category{
+		Kind:  dto.Kind,
+		Name:  strings.ToLower(strings.TrimSpace(dto.Name)),
+		Title: strings.TrimSpace(dto.Title),
+	}
+	if !validCategoryKind(c.Kind) || len(c.Name) > maxCategoryName || !categoryNameRe.MatchString(c.Name) ||
+		len(c.Title) == 0 || utf8.RuneCountInString(c.Title) > maxCategoryTitle {
+		return c, apperror.ErrInvalidCategory
+	}
+
+	c.ID, err = s.storage.CreateCategory(ctx, c)
+	if err != nil {
+		if errors.Is(err, apperror.ErrDuplicate) {
+			return c, err
+		}
+		return c, fmt.Errorf("could not create category: %w", err)
+	}
+	return
+}
+
+func (s service) DeleteCategory(ctx context.Context, id string) error {
+	err := s.storage.DeleteCategory(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("could not delete category: %w", err)
+	}
+	return nil
+}
+
+func (s service) GetCategories(ctx context.Context, kind string) ([]recipe.CategoryDTO, error) {
+	cs, err := s.storage.GetCategories(ctx, kind)
+	if err != nil {
+		return nil, fmt.Errorf("could not get categories: %w", err)
+	}
+	counts, err := s.storage.CountByCategory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not count recipes: %w", err)
+	}
+
+	dtos := make([]recipe.CategoryDTO, 0, len(cs))
+	for _, c := range cs {
+		dtos = append(dtos, recipe.CategoryDTO{
+			Category:    c,
+			RecipeCount: counts[c.ID],
+			ID:          c.ID,
+		})
+	}
+	return dtos, nil
+}
+
+// normalizeCategories checks that categories exist and removes duplicates
+func (s service) normalizeCategories(ctx context.Context, categories []string) ([]string, error) {
+	if len(categories) == 0 {
+		return nil, nil
+	}
+	if len(categories) > maxRecipeCategories {
+		return nil, apperror.ErrInvalidCategory
+	}
+	result := make([]string, 0, len(categories))
+	seen := make(map[string]bool, len(categories))
+	for _, id := range categories {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		_, err := s.storage.GetCategory(ctx, id)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return nil, apperror.ErrInvalidCategory
+			}
+			return nil, fmt.Errorf("could not get category: %w", err)
+		}
+		result = append(result, id)
+	}
+	return result, nil
+}
+
+// normalizeTags trims tags and converts them to lower case, empty and duplicate tags are removed
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if len(tag) == 0 || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, apperror.ErrInvalidTags
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	if len(result) > maxRecipeTags {
+		return nil, apperror.ErrInvalidTags
+	}
+	return result, nil
+}
+
+func validCategoryKind(kind string) bool {
+	switch kind {
+	case recipe.CategoryCuisine, recipe.CategoryMealType, recipe.CategoryCourse, recipe.CategoryDiet:
+		return true
+	}
+	return false
+}
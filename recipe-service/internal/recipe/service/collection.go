@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+)
+
+const (
+	maxCollectionNameLength        = 100
+	maxCollectionDescriptionLength = 500
+	maxCollectionRecipes           = 1000
+)
+
+func (s service) AddFavorite(ctx context.Context, userID string, recipeID string) (f recipe.Favorite, err error) {
+	_, err = s.visibleRecipe(ctx, recipeID, userID)
+	if err != nil {
+		return
+	}
+	f = recipe.Favorite{
+		RecipeID:  recipeID,
+		UserID:    userID,
+		CreatedAt: time.Now().UTC(),
+	}
+	err = s.storage.AddFavorite(ctx, f)
+	if err != nil {
+		return f, fmt.Errorf("could not add favorite: %w", err)
+	}
+	return f, s.updateSavedCount(ctx, recipeID)
+}
+
+func (s service) RemoveFavorite(ctx context.Context, userID string, recipeID string) error {
+	err := s.storage.RemoveFavorite(ctx, userID, recipeID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("could not remove favorite: %w", err)
+	}
+	return s.updateSavedCount(ctx, recipeID)
+}
+
+func (s service) GetFavorites(ctx context.Context, userID string) (fs []recipe.Favorite, err error) {
+	fs, err = s.storage.GetFavorites(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get favorites: %w", err)
+	}
+	return
+}
+
+func (s service) CreateCollection(ctx context.Context, dto recipe.CreateCollectionDTO) (c recipe.Collection, err error) {
+	now := time.Now().UTC()
+	c = recipe.Collection{
+		UserID:      dto.UserID,
+		Name:        dto.Name,
+		Description: dto.Description,
+		Visibility:  dto.Visibility,
+		RecipeIDs:   dto.RecipeIDs,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	err = normalizeCollection(&c)
+	if err != nil {
+		return
+	}
+	for _, id := range c.RecipeIDs {
+		_, err = s.visibleRecipe(ctx, id, c.UserID)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return c, apperror.ErrInvalidCollection
+			}
+			return
+		}
+	}
+
+	c.ID, err = s.storage.CreateCollection(ctx, c)
+	if err != nil {
+		return c, fmt.Errorf("could not create collection: %w", err)
+	}
+	for _, id := range c.RecipeIDs {
+		err = s.updateSavedCount(ctx, id)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (s service) UpdateCollection(ctx context.Context, dto recipe.UpdateCollectionDTO) (c recipe.Collection, err error) {
+	old, err := s.ownCollection(ctx, dto.ID, dto.UserID)
+	if err != nil {
+		return
+	}
+
+	c = recipe.Collection{
+		ID:          old.ID,
+		UserID:      old.UserID,
+		Name:        dto.Name,
+		Description: dto.Description,
+		Visibility:  dto.Visibility,
+		RecipeIDs:   dto.RecipeIDs,
+		CreatedAt:   old.CreatedAt,
+		UpdatedAt:   time.Now().UTC(),
+	}
+	err = normalizeCollection(&c)
+	if err != nil {
+		return
+	}
+	// update only reorders and removes recipes, they are added one by one with AddToCollection
+	kept := make(map[string]bool, len(c.RecipeIDs))
+	for _, id := range c.RecipeIDs {
+		kept[id] = true
+	}
+	current := make(map[string]bool, len(old.RecipeIDs))
+	for _, id := range old.RecipeIDs {
+		current[id] = true
+	}
+	for id := range kept {
+		if !current[id] {
+			return c, apperror.ErrInvalidCollection
+		}
+	}
+
+	err = s.storage.UpdateCollection(ctx, c)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return c, err
+		}
+		return c, fmt.Errorf("could not update collection: %w", err)
+	}
+	for _, id := range old.RecipeIDs {
+		if !kept[id] {
+			err = s.updateSavedCount(ctx, id)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (s service) UpdateCollectionItem(ctx context.Context, dto recipe.CollectionItemDTO) (c recipe.Collection, err error) {
+	c, err = s.ownCollection(ctx, dto.CollectionID, dto.UserID)
+	if err != nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	if dto.Remove {
+		err = s.storage.RemoveFromCollection(ctx, dto.CollectionID, dto.RecipeID, now)
+	} else {
+		if len(c.RecipeIDs) >= maxCollectionRecipes {
+			return c, apperror.ErrInvalidCollection
+		}
+		_, err = s.visibleRecipe(ctx, dto.RecipeID, dto.UserID)
+		if err != nil {
+			return
+		}
+		err = s.storage.AddToCollection(ctx, dto.CollectionID, dto.RecipeID, now)
+	}
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return c, err
+		}
+		return c, fmt.Errorf("could not update collection: %w", err)
+	}
+
+	err = s.updateSavedCount(ctx, dto.RecipeID)
+	if err != nil {
+		return
+	}
+	return s.GetCollection(ctx, dto.CollectionID, dto.UserID)
+}
+
+func (s service) DeleteCollection(ctx context.Context, dto recipe.DeleteCollectionDTO) error {
+	c, err := s.ownCollection(ctx, dto.ID, dto.UserID)
+	if err != nil {
+		return err
+	}
+	err = s.storage.DeleteCollection(ctx, dto.ID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("could not delete collection: %w", err)
+	}
+	for _, id := range c.RecipeIDs {
+		err = s.updateSavedCount(ctx, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s service) GetCollection(ctx context.Context, id string, viewerID string) (c recipe.Collection, err error) {
+	c, err = s.storage.GetCollection(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return
+		}
+		return c, fmt.Errorf("could not get collection: %w", err)
+	}
+	// private collections are not disclosed to other users
+	if !canView(c, viewerID) {
+		return recipe.Collection{}, apperror.ErrNotFound
+	}
+	return
+}
+
+func (s service) GetCollections(ctx context.Context, userID string, viewerID string) ([]recipe.Collection, error) {
+	cs, err := s.storage.GetCollectionsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get collections: %w", err)
+	}
+	visible := cs[:0]
+	for _, c := range cs {
+		if canView(c, viewerID) {
+			visible = append(visible, c)
+		}
+	}
+	return visible, nil
+}
+
+func (s service) DeleteCollectionsByUser(ctx context.Context, userID string) (int, error) {
+	favorites, err := s.GetFavorites(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	collections, err := s.storage.GetCollectionsByUser(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("could not get collections: %w", err)
+	}
+
+	err = s.storage.DeleteFavoritesByUser(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete favorites: %w", err)
+	}
+	err = s.storage.DeleteCollectionsByUser(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete collections: %w", err)
+	}
+
+	recipeIDs := make(map[string]bool)
+	for _, f := range favorites {
+		recipeIDs[f.RecipeID] = true
+	}
+	for _, c := range collections {
+		for _, id := range c.RecipeIDs {
+			recipeIDs[id] = true
+		}
+	}
+	for id := range recipeIDs {
+		err = s.updateSavedCount(ctx, id)
+		if err != nil {
+			return len(collections), err
+		}
+	}
+	return len(collections), nil
+}
+
+// ownCollection returns collection of user, empty user is internal client allowed to change any collection
+func (s service) ownCollection(ctx context.Context, id string, userID string) (c recipe.Collection, err error) {
+	c, err = s.storage.GetCollection(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return
+		}
+		return c, fmt.Errorf("could not get collection: %w", err)
+	}
+	if len(userID) > 0 && c.UserID != userID {
+		if c.Visibility != recipe.VisibilityShared {
+			return recipe.Collection{}, apperror.ErrNotFound
+		}
+		return c, apperror.ErrForbidden
+	}
+	return
+}
+
+func (s service) updateSavedCount(ctx context.Context, recipeID string) error {
+	err := s.storage.UpdateSavedCount(ctx, recipeID)
+	// recipe could be deleted already
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return fmt.Errorf("could not update saved count of recipe %s: %w", recipeID, err)
+	}
+	return nil
+}
+
+func canView(c recipe.Collection, viewerID string) bool {
+	return (len(viewerID) > 0 && c.UserID == viewerID) || c.Visibility == recipe.VisibilityShared
+}
+
+// normalizeCollection validates collection and trims its name and description, duplicate recipes are removed
+func normalizeCollection(c *recipe.Collection) error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Description = strings.TrimSpace(c.Description)
+	if len(c.Visibility) == 0 {
+		c.Visibility = recipe.VisibilityPrivate
+	}
+	if len(c.Name) == 0 || utf8.RuneCountInString(c.Name) > maxCollectionNameLength ||
+		utf8.RuneCountInString(c.Description) > maxCollectionDescriptionLength ||
+		(c.Visibility != recipe.VisibilityPrivate && c.Visibility != recipe.VisibilityShared) ||
+		len(c.RecipeIDs) > maxCollectionRecipes {
+		return apperror.ErrInvalidCollection
+	}
+
+	recipeIDs := make([]string, 0, len(c.RecipeIDs))
+	seen := make(map[string]bool, len(c.RecipeIDs))
+	for _, id := range c.RecipeIDs {
+		if !seen[id] {
+			seen[id] = true
+			recipeIDs = append(recipeIDs, id)
+		}
+	}
+	c.RecipeIDs = recipeIDs
+	return nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+)
+
+const (
+	// maxCommentLength is maximum length of comment in characters
+	maxCommentLength = 2000
+	// defaultCommentsLimit is used when page size is not set, maxCommentsLimit caps requested page size
+	defaultCommentsLimit = 20
+	maxCommentsLimit     = 100
+)
+
+func (s service) AddComment(ctx context.Context, dto recipe.AddCommentDTO) (c recipe.Comment, event recipe.CommentCreatedDTO, err error) {
+	text, err := normalizeComment(dto.Text)
+	if err != nil {
+		return
+	}
+
+	rec, err := s.visibleRecipe(ctx, dto.RecipeID, dto.UserID)
+	if err != nil {
+		return
+	}
+	event.RecipeAuthorID = rec.CreatedBy
+
+	if len(dto.ParentID) > 0 {
+		var parent recipe.Comment
+		parent, err = s.GetComment(ctx, dto.ParentID)
+		if err != nil {
+			return
+		}
+		if parent.RecipeID != dto.RecipeID {
+			return c, event, apperror.ErrInvalidComment
+		}
+		if parent.Deleted {
+			return c, event, apperror.ErrCommentDeleted
+		}
+		event.ParentAuthorID = parent.UserID
+	}
+
+	now := time.Now().UTC()
+	c = recipe.Comment{
+		RecipeID:  dto.RecipeID,
+		ParentID:  dto.ParentID,
+		UserID:    dto.UserID,
+		Text:      text,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	c.ID, err = s.storage.CreateComment(ctx, c)
+	if err != nil {
+		return c, event, fmt.Errorf("could not create comment: %w", err)
+	}
+	event.Comment = c
+	return
+}
+
+func (s service) EditComment(ctx context.Context, dto recipe.EditCommentDTO) (c recipe.Comment, err error) {
+	text, err := normalizeComment(dto.Text)
+	if err != nil {
+		return
+	}
+
+	c, err = s.GetComment(ctx, dto.ID)
+	if err != nil {
+		return
+	}
+	if c.UserID != dto.UserID {
+		return c, apperror.ErrForbidden
+	}
+	if c.Deleted {
+		return c, apperror.ErrCommentDeleted
+	}
+
+	err = s.storage.UpdateCommentText(ctx, dto.ID, text, time.Now().UTC())
+	if err != nil {
+		// comment could be deleted concurrently
+		if errors.Is(err, apperror.ErrNotFound) {
+			return c, apperror.ErrCommentDeleted
+		}
+		return c, fmt.Errorf("could not update comment: %w", err)
+	}
+	return s.GetComment(ctx, dto.ID)
+}
+
+func (s service) DeleteComment(ctx context.Context, dto recipe.DeleteCommentDTO) (c recipe.Comment, err error) {
+	c, err = s.GetComment(ctx, dto.ID)
+	if err != nil {
+		return
+	}
+	if c.Deleted {
+		return c, nil
+	}
+
+	// empty user is internal client, comment author and recipe author moderate comments themselves
+	if len(dto.UserID) > 0 && c.UserID != dto.UserID {
+		var rec recipe.Recipe
+		rec, err = s.GetByID(ctx, c.RecipeID)
+		if err != nil {
+			return
+		}
+		if rec.CreatedBy != dto.UserID {
+			return c, apperror.ErrForbidden
+		}
+	}
+
+	err = s.storage.DeleteComment(ctx, dto.ID, dto.UserID, time.Now().UTC())
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return c, fmt.Errorf("could not delete comment: %w", err)
+	}
+	return s.GetComment(ctx, dto.ID)
+}
+
+func (s service) GetComment(ctx context.Context, id string) (c recipe.Comment, err error) {
+	c, err = s.storage.GetComment(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return
+		}
+		return c, fmt.Errorf("could not get comment: %w", err)
+	}
+	return
+}
+
+func (s service) GetComments(ctx context.Context, recipeID string, parentID string, start int64, limit int64) (cs []recipe.Comment, err error) {
+	if start < 0 {
+		start = 0
+	}
+	if limit <= 0 {
+		limit = defaultCommentsLimit
+	}
+	if limit > maxCommentsLimit {
+		limit = maxCommentsLimit
+	}
+	cs, err = s.storage.GetComments(ctx, recipeID, parentID, start, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not get comments: %w", err)
+	}
+	return
+}
+
+func (s service) GetCommentsByUser(ctx context.Context, userID string) (cs []recipe.Comment, err error) {
+	cs, err = s.storage.GetCommentsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get comments of user: %w", err)
+	}
+	return
+}
+
+func (s service) AnonymizeCommentsByUser(ctx context.Context, userID string) (int, error) {
+	n, err := s.storage.AnonymizeCommentsByUser(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("could not anonymize comments of user: %w", err)
+	}
+	return int(n), nil
+}
+
+func normalizeComment(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 || utf8.RuneCountInString(text) > maxCommentLength {
+		return "", apperror.ErrInvalidComment
+	}
+	return text, nil
+}
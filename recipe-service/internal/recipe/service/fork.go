@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+)
+
+func (s service) Fork(ctx context.Context, dto recipe.ForkRecipeDTO) (string, error) {
+	if len(dto.UserID) == 0 {
+		return "", apperror.ErrForbidden
+	}
+	original, err := s.visibleRecipe(ctx, dto.RecipeID, dto.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	// nutrition facts are recalculated for fork, as it is going to be changed
+	r := recipe.Recipe{
+		Name:        original.Name,
+		CreatedBy:   dto.UserID,
+		Ingredients: original.Ingredients,
+		Steps:       original.Steps,
+		Servings:    original.Servings,
+		Categories:  original.Categories,
+		Tags:        original.Tags,
+		Version:     1,
+		Draft:       true,
+		ForkOf: &recipe.ForkOf{
+			RecipeID: original.ID,
+			AuthorID: original.CreatedBy,
+			Name:     original.Name,
+			Version:  original.Version,
+		},
+	}
+	id, err := s.storage.Create(ctx, r)
+	if err != nil {
+		return "", fmt.Errorf("could not create fork: %w", err)
+	}
+	_, err = s.saveRevision(ctx, recipe.Recipe{ID: id}, dto.UserID, 0)
+	if err != nil {
+		log.Error().Err(err).Msgf("could not record revision of recipe %s", id)
+	}
+	return id, nil
+}
+
+// visibleRecipe returns recipe unless it is draft of another user, drafts are not visible to empty viewerID
+func (s service) visibleRecipe(ctx context.Context, id string, viewerID string) (r recipe.Recipe, err error) {
+	r, err = s.GetByID(ctx, id)
+	if err != nil {
+		return r, err
+	}
+	if r.Draft && (len(viewerID) == 0 || r.CreatedBy != viewerID) {
+		return recipe.Recipe{}, apperror.ErrNotFound
+	}
+	return
+}
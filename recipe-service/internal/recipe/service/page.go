@@ -0,0 +1,87 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+)
+
+// sortOrder returns sort order of listing, relevance is default for text query and newest for other listings
+func sortOrder(sort string, filter recipe.Filter) (string, error) {
+	switch sort {
+	case "":
+		if len(filter.Query) > 0 {
+			return recipe.SortRelevance, nil
+		}
+		return recipe.SortNewest, nil
+	case recipe.SortRelevance:
+		if len(filter.Query) == 0 {
+			return "", apperror.ErrInvalidSort
+		}
+		return sort, nil
+	case recipe.SortNewest, recipe.SortName, recipe.SortRating, recipe.SortCalories:
+		return sort, nil
+	}
+	return "", apperror.ErrInvalidSort
+}
+
+// encodeCursor makes opaque token of cursor, clients pass it back as is
+func encodeCursor(c recipe.Cursor) string {
+	bs, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bs)
+}
+
+// decodeCursor parses token of listing in sort order, empty token is the beginning of listing
+func decodeCursor(token string, sort string) (*recipe.Cursor, error) {
+	if len(token) == 0 {
+		return nil, nil
+	}
+	bs, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, apperror.ErrInvalidCursor
+	}
+	var c recipe.Cursor
+	err = json.Unmarshal(bs, &c)
+	if err != nil || c.Sort != sort {
+		return nil, apperror.ErrInvalidCursor
+	}
+	switch sort {
+	case recipe.SortName:
+		_, ok := c.Value.(string)
+		if !ok {
+			return nil, apperror.ErrInvalidCursor
+		}
+	case recipe.SortRating, recipe.SortCalories, recipe.SortRelevance:
+		_, ok := c.Value.(float64)
+		if !ok {
+			return nil, apperror.ErrInvalidCursor
+		}
+	}
+	if _, err = hex.DecodeString(c.ID); err != nil || len(c.ID) != 24 {
+		return nil, apperror.ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// nextCursor returns token of position after last recipe of page
+func nextCursor(sort string, page []recipe.Recipe) string {
+	last := page[len(page)-1]
+	c := recipe.Cursor{
+		Sort: sort,
+		ID:   last.ID,
+	}
+	switch sort {
+	case recipe.SortRelevance:
+		c.Value = last.TextScore
+	case recipe.SortName:
+		c.Value = last.Name
+	case recipe.SortRating:
+		c.Value = last.RatingAverage
+	case recipe.SortCalories:
+		c.Value = last.NutritionFacts.Calories
+	}
+	return encodeCursor(c)
+}
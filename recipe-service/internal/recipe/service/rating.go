@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+)
+
+// maxReviewLength is maximum length of review in characters
+const maxReviewLength = 2000
+
+func (s service) GetTopRated(ctx context.Context, limit int64) (rs []recipe.Recipe, err error) {
+	rs, err = s.storage.GetTopRated(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not get top rated recipes: %w", err)
+	}
+	return
+}
+
+func (s service) Rate(ctx context.Context, dto recipe.RateRecipeDTO) (r recipe.Rating, err error) {
+	if dto.Score < recipe.MinScore || dto.Score > recipe.MaxScore || utf8.RuneCountInString(dto.Review) > maxReviewLength {
+		return r, apperror.ErrInvalidRating
+	}
+
+	rec, err := s.visibleRecipe(ctx, dto.RecipeID, dto.UserID)
+	if err != nil {
+		return
+	}
+	if rec.CreatedBy == dto.UserID {
+		return r, apperror.ErrOwnRecipe
+	}
+
+	now := time.Now().UTC()
+	r, err = s.storage.SaveRating(ctx, recipe.Rating{
+		RecipeID:  dto.RecipeID,
+		UserID:    dto.UserID,
+		Score:     dto.Score,
+		Review:    dto.Review,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return r, fmt.Errorf("could not save rating: %w", err)
+	}
+	return
+}
+
+func (s service) GetRating(ctx context.Context, recipeID string, userID string) (r recipe.Rating, err error) {
+	r, err = s.storage.GetRating(ctx, recipeID, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return
+		}
+		return r, fmt.Errorf("could not get rating: %w", err)
+	}
+	return
+}
+
+func (s service) GetRatings(ctx context.Context, recipeID string) (rs []recipe.Rating, err error) {
+	rs, err = s.storage.GetRatings(ctx, recipeID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get ratings of recipe: %w", err)
+	}
+	return
+}
+
+func (s service) GetRatingsByUser(ctx context.Context, userID string) (rs []recipe.Rating, err error) {
+	rs, err = s.storage.GetRatingsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get ratings of user: %w", err)
+	}
+	return
+}
+
+func (s service) DeleteRatingsByUser(ctx context.Context, userID string) (int, error) {
+	rs, err := s.GetRatingsByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	err = s.storage.DeleteRatingsByUser(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete ratings of user: %w", err)
+	}
+	return len(rs), nil
+}
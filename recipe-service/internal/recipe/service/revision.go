@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+)
+
+// revisionAttempts is number of attempts to number revision when revisions of recipe are saved concurrently
+const revisionAttempts = 3
+
+// revisionFields are fields of recipe edited by users, revisions record changes of them
+var revisionFields = []struct {
+	name  string
+	value func(r recipe.Recipe) interface{}
+}{
+	{"name", func(r recipe.Recipe) interface{} { return r.Name }},
+	{"ingredients", func(r recipe.Recipe) interface{} { return r.Ingredients }},
+	{"steps", func(r recipe.Recipe) interface{} { return r.Steps }},
+	{"servings", func(r recipe.Recipe) interface{} { return r.Servings }},
+	{"nutrition_facts", func(r recipe.Recipe) interface{} { return r.NutritionFacts }},
+	{"categories", func(r recipe.Recipe) interface{} { return r.Categories }},
+	{"tags", func(r recipe.Recipe) interface{} { return r.Tags }},
+	{"draft", func(r recipe.Recipe) interface{} { return r.Draft }},
+}
+
+func (s service) GetRevisions(ctx context.Context, recipeID string) ([]recipe.Revision, error) {
+	revisions, err := s.storage.GetRevisions(ctx, recipeID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+func (s service) DiffRevisions(ctx context.Context, recipeID string, from int64, to int64) ([]recipe.FieldChange, error) {
+	fromRevision, err := s.getRevision(ctx, recipeID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := s.getRevision(ctx, recipeID, to)
+	if err != nil {
+		return nil, err
+	}
+	return diff(fromRevision.Recipe, toRevision.Recipe), nil
+}
+
+func (s service) Revert(ctx context.Context, dto recipe.RevertRecipeDTO) (r recipe.Recipe, err error) {
+	old, err := s.ownRecipe(ctx, dto.RecipeID, dto.UserID)
+	if err != nil {
+		return r, err
+	}
+	revision, err := s.getRevision(ctx, dto.RecipeID, dto.Revision)
+	if err != nil {
+		return r, err
+	}
+
+	content := revision.Recipe
+	// categories could be deleted since revision was made
+	var categories []string
+	for _, id := range content.Categories {
+		_, err = s.storage.GetCategory(ctx, id)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				continue
+			}
+			return r, fmt.Errorf("could not check category: %w", err)
+		}
+		categories = append(categories, id)
+	}
+	content.Categories = categories
+
+	// recipe changed after it was read is not reverted, as revision would not record the change properly
+	err = s.storage.RestoreContent(ctx, dto.RecipeID, old.Version, content)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) || errors.Is(err, apperror.ErrConflict) {
+			return r, err
+		}
+		return r, fmt.Errorf("could not revert recipe: %w", err)
+	}
+	return s.saveRevision(ctx, old, dto.UserID, revision.Number)
+}
+
+// ownRecipe returns recipe which userID may change, empty userID is a service which may change any recipe
+func (s service) ownRecipe(ctx context.Context, id string, userID string) (r recipe.Recipe, err error) {
+	r, err = s.GetByID(ctx, id)
+	if err != nil {
+		return r, err
+	}
+	if len(userID) > 0 && r.CreatedBy != userID {
+		return r, apperror.ErrForbidden
+	}
+	return
+}
+
+func (s service) getRevision(ctx context.Context, recipeID string, number int64) (r recipe.Revision, err error) {
+	r, err = s.storage.GetRevision(ctx, recipeID, number)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return r, err
+		}
+		return r, fmt.Errorf("could not get revision: %w", err)
+	}
+	return
+}
+
+// saveRevision records changes of recipe made since old state, returning current recipe. Nothing is recorded
+// if recipe has not changed. Changes are applied already when revision is saved, so failure to save it is only
+// logged and error is returned only if current recipe could not be read
+func (s service) saveRevision(ctx context.Context, old recipe.Recipe, author string, revertedFrom int64) (r recipe.Recipe, err error) {
+	r, err = s.GetByID(ctx, old.ID)
+	if err != nil {
+		return r, err
+	}
+	var changed []string
+	for _, change := range diff(old, r) {
+		changed = append(changed, change.Field)
+	}
+	if len(changed) == 0 {
+		return r, nil
+	}
+
+	revision := recipe.Revision{
+		RecipeID:     r.ID,
+		Author:       author,
+		CreatedAt:    time.Now().UTC(),
+		Changed:      changed,
+		RevertedFrom: revertedFrom,
+		Recipe:       r,
+	}
+	for i := 0; i < revisionAttempts; i++ {
+		_, err = s.storage.CreateRevision(ctx, revision)
+		if !errors.Is(err, apperror.ErrDuplicate) {
+			break
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("could not save revision of recipe %s", r.ID)
+	}
+	return r, nil
+}
+
+// diff returns fields of recipe which differ between from and to
+func diff(from recipe.Recipe, to recipe.Recipe) (changes []recipe.FieldChange) {
+	for _, field := range revisionFields {
+		a, b := emptyToNil(field.value(from)), emptyToNil(field.value(to))
+		if !reflect.DeepEqual(a, b) {
+			changes = append(changes, recipe.FieldChange{
+				Field: field.name,
+				From:  a,
+				To:    b,
+			})
+		}
+	}
+	return
+}
+
+// emptyToNil makes empty strings, slices and nil pointers equal, as they are all missing in storage
+func emptyToNil(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
+)
+
+// maxServings is maximum number of servings of recipe
+const maxServings = 1000
+
+// unit is unit of measure of size in the smallest unit of its scale
+type unit struct {
+	name string
+	size float64
+}
+
+// unitScale is units convertible to each other from the smallest to the largest. Amounts in spoons and cups are
+// converted to larger unit only when they are a multiple of quarter of it, as 1.67 tbsp is not handy to measure
+type unitScale struct {
+	units   []unit
+	quarter bool
+}
+
+var unitScales = []unitScale{
+	{units: []unit{{"г", 1}, {"кг", 1000}}},
+	{units: []unit{{"мл", 1}, {"л", 1000}}},
+	{units: []unit{{"ч. л.", 1}, {"ст. л.", 3}, {"стакан", 48}}, quarter: true},
+	{units: []unit{{"g", 1}, {"kg", 1000}}},
+	{units: []unit{{"ml", 1}, {"l", 1000}}},
+	{units: []unit{{"tsp", 1}, {"tbsp", 3}, {"cup", 48}}, quarter: true},
+}
+
+func (s service) Scale(ctx context.Context, dto recipe.ScaleRecipeDTO) (r recipe.Recipe, err error) {
+	r, err = s.visibleRecipe(ctx, dto.RecipeID, dto.UserID)
+	if err != nil {
+		return r, err
+	}
+	if r.Servings <= 0 || dto.Servings <= 0 || dto.Servings > maxServings {
+		return recipe.Recipe{}, apperror.ErrInvalidServings
+	}
+
+	factor := float64(dto.Servings) / float64(r.Servings)
+	ingredients := make([]recipe.RecipeIngredient, 0, len(r.Ingredients))
+	for _, ing := range r.Ingredients {
+		ing.Amount, ing.Unit = normalizeAmount(ing.Amount*factor, ing.Unit)
+		ingredients = append(ingredients, ing)
+	}
+	r.Ingredients = ingredients
+	r.Servings = dto.Servings
+	if r.NutritionFacts != nil {
+		r.NutritionFacts = &recipe.NutritionFacts{
+			Calories:      round(r.NutritionFacts.Calories * factor),
+			Proteins:      round(r.NutritionFacts.Proteins * factor),
+			Fats:          round(r.NutritionFacts.Fats * factor),
+			Carbohydrates: round(r.NutritionFacts.Carbohydrates * factor),
+		}
+	}
+	if !dto.Save {
+		return r, nil
+	}
+
+	if len(dto.UserID) == 0 {
+		return recipe.Recipe{}, apperror.ErrForbidden
+	}
+	// scaled nutrition facts are kept, as nutrition-facts-service does not convert units yet
+	scaled := recipe.Recipe{
+		Name:            r.Name,
+		CreatedBy:       dto.UserID,
+		Ingredients:     r.Ingredients,
+		Steps:           r.Steps,
+		NutritionFacts:  r.NutritionFacts,
+		Servings:        r.Servings,
+		Categories:      r.Categories,
+		Tags:            r.Tags,
+		IngredientNames: r.IngredientNames,
+		Version:         1,
+		Draft:           true,
+		ForkOf: &recipe.ForkOf{
+			RecipeID: r.ID,
+			AuthorID: r.CreatedBy,
+			Name:     r.Name,
+			Version:  r.Version,
+		},
+	}
+	id, err := s.storage.Create(ctx, scaled)
+	if err != nil {
+		return recipe.Recipe{}, fmt.Errorf("could not save scaled recipe: %w", err)
+	}
+	return s.saveRevision(ctx, recipe.Recipe{ID: id}, dto.UserID, 0)
+}
+
+// normalizeAmount converts amount to the largest unit of its scale in which it is at least 1, amounts in unknown units
+// are kept as is
+func normalizeAmount(amount float64, unitName string) (float64, string) {
+	name := strings.ToLower(strings.TrimSpace(unitName))
+	for _, scale := range unitScales {
+		for _, u := range scale.units {
+			if u.name != name {
+				continue
+			}
+			size := amount * u.size
+			for i := len(scale.units) - 1; i > 0; i-- {
+				v := size / scale.units[i].size
+				if v >= 1 && (!scale.quarter || isQuarter(v)) {
+					return round(v), scale.units[i].name
+				}
+			}
+			return round(size / scale.units[0].size), scale.units[0].name
+		}
+	}
+	return round(amount), unitName
+}
+
+func isQuarter(v float64) bool {
+	return math.Abs(v*4-math.Round(v*4)) < 1e-6
+}
+
+// round rounds v to 3 decimal places, dropping float errors of scaling
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
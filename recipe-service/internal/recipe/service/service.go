@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/rs/zerolog/log"
 	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/storage"
@@ -12,13 +15,74 @@ import (
 
 type Service interface {
 	Create(ctx context.Context, dto recipe.CreateRecipeDTO) (string, error)
+	// Update changes recipe on behalf of its author or a service and saves revision of it.
+	// ErrConflict is returned if recipe has been changed since dto.Version
 	Update(ctx context.Context, dto recipe.UpdateRecipeDTO) error
+	// UpdateNutritionFacts sets calculated nutrition facts of recipe, leaving its content to its author
+	UpdateNutritionFacts(ctx context.Context, dto recipe.RecipeNutritionsDTO) error
 	GetByID(ctx context.Context, id string) (recipe.Recipe, error)
+	// GetAllByUser returns all recipes of user at once, listings for clients use GetAll
 	GetAllByUser(ctx context.Context, userID string) ([]recipe.Recipe, error)
-	GetAll(ctx context.Context, start int64, limit int64) ([]recipe.Recipe, error)
+	// GetAll returns page of recipes matching filter and cursor of the next page, which is empty for last page
+	GetAll(ctx context.Context, filter recipe.Filter, page recipe.PageRequest) ([]recipe.Recipe, string, error)
 	FindByIngredients(ctx context.Context, ingredientIDs []string) ([]recipe.Recipe, error)
+	DeleteAllByUser(ctx context.Context, userID string) (int, error)
+	// AnonymizeAllByUser keeps recipes of user, replacing author with recipe.DeletedUser
+	AnonymizeAllByUser(ctx context.Context, userID string) (int, error)
+	// GetTopRated returns up to limit rated recipes, best rated first
+	GetTopRated(ctx context.Context, limit int64) ([]recipe.Recipe, error)
+	// Rate creates or replaces rating of user for recipe and updates rating summary of recipe
+	Rate(ctx context.Context, dto recipe.RateRecipeDTO) (recipe.Rating, error)
+	GetRating(ctx context.Context, recipeID string, userID string) (recipe.Rating, error)
+	GetRatings(ctx context.Context, recipeID string) ([]recipe.Rating, error)
+	GetRatingsByUser(ctx context.Context, userID string) ([]recipe.Rating, error)
+	// DeleteRatingsByUser deletes ratings of user and updates rating summaries of rated recipes
+	DeleteRatingsByUser(ctx context.Context, userID string) (int, error)
+	// AddComment adds comment to recipe, returned event is meant for notification consumers
+	AddComment(ctx context.Context, dto recipe.AddCommentDTO) (recipe.Comment, recipe.CommentCreatedDTO, error)
+	EditComment(ctx context.Context, dto recipe.EditCommentDTO) (recipe.Comment, error)
+	// DeleteComment marks comment deleted, comment can be deleted by its author or by author of recipe
+	DeleteComment(ctx context.Context, dto recipe.DeleteCommentDTO) (recipe.Comment, error)
+	GetComment(ctx context.Context, id string) (recipe.Comment, error)
+	GetComments(ctx context.Context, recipeID string, parentID string, start int64, limit int64) ([]recipe.Comment, error)
+	GetCommentsByUser(ctx context.Context, userID string) ([]recipe.Comment, error)
+	// AnonymizeCommentsByUser deletes text of comments of user, keeping replies of other users
+	AnonymizeCommentsByUser(ctx context.Context, userID string) (int, error)
+	AddFavorite(ctx context.Context, userID string, recipeID string) (recipe.Favorite, error)
+	RemoveFavorite(ctx context.Context, userID string, recipeID string) error
+	GetFavorites(ctx context.Context, userID string) ([]recipe.Favorite, error)
+	CreateCollection(ctx context.Context, dto recipe.CreateCollectionDTO) (recipe.Collection, error)
+	// UpdateCollection changes collection of dto.UserID, recipes can be reordered or removed, but not added
+	UpdateCollection(ctx context.Context, dto recipe.UpdateCollectionDTO) (recipe.Collection, error)
+	// UpdateCollectionItem adds recipe to the end of collection of dto.UserID or removes it
+	UpdateCollectionItem(ctx context.Context, dto recipe.CollectionItemDTO) (recipe.Collection, error)
+	DeleteCollection(ctx context.Context, dto recipe.DeleteCollectionDTO) error
+	// GetCollection returns collection if it is shared or belongs to viewerID, empty viewer sees every collection
+	GetCollection(ctx context.Context, id string, viewerID string) (recipe.Collection, error)
+	// GetCollections returns collections of user visible to viewerID
+	GetCollections(ctx context.Context, userID string, viewerID string) ([]recipe.Collection, error)
+	// DeleteCollectionsByUser deletes favorites and collections of user
+	DeleteCollectionsByUser(ctx context.Context, userID string) (int, error)
+	// Fork copies recipe into drafts of dto.UserID keeping link to original recipe, returns id of fork
+	Fork(ctx context.Context, dto recipe.ForkRecipeDTO) (string, error)
+	// GetRevisions returns revisions of recipe, the latest first
+	GetRevisions(ctx context.Context, recipeID string) ([]recipe.Revision, error)
+	// DiffRevisions returns fields of recipe changed between revisions from and to
+	DiffRevisions(ctx context.Context, recipeID string, from int64, to int64) ([]recipe.FieldChange, error)
+	// Scale returns recipe scaled to dto.Servings, scaled recipe is saved as draft of dto.UserID if dto.Save is set
+	Scale(ctx context.Context, dto recipe.ScaleRecipeDTO) (recipe.Recipe, error)
+	// Revert restores content of recipe from its earlier revision, which is saved as the new revision
+	Revert(ctx context.Context, dto recipe.RevertRecipeDTO) (recipe.Recipe, error)
+	CreateCategory(ctx context.Context, dto recipe.CreateCategoryDTO) (recipe.Category, error)
+	// DeleteCategory deletes category and removes it from recipes
+	DeleteCategory(ctx context.Context, id string) error
+	// GetCategories returns categories of kind (all categories if kind is empty) with number of recipes in them
+	GetCategories(ctx context.Context, kind string) ([]recipe.CategoryDTO, error)
 }
 
+// maxQueryLength is maximum length of search query in characters
+const maxQueryLength = 200
+
 type service struct {
 	storage storage.Storage
 }
@@ -30,38 +94,107 @@ func NewService(storage storage.Storage) Service {
 }
 
 func (s service) Create(ctx context.Context, dto recipe.CreateRecipeDTO) (string, error) {
+	categories, err := s.normalizeCategories(ctx, dto.Categories)
+	if err != nil {
+		return "", err
+	}
+	tags, err := normalizeTags(dto.Tags)
+	if err != nil {
+		return "", err
+	}
+	if dto.Servings < 0 || dto.Servings > maxServings {
+		return "", apperror.ErrInvalidServings
+	}
+
 	r := recipe.Recipe{
 		Name:        dto.Name,
 		CreatedBy:   dto.CreatedBy,
 		Ingredients: dto.Ingredients,
 		Steps:       dto.Steps,
+		Servings:    dto.Servings,
+		Categories:  categories,
+		Tags:        tags,
+		Version:     1,
 	}
 
 	id, err := s.storage.Create(ctx, r)
 	if err != nil {
 		return "", fmt.Errorf("could not create recipe: %w", err)
 	}
+	_, err = s.saveRevision(ctx, recipe.Recipe{ID: id}, dto.CreatedBy, 0)
+	if err != nil {
+		log.Error().Err(err).Msgf("could not record revision of recipe %s", id)
+	}
 	return id, nil
 }
 
 func (s service) Update(ctx context.Context, dto recipe.UpdateRecipeDTO) error {
+	old, err := s.ownRecipe(ctx, dto.ID, dto.UserID)
+	if err != nil {
+		return err
+	}
+	if old.Version != dto.Version {
+		return apperror.ErrConflict
+	}
+	categories, err := s.normalizeCategories(ctx, dto.Categories)
+	if err != nil {
+		return err
+	}
+	tags, err := normalizeTags(dto.Tags)
+	if err != nil {
+		return err
+	}
+	if dto.Servings < 0 || dto.Servings > maxServings {
+		return apperror.ErrInvalidServings
+	}
+
 	r := recipe.Recipe{
-		ID:             dto.ID,
-		Name:           dto.Name,
-		Ingredients:    dto.Ingredients,
-		Steps:          dto.Steps,
-		NutritionFacts: dto.NutritionFacts,
+		ID:          dto.ID,
+		Name:        dto.Name,
+		Ingredients: dto.Ingredients,
+		Steps:       dto.Steps,
+		Servings:    dto.Servings,
+		Categories:  categories,
+		Tags:        tags,
+		Version:     dto.Version,
 	}
-	err := s.storage.Update(ctx, r)
+	err = s.storage.Update(ctx, r)
 	if err != nil {
-		if errors.Is(err, apperror.ErrNotFound) {
+		if errors.Is(err, apperror.ErrNotFound) || errors.Is(err, apperror.ErrConflict) {
 			return err
 		}
 		return fmt.Errorf("could not update recipe: %w", err)
 	}
+	if dto.Publish && old.Draft {
+		err = s.storage.Publish(ctx, dto.ID)
+		if err != nil {
+			return fmt.Errorf("could not publish recipe: %w", err)
+		}
+	}
+	// update is applied already, so it must not fail and make retries conflict
+	_, err = s.saveRevision(ctx, old, dto.UserID, 0)
+	if err != nil {
+		log.Error().Err(err).Msgf("could not record revision of recipe %s", dto.ID)
+	}
 	return nil
 }
 
+func (s service) UpdateNutritionFacts(ctx context.Context, dto recipe.RecipeNutritionsDTO) error {
+	old, err := s.GetByID(ctx, dto.RecipeID)
+	if err != nil {
+		return err
+	}
+	err = s.storage.UpdateNutritionFacts(ctx, dto.RecipeID, dto.NutritionFacts, dto.IngredientNames)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("could not update nutrition facts: %w", err)
+	}
+	_, err = s.saveRevision(ctx, old, "", 0)
+	return err
+}
+
 func (s service) GetByID(ctx context.Context, id string) (r recipe.Recipe, err error) {
 	r, err = s.storage.GetByID(ctx, id)
 	if err != nil {
@@ -78,11 +211,116 @@ func (s service) GetAllByUser(ctx context.Context, userID string) (rs []recipe.R
 	return
 }
 
-func (s service) GetAll(ctx context.Context, start int64, limit int64) (rs []recipe.Recipe, err error) {
-	rs, err = s.storage.GetAll(ctx, start, limit)
+func (s service) GetAll(ctx context.Context, filter recipe.Filter, page recipe.PageRequest) (rs []recipe.Recipe, next string, err error) {
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultRecipesLimit
+	}
+	if limit > maxRecipesLimit {
+		limit = maxRecipesLimit
+	}
+	// tags are stored normalized
+	filter.Tags, err = normalizeTags(filter.Tags)
+	if err != nil {
+		return nil, "", err
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+	if utf8.RuneCountInString(filter.Query) > maxQueryLength {
+		return nil, "", apperror.ErrInvalidQuery
+	}
+	sort, err := sortOrder(page.Sort, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	after, err := decodeCursor(page.Cursor, sort)
+	if err != nil {
+		return nil, "", err
+	}
+	// one more recipe tells whether there is next page
+	rs, err = s.storage.GetAll(ctx, filter, sort, after, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not get recipes: %w", err)
+	}
+	if int64(len(rs)) > limit {
+		rs = rs[:limit]
+		next = nextCursor(sort, rs)
+	}
 	return
 }
 
+func (s service) DeleteAllByUser(ctx context.Context, userID string) (int, error) {
+	rs, err := s.storage.GetAllByUser(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("could not get recipes of user: %w", err)
+	}
+	deleted := 0
+	for _, r := range rs {
+		// ratings, comments, saves and revisions are deleted first, so that failed attempt can be repeated
+		err = s.storage.DeleteRatingsByRecipe(ctx, r.ID)
+		if err != nil {
+			return deleted, fmt.Errorf("could not delete ratings of recipe %s: %w", r.ID, err)
+		}
+		err = s.storage.DeleteCommentsByRecipe(ctx, r.ID)
+		if err != nil {
+			return deleted, fmt.Errorf("could not delete comments of recipe %s: %w", r.ID, err)
+		}
+		err = s.storage.RemoveFromAllCollections(ctx, r.ID)
+		if err != nil {
+			return deleted, fmt.Errorf("could not remove recipe %s from collections: %w", r.ID, err)
+		}
+		err = s.storage.DeleteRevisionsByRecipe(ctx, r.ID)
+		if err != nil {
+			return deleted, fmt.Errorf("could not delete revisions of recipe %s: %w", r.ID, err)
+		}
+		err = s.storage.Delete(ctx, r.ID)
+		if err != nil {
+			// recipe could be deleted by previous attempt
+			if errors.Is(err, apperror.ErrNotFound) {
+				continue
+			}
+			return deleted, fmt.Errorf("could not delete recipe %s: %w", r.ID, err)
+		}
+		deleted++
+	}
+	// forks of deleted recipes keep link to them, but not to their author
+	err = s.storage.AnonymizeForksOf(ctx, userID)
+	if err != nil {
+		return deleted, fmt.Errorf("could not anonymize forks: %w", err)
+	}
+	err = s.storage.AnonymizeRevisionsByUser(ctx, userID)
+	if err != nil {
+		return deleted, fmt.Errorf("could not anonymize revisions: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s service) AnonymizeAllByUser(ctx context.Context, userID string) (int, error) {
+	rs, err := s.storage.GetAllByUser(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("could not get recipes of user: %w", err)
+	}
+	anonymized := 0
+	for _, r := range rs {
+		err = s.storage.SetAuthor(ctx, r.ID, recipe.DeletedUser)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				continue
+			}
+			return anonymized, fmt.Errorf("could not anonymize recipe %s: %w", r.ID, err)
+		}
+		anonymized++
+	}
+	err = s.storage.AnonymizeForksOf(ctx, userID)
+	if err != nil {
+		return anonymized, fmt.Errorf("could not anonymize forks: %w", err)
+	}
+	err = s.storage.AnonymizeRevisionsByUser(ctx, userID)
+	if err != nil {
+		return anonymized, fmt.Errorf("could not anonymize revisions: %w", err)
+	}
+	return anonymized, nil
+}
+
 func (s service) FindByIngredients(ctx context.Context, ingredientIDs []string) ([]recipe.Recipe, error) {
 	// TODO implement me
 	panic("implement me")
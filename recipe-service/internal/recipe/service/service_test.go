@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/storage/mongodb"
 )
@@ -56,6 +57,7 @@ func TestService(t *testing.T) {
 			CreatedBy:   "639673eb2c5bcae361a8ad4a",
 			Ingredients: nil,
 			Steps:       nil,
+			Tags:        []string{"суп"},
 		}
 
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -68,14 +70,549 @@ func TestService(t *testing.T) {
 		got, err := serv.GetByID(ctx, id)
 		require.NoError(t, err)
 		assert.Equal(t, dto.Name, got.Name)
+		assert.Equal(t, []string{"суп"}, got.Tags)
 
+		// tags missing in update are cleared
 		updateDTO := recipe.UpdateRecipeDTO{
 			ID:          got.ID,
 			Name:        "Рецепт 3 (ред.)",
 			Ingredients: got.Ingredients,
 			Steps:       got.Steps,
+			Version:     got.Version,
 		}
 		err = serv.Update(ctx, updateDTO)
 		require.NoError(t, err)
+
+		// the same change based on outdated version is rejected
+		err = serv.Update(ctx, updateDTO)
+		assert.ErrorIs(t, err, apperror.ErrConflict)
+		got, err = serv.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, updateDTO.Version+1, got.Version)
+		assert.Equal(t, updateDTO.Name, got.Name)
+		assert.Empty(t, got.Tags)
+	})
+	t.Run("delete recipes of user", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		userID := "639673eb2c5bcae361a8ad4b"
+		for _, name := range []string{"Рецепт 4", "Рецепт 5"} {
+			_, err := serv.Create(ctx, recipe.CreateRecipeDTO{Name: name, CreatedBy: userID})
+			require.NoError(t, err)
+		}
+
+		deleted, err := serv.DeleteAllByUser(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, deleted)
+
+		rs, err := serv.GetAllByUser(ctx, userID)
+		require.NoError(t, err)
+		assert.Empty(t, rs)
+	})
+	t.Run("anonymize recipes of user", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		userID := "639673eb2c5bcae361a8ad4c"
+		id, err := serv.Create(ctx, recipe.CreateRecipeDTO{Name: "Рецепт 6", CreatedBy: userID})
+		require.NoError(t, err)
+
+		anonymized, err := serv.AnonymizeAllByUser(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, anonymized)
+
+		r, err := serv.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, recipe.DeletedUser, r.CreatedBy)
+		assert.Equal(t, "Рецепт 6", r.Name)
+	})
+	t.Run("rate recipe", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		authorID := "639673eb2c5bcae361a8ad4d"
+		id, err := serv.Create(ctx, recipe.CreateRecipeDTO{Name: "Рецепт 7", CreatedBy: authorID})
+		require.NoError(t, err)
+
+		_, err = serv.Rate(ctx, recipe.RateRecipeDTO{RecipeID: id, UserID: authorID, Score: 5})
+		assert.ErrorIs(t, err, apperror.ErrOwnRecipe)
+		_, err = serv.Rate(ctx, recipe.RateRecipeDTO{RecipeID: id, UserID: "639673eb2c5bcae361a8ad4e", Score: 6})
+		assert.ErrorIs(t, err, apperror.ErrInvalidRating)
+
+		rating, err := serv.Rate(ctx, recipe.RateRecipeDTO{RecipeID: id, UserID: "639673eb2c5bcae361a8ad4e", Score: 2})
+		require.NoError(t, err)
+		assert.NotEmpty(t, rating.ID)
+		_, err = serv.Rate(ctx, recipe.RateRecipeDTO{RecipeID: id, UserID: "639673eb2c5bcae361a8ad4f", Score: 4, Review: "Вкусно"})
+		require.NoError(t, err)
+
+		// second rating of the same user replaces the first one
+		updated, err := serv.Rate(ctx, recipe.RateRecipeDTO{RecipeID: id, UserID: "639673eb2c5bcae361a8ad4e", Score: 5})
+		require.NoError(t, err)
+		assert.Equal(t, rating.ID, updated.ID)
+		assert.Equal(t, 5, updated.Score)
+
+		ratings, err := serv.GetRatings(ctx, id)
+		require.NoError(t, err)
+		assert.Len(t, ratings, 2)
+
+		r, err := serv.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), r.RatingCount)
+		assert.InDelta(t, 4.5, r.RatingAverage, 0.001)
+
+		top, err := serv.GetTopRated(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, top, 1)
+		assert.Equal(t, id, top[0].ID)
+
+		deleted, err := serv.DeleteRatingsByUser(ctx, "639673eb2c5bcae361a8ad4e")
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+
+		r, err = serv.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), r.RatingCount)
+		assert.InDelta(t, 4, r.RatingAverage, 0.001)
+	})
+	t.Run("comments", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		authorID := "639673eb2c5bcae361a8ad50"
+		userID := "639673eb2c5bcae361a8ad51"
+		otherID := "639673eb2c5bcae361a8ad52"
+		id, err := serv.Create(ctx, recipe.CreateRecipeDTO{Name: "Рецепт 8", CreatedBy: authorID})
+		require.NoError(t, err)
+
+		_, _, err = serv.AddComment(ctx, recipe.AddCommentDTO{RecipeID: id, UserID: userID, Text: "  "})
+		assert.ErrorIs(t, err, apperror.ErrInvalidComment)
+
+		question, event, err := serv.AddComment(ctx, recipe.AddCommentDTO{RecipeID: id, UserID: userID, Text: "Сколько варить?"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, question.ID)
+		assert.Equal(t, authorID, event.RecipeAuthorID)
+
+		answer, event, err := serv.AddComment(ctx, recipe.AddCommentDTO{RecipeID: id, ParentID: question.ID, UserID: authorID, Text: "10 минут"})
+		require.NoError(t, err)
+		assert.Equal(t, userID, event.ParentAuthorID)
+
+		cs, err := serv.GetComments(ctx, id, "", 0, 0)
+		require.NoError(t, err)
+		require.Len(t, cs, 1)
+		assert.Equal(t, int64(1), cs[0].ReplyCount)
+
+		replies, err := serv.GetComments(ctx, id, question.ID, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, replies, 1)
+		assert.Equal(t, answer.ID, replies[0].ID)
+
+		_, err = serv.EditComment(ctx, recipe.EditCommentDTO{ID: question.ID, UserID: otherID, Text: "Спам"})
+		assert.ErrorIs(t, err, apperror.ErrForbidden)
+		edited, err := serv.EditComment(ctx, recipe.EditCommentDTO{ID: question.ID, UserID: userID, Text: "Сколько варить макароны?"})
+		require.NoError(t, err)
+		assert.Equal(t, "Сколько варить макароны?", edited.Text)
+
+		_, err = serv.DeleteComment(ctx, recipe.DeleteCommentDTO{ID: question.ID, UserID: otherID})
+		assert.ErrorIs(t, err, apperror.ErrForbidden)
+		// recipe author moderates comments on own recipe
+		deleted, err := serv.DeleteComment(ctx, recipe.DeleteCommentDTO{ID: question.ID, UserID: authorID})
+		require.NoError(t, err)
+		assert.True(t, deleted.Deleted)
+		assert.Empty(t, deleted.Text)
+
+		_, _, err = serv.AddComment(ctx, recipe.AddCommentDTO{RecipeID: id, ParentID: question.ID, UserID: otherID, Text: "Ответ"})
+		assert.ErrorIs(t, err, apperror.ErrCommentDeleted)
+
+		n, err := serv.AnonymizeCommentsByUser(ctx, authorID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		cs, err = serv.GetCommentsByUser(ctx, authorID)
+		require.NoError(t, err)
+		assert.Empty(t, cs)
+	})
+	t.Run("favorites and collections", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		authorID := "639673eb2c5bcae361a8ad53"
+		userID := "639673eb2c5bcae361a8ad54"
+		otherID := "639673eb2c5bcae361a8ad55"
+		first, err := serv.Create(ctx, recipe.CreateRecipeDTO{Name: "Рецепт 9", CreatedBy: authorID})
+		require.NoError(t, err)
+		second, err := serv.Create(ctx, recipe.CreateRecipeDTO{Name: "Рецепт 10", CreatedBy: authorID})
+		require.NoError(t, err)
+
+		_, err = serv.AddFavorite(ctx, userID, first)
+		require.NoError(t, err)
+		// saving twice has no effect
+		_, err = serv.AddFavorite(ctx, userID, first)
+		require.NoError(t, err)
+		fs, err := serv.GetFavorites(ctx, userID)
+		require.NoError(t, err)
+		assert.Len(t, fs, 1)
+
+		_, err = serv.CreateCollection(ctx, recipe.CreateCollectionDTO{UserID: userID, Name: " "})
+		assert.ErrorIs(t, err, apperror.ErrInvalidCollection)
+		c, err := serv.CreateCollection(ctx, recipe.CreateCollectionDTO{
+			UserID:    userID,
+			Name:      "Ужины на неделе",
+			RecipeIDs: []string{first},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, recipe.VisibilityPrivate, c.Visibility)
+
+		c, err = serv.UpdateCollectionItem(ctx, recipe.CollectionItemDTO{CollectionID: c.ID, UserID: userID, RecipeID: second})
+		require.NoError(t, err)
+		assert.Equal(t, []string{first, second}, c.RecipeIDs)
+
+		r, err := serv.GetByID(ctx, first)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), r.SavedCount)
+
+		// private collection is hidden from other users
+		_, err = serv.GetCollection(ctx, c.ID, otherID)
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+		_, err = serv.GetCollection(ctx, c.ID, "")
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+		_, err = serv.UpdateCollection(ctx, recipe.UpdateCollectionDTO{ID: c.ID, UserID: otherID, Name: "Мои"})
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+		c, err = serv.UpdateCollection(ctx, recipe.UpdateCollectionDTO{
+			ID:         c.ID,
+			UserID:     userID,
+			Name:       "Ужины",
+			Visibility: recipe.VisibilityShared,
+			RecipeIDs:  []string{second, first},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{second, first}, c.RecipeIDs)
+		_, err = serv.UpdateCollection(ctx, recipe.UpdateCollectionDTO{
+			ID:        c.ID,
+			UserID:    userID,
+			Name:      "Ужины",
+			RecipeIDs: []string{second, first, "639673eb2c5bcae361a8ad56"},
+		})
+		assert.ErrorIs(t, err, apperror.ErrInvalidCollection)
+
+		cs, err := serv.GetCollections(ctx, userID, otherID)
+		require.NoError(t, err)
+		assert.Len(t, cs, 1)
+
+		// deleted recipe is removed from every collection
+		_, err = serv.DeleteAllByUser(ctx, authorID)
+		require.NoError(t, err)
+		c, err = serv.GetCollection(ctx, c.ID, userID)
+		require.NoError(t, err)
+		assert.Empty(t, c.RecipeIDs)
+		fs, err = serv.GetFavorites(ctx, userID)
+		require.NoError(t, err)
+		assert.Empty(t, fs)
+
+		err = serv.DeleteCollection(ctx, recipe.DeleteCollectionDTO{ID: c.ID, UserID: userID})
+		require.NoError(t, err)
+		_, err = serv.GetCollection(ctx, c.ID, userID)
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+	})
+	t.Run("categories and tags", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, err := serv.CreateCategory(ctx, recipe.CreateCategoryDTO{Kind: "country", Name: "italian", Title: "Итальянская"})
+		assert.ErrorIs(t, err, apperror.ErrInvalidCategory)
+		italian, err := serv.CreateCategory(ctx, recipe.CreateCategoryDTO{Kind: recipe.CategoryCuisine, Name: "Italian", Title: "Итальянская"})
+		require.NoError(t, err)
+		assert.Equal(t, "italian", italian.Name)
+		_, err = serv.CreateCategory(ctx, recipe.CreateCategoryDTO{Kind: recipe.CategoryCuisine, Name: "italian", Title: "Итальянская"})
+		assert.ErrorIs(t, err, apperror.ErrDuplicate)
+		breakfast, err := serv.CreateCategory(ctx, recipe.CreateCategoryDTO{Kind: recipe.CategoryMealType, Name: "breakfast", Title: "Завтрак"})
+		require.NoError(t, err)
+
+		userID := "639673eb2c5bcae361a8ad57"
+		_, err = serv.Create(ctx, recipe.CreateRecipeDTO{Name: "Рецепт 11", CreatedBy: userID, Categories: []string{"639673eb2c5bcae361a8ad58"}})
+		assert.ErrorIs(t, err, apperror.ErrInvalidCategory)
+		frittata, err := serv.Create(ctx, recipe.CreateRecipeDTO{
+			Name:       "Фриттата",
+			CreatedBy:  userID,
+			Categories: []string{italian.ID, breakfast.ID},
+			Tags:       []string{" Яйца ", "быстро", "яйца"},
+		})
+		require.NoError(t, err)
+		_, err = serv.Create(ctx, recipe.CreateRecipeDTO{Name: "Ризотто", CreatedBy: userID, Categories: []string{italian.ID}})
+		require.NoError(t, err)
+
+		r, err := serv.GetByID(ctx, frittata)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"яйца", "быстро"}, r.Tags)
+
+		rs, _, err := serv.GetAll(ctx, recipe.Filter{Categories: []string{italian.ID}}, recipe.PageRequest{Limit: 10})
+		require.NoError(t, err)
+		assert.Len(t, rs, 2)
+		rs, _, err = serv.GetAll(ctx, recipe.Filter{Categories: []string{italian.ID}, Tags: []string{"ЯЙЦА"}}, recipe.PageRequest{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, frittata, rs[0].ID)
+
+		cs, err := serv.GetCategories(ctx, recipe.CategoryCuisine)
+		require.NoError(t, err)
+		require.Len(t, cs, 1)
+		assert.Equal(t, int64(2), cs[0].RecipeCount)
+
+		err = serv.DeleteCategory(ctx, italian.ID)
+		require.NoError(t, err)
+		r, err = serv.GetByID(ctx, frittata)
+		require.NoError(t, err)
+		assert.Equal(t, []string{breakfast.ID}, r.Categories)
+	})
+	t.Run("search recipes", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		userID := "639673eb2c5bcae361a8ad59"
+		soup, err := serv.Create(ctx, recipe.CreateRecipeDTO{
+			Name:      "Суп с курицей",
+			CreatedBy: userID,
+			Steps:     []recipe.Step{{Description: "Сварить бульон"}},
+		})
+		require.NoError(t, err)
+		calories := 350.0
+		err = serv.UpdateNutritionFacts(ctx, recipe.RecipeNutritionsDTO{
+			RecipeID:        soup,
+			NutritionFacts:  recipe.NutritionFacts{Calories: calories},
+			IngredientNames: []string{"Курица", "Морковь"},
+		})
+		require.NoError(t, err)
+		// nutrition facts are not user content and do not change version
+		r, err := serv.GetByID(ctx, soup)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), r.Version)
+		salad, err := serv.Create(ctx, recipe.CreateRecipeDTO{
+			Name:      "Салат",
+			CreatedBy: "639673eb2c5bcae361a8ad5a",
+			Steps:     []recipe.Step{{Description: "Нарезать морковь"}},
+		})
+		require.NoError(t, err)
+
+		rs, _, err := serv.GetAll(ctx, recipe.Filter{Query: "морковь"}, recipe.PageRequest{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, rs, 2)
+		// match in ingredients weighs more than match in steps
+		assert.Equal(t, soup, rs[0].ID)
+		assert.Equal(t, salad, rs[1].ID)
+
+		rs, _, err = serv.GetAll(ctx, recipe.Filter{Query: "морковь", CreatedBy: "639673eb2c5bcae361a8ad5a"}, recipe.PageRequest{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, salad, rs[0].ID)
+
+		maxCalories := 300.0
+		rs, _, err = serv.GetAll(ctx, recipe.Filter{Query: "курица", MaxCalories: &maxCalories}, recipe.PageRequest{Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, rs)
+		rs, _, err = serv.GetAll(ctx, recipe.Filter{Query: "курица", MinCalories: &maxCalories}, recipe.PageRequest{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, soup, rs[0].ID)
+	})
+	t.Run("list recipes by pages", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		userID := "639673eb2c5bcae361a8ad5b"
+		names := []string{"Борщ", "Блины", "Вареники", "Оладьи", "Щи"}
+		for _, name := range names {
+			_, err := serv.Create(ctx, recipe.CreateRecipeDTO{Name: name, CreatedBy: userID})
+			require.NoError(t, err)
+		}
+
+		filter := recipe.Filter{CreatedBy: userID}
+		var listed []string
+		page := recipe.PageRequest{Sort: recipe.SortName, Limit: 2}
+		for i := 0; i < len(names); i++ {
+			rs, next, err := serv.GetAll(ctx, filter, page)
+			require.NoError(t, err)
+			for _, r := range rs {
+				listed = append(listed, r.Name)
+			}
+			if len(next) == 0 {
+				break
+			}
+			page.Cursor = next
+		}
+		assert.Equal(t, []string{"Блины", "Борщ", "Вареники", "Оладьи", "Щи"}, listed)
+
+		rs, next, err := serv.GetAll(ctx, filter, recipe.PageRequest{Limit: 5})
+		require.NoError(t, err)
+		assert.Empty(t, next)
+		require.Len(t, rs, 5)
+		assert.Equal(t, "Щи", rs[0].Name)
+
+		_, _, err = serv.GetAll(ctx, filter, recipe.PageRequest{Sort: "popular"})
+		assert.ErrorIs(t, err, apperror.ErrInvalidSort)
+		_, _, err = serv.GetAll(ctx, filter, recipe.PageRequest{Sort: recipe.SortNewest, Cursor: "abc"})
+		assert.ErrorIs(t, err, apperror.ErrInvalidCursor)
+		_, next, err = serv.GetAll(ctx, filter, recipe.PageRequest{Sort: recipe.SortName, Limit: 1})
+		require.NoError(t, err)
+		_, _, err = serv.GetAll(ctx, filter, recipe.PageRequest{Sort: recipe.SortNewest, Cursor: next})
+		assert.ErrorIs(t, err, apperror.ErrInvalidCursor)
+	})
+	t.Run("revisions of recipe", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		userID := "639673eb2c5bcae361a8ad5c"
+		id, err := serv.Create(ctx, recipe.CreateRecipeDTO{
+			Name:      "Омлет",
+			CreatedBy: userID,
+			Steps:     []recipe.Step{{Description: "Взбить яйца"}},
+		})
+		require.NoError(t, err)
+
+		err = serv.Update(ctx, recipe.UpdateRecipeDTO{ID: id, UserID: "639673eb2c5bcae361a8ad5d", Version: 1, Name: "Яичница"})
+		assert.ErrorIs(t, err, apperror.ErrForbidden)
+		err = serv.Update(ctx, recipe.UpdateRecipeDTO{ID: id, UserID: userID, Version: 1, Name: "Омлет с молоком", Tags: []string{"завтрак"}})
+		require.NoError(t, err)
+		// update which changes nothing is not recorded
+		err = serv.Update(ctx, recipe.UpdateRecipeDTO{ID: id, Version: 2, Name: "Омлет с молоком"})
+		require.NoError(t, err)
+
+		revisions, err := serv.GetRevisions(ctx, id)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, int64(2), revisions[0].Number)
+		assert.Equal(t, userID, revisions[0].Author)
+		assert.Equal(t, []string{"name", "tags"}, revisions[0].Changed)
+		assert.Equal(t, []string{"name", "steps"}, revisions[1].Changed)
+
+		changes, err := serv.DiffRevisions(ctx, id, 1, 2)
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Equal(t, recipe.FieldChange{Field: "name", From: "Омлет", To: "Омлет с молоком"}, changes[0])
+
+		r, err := serv.Revert(ctx, recipe.RevertRecipeDTO{RecipeID: id, UserID: userID, Revision: 1})
+		require.NoError(t, err)
+		assert.Equal(t, "Омлет", r.Name)
+		assert.Empty(t, r.Tags)
+		revisions, err = serv.GetRevisions(ctx, id)
+		require.NoError(t, err)
+		require.Len(t, revisions, 3)
+		assert.Equal(t, int64(1), revisions[0].RevertedFrom)
+
+		_, err = serv.Revert(ctx, recipe.RevertRecipeDTO{RecipeID: id, UserID: userID, Revision: 10})
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+	})
+
+	t.Run("fork recipe", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		authorID := "639673eb2c5bcae361a8ad6a"
+		userID := "639673eb2c5bcae361a8ad6b"
+		id, err := serv.Create(ctx, recipe.CreateRecipeDTO{
+			Name:      "Борщ",
+			CreatedBy: authorID,
+			Steps:     []recipe.Step{{Description: "Сварить бульон"}},
+			Tags:      []string{"суп"},
+		})
+		require.NoError(t, err)
+
+		forkID, err := serv.Fork(ctx, recipe.ForkRecipeDTO{RecipeID: id, UserID: userID})
+		require.NoError(t, err)
+		fork, err := serv.GetByID(ctx, forkID)
+		require.NoError(t, err)
+		assert.True(t, fork.Draft)
+		assert.Equal(t, userID, fork.CreatedBy)
+		assert.Equal(t, int64(1), fork.Version)
+		assert.Equal(t, []string{"суп"}, fork.Tags)
+		require.NotNil(t, fork.ForkOf)
+		assert.Equal(t, recipe.ForkOf{RecipeID: id, AuthorID: authorID, Name: "Борщ", Version: 1}, *fork.ForkOf)
+
+		// draft is hidden from other users
+		_, err = serv.Fork(ctx, recipe.ForkRecipeDTO{RecipeID: forkID, UserID: authorID})
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+		_, err = serv.Fork(ctx, recipe.ForkRecipeDTO{RecipeID: id})
+		assert.ErrorIs(t, err, apperror.ErrForbidden)
+		forks, _, err := serv.GetAll(ctx, recipe.Filter{ForkOf: id}, recipe.PageRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, forks)
+
+		err = serv.Update(ctx, recipe.UpdateRecipeDTO{ID: forkID, UserID: userID, Version: 1, Name: "Борщ с фасолью", Publish: true})
+		require.NoError(t, err)
+		forks, _, err = serv.GetAll(ctx, recipe.Filter{ForkOf: id}, recipe.PageRequest{})
+		require.NoError(t, err)
+		require.Len(t, forks, 1)
+		assert.Equal(t, forkID, forks[0].ID)
+		assert.False(t, forks[0].Draft)
+
+		// fork keeps link to deleted original, but not to its author
+		_, err = serv.DeleteAllByUser(ctx, authorID)
+		require.NoError(t, err)
+		fork, err = serv.GetByID(ctx, forkID)
+		require.NoError(t, err)
+		require.NotNil(t, fork.ForkOf)
+		assert.Equal(t, id, fork.ForkOf.RecipeID)
+		assert.Equal(t, "Борщ", fork.ForkOf.Name)
+		assert.Empty(t, fork.ForkOf.AuthorID)
+	})
+
+	t.Run("scale recipe", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		userID := "639673eb2c5bcae361a8ad7a"
+		id, err := serv.Create(ctx, recipe.CreateRecipeDTO{
+			Name:      "Блины",
+			CreatedBy: userID,
+			Servings:  4,
+			Ingredients: []recipe.RecipeIngredient{
+				{IngredientID: "63a1b2c3d4e5f6a7b8c9d0e1", Unit: "г", Amount: 750},
+				{IngredientID: "63a1b2c3d4e5f6a7b8c9d0e2", Unit: "ст. л.", Amount: 8},
+				{IngredientID: "63a1b2c3d4e5f6a7b8c9d0e3", Unit: "ч. л.", Amount: 2.5},
+				{IngredientID: "63a1b2c3d4e5f6a7b8c9d0e4", Unit: "шт", Amount: 3},
+			},
+		})
+		require.NoError(t, err)
+		err = serv.UpdateNutritionFacts(ctx, recipe.RecipeNutritionsDTO{
+			RecipeID:       id,
+			NutritionFacts: recipe.NutritionFacts{Calories: 1500, Proteins: 40, Fats: 50, Carbohydrates: 220},
+		})
+		require.NoError(t, err)
+
+		r, err := serv.Scale(ctx, recipe.ScaleRecipeDTO{RecipeID: id, Servings: 8})
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), r.Servings)
+		assert.Equal(t, []recipe.RecipeIngredient{
+			{IngredientID: "63a1b2c3d4e5f6a7b8c9d0e1", Unit: "кг", Amount: 1.5},
+			{IngredientID: "63a1b2c3d4e5f6a7b8c9d0e2", Unit: "стакан", Amount: 1},
+			// 5 tsp is not a multiple of quarter tbsp
+			{IngredientID: "63a1b2c3d4e5f6a7b8c9d0e3", Unit: "ч. л.", Amount: 5},
+			{IngredientID: "63a1b2c3d4e5f6a7b8c9d0e4", Unit: "шт", Amount: 6},
+		}, r.Ingredients)
+		require.NotNil(t, r.NutritionFacts)
+		assert.Equal(t, recipe.NutritionFacts{Calories: 3000, Proteins: 80, Fats: 100, Carbohydrates: 440}, *r.NutritionFacts)
+
+		// scaled recipe is not saved unless requested
+		original, err := serv.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), original.Servings)
+		assert.Equal(t, "г", original.Ingredients[0].Unit)
+
+		_, err = serv.Scale(ctx, recipe.ScaleRecipeDTO{RecipeID: id, Servings: 0})
+		assert.ErrorIs(t, err, apperror.ErrInvalidServings)
+
+		saved, err := serv.Scale(ctx, recipe.ScaleRecipeDTO{RecipeID: id, UserID: userID, Servings: 2, Save: true})
+		require.NoError(t, err)
+		assert.NotEqual(t, id, saved.ID)
+		assert.True(t, saved.Draft)
+		assert.Equal(t, int64(2), saved.Servings)
+		assert.Equal(t, recipe.RecipeIngredient{IngredientID: "63a1b2c3d4e5f6a7b8c9d0e2", Unit: "ст. л.", Amount: 4}, saved.Ingredients[1])
+		require.NotNil(t, saved.ForkOf)
+		assert.Equal(t, id, saved.ForkOf.RecipeID)
+		require.NotNil(t, saved.NutritionFacts)
+		assert.Equal(t, float64(750), saved.NutritionFacts.Calories)
+
+		// saved draft is visible only to its author
+		_, err = serv.Scale(ctx, recipe.ScaleRecipeDTO{RecipeID: saved.ID, Servings: 4})
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
 	})
 }
@@ -4,25 +4,54 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"time"
 
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/rs/zerolog/log"
+	edb "github.com/tony-spark/recipetor-backend/recipe-service/db"
 	apperror "github.com/tony-spark/recipetor-backend/recipe-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/recipe-service/internal/metrics"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe/storage"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 type mongoStorage struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	ratings    *mongo.Collection
+	comments   *mongo.Collection
+	favorites  *mongo.Collection
+	// collections holds recipe collections of users
+	collections *mongo.Collection
+	categories  *mongo.Collection
+	revisions   *mongo.Collection
 }
 
 func NewStorage(dsn string, dbname string) (storage.Storage, error) {
-	client, err := mongo.NewClient(options.Client().ApplyURI(dsn))
+	driver, err := iofs.New(edb.EmbeddedDBFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("could not open migrations: %w", err)
+	}
+
+	migrator, err := migrate.NewWithSourceInstance("iofs", driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize migrator: %w", err)
+	}
+
+	err = migrator.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return nil, fmt.Errorf("could not execute db migrations: %w", err)
+	}
+
+	client, err := mongo.NewClient(options.Client().ApplyURI(dsn).SetMonitor(otelmongo.NewMonitor()))
 	if err != nil {
 		return nil, fmt.Errorf("could not create connection to test DB: %w", err)
 	}
@@ -42,9 +71,21 @@ func NewStorage(dsn string, dbname string) (storage.Storage, error) {
 	db := client.Database(dbname)
 
 	collection := db.Collection("recipes")
+	ratings := db.Collection("ratings")
+	comments := db.Collection("comments")
+	favorites := db.Collection("favorites")
+	collections := db.Collection("collections")
+	categories := db.Collection("categories")
+	revisions := db.Collection("revisions")
 	return mongoStorage{
-		client:     client,
-		collection: collection,
+		client:      client,
+		collection:  collection,
+		ratings:     ratings,
+		comments:    comments,
+		favorites:   favorites,
+		collections: collections,
+		categories:  categories,
+		revisions:   revisions,
 	}, nil
 }
 
@@ -64,6 +105,8 @@ func NewTestStorage(dsn string, dbname string) (storage.Storage, func(ctx contex
 }
 
 func (m mongoStorage) Create(ctx context.Context, recipe recipe.Recipe) (string, error) {
+	defer metrics.ObserveStorage("Create", time.Now())
+
 	result, err := m.collection.InsertOne(ctx, recipe)
 	if err != nil {
 		return "", fmt.Errorf("failed to insert recipe: %w", err)
@@ -78,6 +121,8 @@ func (m mongoStorage) Create(ctx context.Context, recipe recipe.Recipe) (string,
 }
 
 func (m mongoStorage) GetByID(ctx context.Context, id string) (r recipe.Recipe, err error) {
+	defer metrics.ObserveStorage("GetByID", time.Now())
+
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return r, fmt.Errorf("wrong id: %w", err)
@@ -97,8 +142,99 @@ func (m mongoStorage) GetByID(ctx context.Context, id string) (r recipe.Recipe,
 	return
 }
 
-func (m mongoStorage) GetAll(ctx context.Context, start int64, limit int64) (rs []recipe.Recipe, err error) {
-	cursor, err := m.collection.Find(ctx, bson.D{{}}, options.Find().SetSkip(start).SetLimit(limit))
+func (m mongoStorage) GetAll(ctx context.Context, filter recipe.Filter, sort string, after *recipe.Cursor, limit int64) (rs []recipe.Recipe, err error) {
+	defer metrics.ObserveStorage("GetAll", time.Now())
+
+	query := bson.M{}
+	if len(filter.Query) > 0 {
+		query["$text"] = bson.M{"$search": filter.Query}
+	}
+	if len(filter.CreatedBy) > 0 {
+		query["created_by"] = filter.CreatedBy
+	}
+	if !filter.Drafts {
+		query["draft"] = bson.M{"$ne": true}
+	}
+	if len(filter.ForkOf) > 0 {
+		query["fork_of.recipe_id"] = filter.ForkOf
+	}
+	if len(filter.Categories) > 0 {
+		query["categories"] = bson.M{"$all": filter.Categories}
+	}
+	if len(filter.Tags) > 0 {
+		query["tags"] = bson.M{"$all": filter.Tags}
+	}
+	calories := bson.M{}
+	if filter.MinCalories != nil {
+		calories["$gte"] = *filter.MinCalories
+	}
+	if filter.MaxCalories != nil {
+		calories["$lte"] = *filter.MaxCalories
+	}
+
+	// every order ends with _id, so that recipes with equal sort keys keep their places between pages
+	var key string
+	order := 1
+	switch sort {
+	case recipe.SortRelevance:
+		key, order = "score", -1
+	case recipe.SortNewest:
+		order = -1
+	case recipe.SortName:
+		key = "name"
+	case recipe.SortRating:
+		key, order = "rating_average", -1
+		query["rating_count"] = bson.M{"$gt": 0}
+	case recipe.SortCalories:
+		key = "nutrition_facts.calories"
+		calories["$exists"] = true
+	default:
+		return nil, fmt.Errorf("unknown sort order %q", sort)
+	}
+	if len(calories) > 0 {
+		query["nutrition_facts.calories"] = calories
+	}
+	sortKeys := bson.D{{Key: "_id", Value: order}}
+	if len(key) > 0 {
+		sortKeys = append(bson.D{{Key: key, Value: order}}, sortKeys...)
+	}
+	position := bson.M{}
+	if after != nil {
+		oid, err := primitive.ObjectIDFromHex(after.ID)
+		if err != nil {
+			return nil, fmt.Errorf("wrong id: %w", err)
+		}
+		cmp := "$gt"
+		if order < 0 {
+			cmp = "$lt"
+		}
+		if len(key) > 0 {
+			position["$or"] = bson.A{
+				bson.M{key: bson.M{cmp: after.Value}},
+				bson.M{key: after.Value, "_id": bson.M{cmp: oid}},
+			}
+		} else {
+			position["_id"] = bson.M{cmp: oid}
+		}
+	}
+
+	var cursor *mongo.Cursor
+	if sort == recipe.SortRelevance {
+		// text score can not be queried by find, so it is added to recipes in aggregation before position is matched
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: query}},
+			{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}},
+			{{Key: "$match", Value: position}},
+			{{Key: "$sort", Value: sortKeys}},
+			{{Key: "$limit", Value: limit}},
+		}
+		cursor, err = m.collection.Aggregate(ctx, pipeline)
+	} else {
+		for field, value := range position {
+			query[field] = value
+		}
+		cursor, err = m.collection.Find(ctx, query, options.Find().SetSort(sortKeys).SetLimit(limit))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to find recipes: %w", err)
 	}
@@ -112,6 +248,8 @@ func (m mongoStorage) GetAll(ctx context.Context, start int64, limit int64) (rs
 }
 
 func (m mongoStorage) GetAllByUser(ctx context.Context, userID string) (rs []recipe.Recipe, err error) {
+	defer metrics.ObserveStorage("GetAllByUser", time.Now())
+
 	_, err = primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, fmt.Errorf("wrong id: %w", err)
@@ -128,26 +266,139 @@ func (m mongoStorage) GetAllByUser(ctx context.Context, userID string) (rs []rec
 }
 
 func (m mongoStorage) Update(ctx context.Context, recipe recipe.Recipe) error {
+	defer metrics.ObserveStorage("Update", time.Now())
+
 	oid, err := primitive.ObjectIDFromHex(recipe.ID)
 	if err != nil {
 		return err
 	}
 
-	bs, err := bson.Marshal(recipe)
+	update := setOrUnset(contentFields(recipe))
+	update["$inc"] = bson.M{"version": 1}
+	return m.updateVersion(ctx, oid, recipe.Version, update)
+}
+
+// contentFields returns fields of recipe which are replaced as a whole when recipe is updated
+func contentFields(r recipe.Recipe) bson.M {
+	return bson.M{
+		"name":        r.Name,
+		"ingredients": r.Ingredients,
+		"steps":       r.Steps,
+		"servings":    r.Servings,
+		"categories":  r.Categories,
+		"tags":        r.Tags,
+	}
+}
+
+// setOrUnset returns update setting non-empty fields and removing empty ones the same way they are omitted on insert
+func setOrUnset(fields bson.M) bson.M {
+	set, unset := bson.M{}, bson.M{}
+	for field, value := range fields {
+		v := reflect.ValueOf(value)
+		if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+			unset[field] = ""
+		} else {
+			set[field] = value
+		}
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+func (m mongoStorage) RestoreContent(ctx context.Context, id string, version int64, content recipe.Recipe) error {
+	defer metrics.ObserveStorage("RestoreContent", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return fmt.Errorf("wrong id: %w", err)
 	}
+	// calculated fields are restored too, as they match restored content
+	fields := contentFields(content)
+	fields["nutrition_facts"] = content.NutritionFacts
+	fields["ingredient_names"] = content.IngredientNames
+
+	update := setOrUnset(fields)
+	update["$inc"] = bson.M{"version": 1}
+	return m.updateVersion(ctx, oid, version, update)
+}
 
-	var updates bson.M
-	err = bson.Unmarshal(bs, &updates)
+// updateVersion applies update to recipe if it is of version, recipes created before versioning have version 0
+func (m mongoStorage) updateVersion(ctx context.Context, oid primitive.ObjectID, version int64, update bson.M) error {
+	filter := bson.M{"_id": oid, "version": version}
+	if version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	result, err := m.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to update recipe: %w", err)
 	}
-	delete(updates, "_id")
+	if result.MatchedCount > 0 {
+		return nil
+	}
+	count, err := m.collection.CountDocuments(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return fmt.Errorf("failed to check recipe: %w", err)
+	}
+	if count == 0 {
+		return apperror.ErrNotFound
+	}
+	return apperror.ErrConflict
+}
 
-	result, err := m.collection.UpdateByID(ctx, oid, bson.M{"$set": updates})
+func (m mongoStorage) UpdateNutritionFacts(ctx context.Context, id string, facts recipe.NutritionFacts, ingredientNames []string) error {
+	defer metrics.ObserveStorage("UpdateNutritionFacts", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return fmt.Errorf("wrong id: %w", err)
+	}
+	set := bson.M{"nutrition_facts": facts}
+	if len(ingredientNames) > 0 {
+		set["ingredient_names"] = ingredientNames
+	}
+	result, err := m.collection.UpdateByID(ctx, oid, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to update nutrition facts: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) SetAuthor(ctx context.Context, id string, author string) error {
+	defer metrics.ObserveStorage("SetAuthor", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("wrong id: %w", err)
+	}
+	result, err := m.collection.UpdateByID(ctx, oid, bson.M{"$set": bson.M{"created_by": author}})
+	if err != nil {
+		return fmt.Errorf("failed to update author: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) Publish(ctx context.Context, id string) error {
+	defer metrics.ObserveStorage("Publish", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("wrong id: %w", err)
+	}
+	result, err := m.collection.UpdateByID(ctx, oid, bson.M{"$unset": bson.M{"draft": ""}})
+	if err != nil {
+		return fmt.Errorf("failed to publish recipe: %w", err)
 	}
 	if result.MatchedCount == 0 {
 		return apperror.ErrNotFound
@@ -155,7 +406,21 @@ func (m mongoStorage) Update(ctx context.Context, recipe recipe.Recipe) error {
 	return nil
 }
 
+func (m mongoStorage) AnonymizeForksOf(ctx context.Context, userID string) error {
+	defer metrics.ObserveStorage("AnonymizeForksOf", time.Now())
+
+	_, err := m.collection.UpdateMany(ctx,
+		bson.M{"fork_of.author_id": userID},
+		bson.M{"$set": bson.M{"fork_of.author_id": recipe.DeletedUser}})
+	if err != nil {
+		return fmt.Errorf("failed to anonymize forks: %w", err)
+	}
+	return nil
+}
+
 func (m mongoStorage) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveStorage("Delete", time.Now())
+
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return fmt.Errorf("wrong id: %w", err)
@@ -169,3 +434,730 @@ func (m mongoStorage) Delete(ctx context.Context, id string) error {
 	}
 	return nil
 }
+
+func (m mongoStorage) GetTopRated(ctx context.Context, limit int64) (rs []recipe.Recipe, err error) {
+	defer metrics.ObserveStorage("GetTopRated", time.Now())
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "rating_average", Value: -1}, {Key: "rating_count", Value: -1}}).
+		SetLimit(limit)
+	cursor, err := m.collection.Find(ctx, bson.M{"rating_count": bson.M{"$gt": 0}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find recipes: %w", err)
+	}
+	err = cursor.All(ctx, &rs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recipes: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) SaveRating(ctx context.Context, rating recipe.Rating) (r recipe.Rating, err error) {
+	defer metrics.ObserveStorage("SaveRating", time.Now())
+
+	id := primitive.NewObjectID()
+	filter := bson.M{"recipe_id": rating.RecipeID, "user_id": rating.UserID}
+	update := bson.M{
+		"$set": bson.M{
+			"score":      rating.Score,
+			"review":     rating.Review,
+			"updated_at": rating.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{"_id": id, "created_at": rating.CreatedAt},
+	}
+	// previous rating of user tells how to change rating summary of recipe
+	var previous recipe.Rating
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+	err = m.ratings.FindOneAndUpdate(ctx, filter, update, opts).Decode(&previous)
+	r = rating
+	scoreDelta, countDelta := rating.Score, 1
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		r.ID = id.Hex()
+	case err != nil:
+		return r, fmt.Errorf("failed to save rating: %w", err)
+	default:
+		r.ID, r.CreatedAt = previous.ID, previous.CreatedAt
+		scoreDelta, countDelta = rating.Score-previous.Score, 0
+	}
+
+	err = m.addToRatingSummary(ctx, rating.RecipeID, scoreDelta, countDelta)
+	return r, err
+}
+
+func (m mongoStorage) GetRating(ctx context.Context, recipeID string, userID string) (r recipe.Rating, err error) {
+	defer metrics.ObserveStorage("GetRating", time.Now())
+
+	err = m.ratings.FindOne(ctx, bson.M{"recipe_id": recipeID, "user_id": userID}).Decode(&r)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return r, apperror.ErrNotFound
+		}
+		return r, fmt.Errorf("failed to find rating: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) GetRatings(ctx context.Context, recipeID string) ([]recipe.Rating, error) {
+	defer metrics.ObserveStorage("GetRatings", time.Now())
+
+	return m.findRatings(ctx, bson.M{"recipe_id": recipeID})
+}
+
+func (m mongoStorage) GetRatingsByUser(ctx context.Context, userID string) ([]recipe.Rating, error) {
+	defer metrics.ObserveStorage("GetRatingsByUser", time.Now())
+
+	return m.findRatings(ctx, bson.M{"user_id": userID})
+}
+
+func (m mongoStorage) findRatings(ctx context.Context, filter bson.M) (rs []recipe.Rating, err error) {
+	cursor, err := m.ratings.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find ratings: %w", err)
+	}
+	err = cursor.All(ctx, &rs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ratings: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) DeleteRatingsByUser(ctx context.Context, userID string) error {
+	defer metrics.ObserveStorage("DeleteRatingsByUser", time.Now())
+
+	rs, err := m.findRatings(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return err
+	}
+	for _, r := range rs {
+		// rating is removed from summary only by the request which has deleted it
+		var deleted recipe.Rating
+		err = m.ratings.FindOneAndDelete(ctx, bson.M{"recipe_id": r.RecipeID, "user_id": userID}).Decode(&deleted)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				continue
+			}
+			return fmt.Errorf("failed to delete rating: %w", err)
+		}
+		err = m.addToRatingSummary(ctx, deleted.RecipeID, -deleted.Score, -1)
+		// rated recipe could be deleted already
+		if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m mongoStorage) DeleteRatingsByRecipe(ctx context.Context, recipeID string) error {
+	defer metrics.ObserveStorage("DeleteRatingsByRecipe", time.Now())
+
+	_, err := m.ratings.DeleteMany(ctx, bson.M{"recipe_id": recipeID})
+	if err != nil {
+		return fmt.Errorf("failed to delete ratings: %w", err)
+	}
+	return nil
+}
+
+// addToRatingSummary changes sum and number of ratings of recipe and recalculates its average rating in one
+// update, so concurrent ratings of recipe do not overwrite each other. Recipe without ratings has no rating fields
+func (m mongoStorage) addToRatingSummary(ctx context.Context, recipeID string, scoreDelta int, countDelta int) error {
+	oid, err := primitive.ObjectIDFromHex(recipeID)
+	if err != nil {
+		return fmt.Errorf("wrong id: %w", err)
+	}
+
+	rated := bson.M{"$gt": bson.A{"$rating_count", 0}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"rating_sum":   bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$rating_sum", 0}}, scoreDelta}},
+			"rating_count": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$rating_count", 0}}, countDelta}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"rating_sum":     bson.M{"$cond": bson.A{rated, "$rating_sum", "$$REMOVE"}},
+			"rating_count":   bson.M{"$cond": bson.A{rated, "$rating_count", "$$REMOVE"}},
+			"rating_average": bson.M{"$cond": bson.A{rated, bson.M{"$divide": bson.A{"$rating_sum", "$rating_count"}}, "$$REMOVE"}},
+		}}},
+	}
+	result, err := m.collection.UpdateByID(ctx, oid, update)
+	if err != nil {
+		return fmt.Errorf("failed to update rating summary: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) CreateComment(ctx context.Context, comment recipe.Comment) (string, error) {
+	defer metrics.ObserveStorage("CreateComment", time.Now())
+
+	result, err := m.comments.InsertOne(ctx, comment)
+	if err != nil {
+		return "", fmt.Errorf("failed to insert comment: %w", err)
+	}
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return "", fmt.Errorf("failed to insert comment: invalid InsertedID")
+	}
+
+	if len(comment.ParentID) > 0 {
+		parentID, err := primitive.ObjectIDFromHex(comment.ParentID)
+		if err != nil {
+			return "", fmt.Errorf("wrong parent id: %w", err)
+		}
+		_, err = m.comments.UpdateByID(ctx, parentID, bson.M{"$inc": bson.M{"reply_count": 1}})
+		if err != nil {
+			return "", fmt.Errorf("failed to update reply count: %w", err)
+		}
+	}
+
+	return id.Hex(), nil
+}
+
+func (m mongoStorage) GetComment(ctx context.Context, id string) (c recipe.Comment, err error) {
+	defer metrics.ObserveStorage("GetComment", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return c, fmt.Errorf("wrong id: %w", err)
+	}
+	err = m.comments.FindOne(ctx, bson.M{"_id": oid}).Decode(&c)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c, apperror.ErrNotFound
+		}
+		return c, fmt.Errorf("failed to find comment: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) GetComments(ctx context.Context, recipeID string, parentID string, start int64, limit int64) ([]recipe.Comment, error) {
+	defer metrics.ObserveStorage("GetComments", time.Now())
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(start).
+		SetLimit(limit)
+	return m.findComments(ctx, bson.M{"recipe_id": recipeID, "parent_id": parentID}, opts)
+}
+
+func (m mongoStorage) GetCommentsByUser(ctx context.Context, userID string) ([]recipe.Comment, error) {
+	defer metrics.ObserveStorage("GetCommentsByUser", time.Now())
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	return m.findComments(ctx, bson.M{"user_id": userID}, opts)
+}
+
+func (m mongoStorage) findComments(ctx context.Context, filter bson.M, opts *options.FindOptions) (cs []recipe.Comment, err error) {
+	cursor, err := m.comments.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find comments: %w", err)
+	}
+	err = cursor.All(ctx, &cs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch comments: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) UpdateCommentText(ctx context.Context, id string, text string, updatedAt time.Time) error {
+	defer metrics.ObserveStorage("UpdateCommentText", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("wrong id: %w", err)
+	}
+	result, err := m.comments.UpdateOne(ctx,
+		bson.M{"_id": oid, "deleted": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"text": text, "updated_at": updatedAt}})
+	if err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) DeleteComment(ctx context.Context, id string, deletedBy string, deletedAt time.Time) error {
+	defer metrics.ObserveStorage("DeleteComment", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("wrong id: %w", err)
+	}
+	result, err := m.comments.UpdateOne(ctx,
+		bson.M{"_id": oid, "deleted": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"text": "", "deleted": true, "deleted_by": deletedBy, "deleted_at": deletedAt}})
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) AnonymizeCommentsByUser(ctx context.Context, userID string, deletedAt time.Time) (int64, error) {
+	defer metrics.ObserveStorage("AnonymizeCommentsByUser", time.Now())
+
+	result, err := m.comments.UpdateMany(ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$set": bson.M{
+			"user_id":    recipe.DeletedUser,
+			"text":       "",
+			"deleted":    true,
+			"deleted_by": recipe.DeletedUser,
+			"deleted_at": deletedAt,
+		}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize comments: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+func (m mongoStorage) DeleteCommentsByRecipe(ctx context.Context, recipeID string) error {
+	defer metrics.ObserveStorage("DeleteCommentsByRecipe", time.Now())
+
+	_, err := m.comments.DeleteMany(ctx, bson.M{"recipe_id": recipeID})
+	if err != nil {
+		return fmt.Errorf("failed to delete comments: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) AddFavorite(ctx context.Context, favorite recipe.Favorite) error {
+	defer metrics.ObserveStorage("AddFavorite", time.Now())
+
+	_, err := m.favorites.UpdateOne(ctx,
+		bson.M{"user_id": favorite.UserID, "recipe_id": favorite.RecipeID},
+		bson.M{"$setOnInsert": bson.M{"created_at": favorite.CreatedAt}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save favorite: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) RemoveFavorite(ctx context.Context, userID string, recipeID string) error {
+	defer metrics.ObserveStorage("RemoveFavorite", time.Now())
+
+	result, err := m.favorites.DeleteOne(ctx, bson.M{"user_id": userID, "recipe_id": recipeID})
+	if err != nil {
+		return fmt.Errorf("failed to delete favorite: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) GetFavorites(ctx context.Context, userID string) (fs []recipe.Favorite, err error) {
+	defer metrics.ObserveStorage("GetFavorites", time.Now())
+
+	cursor, err := m.favorites.Find(ctx, bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find favorites: %w", err)
+	}
+	err = cursor.All(ctx, &fs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch favorites: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) DeleteFavoritesByUser(ctx context.Context, userID string) error {
+	defer metrics.ObserveStorage("DeleteFavoritesByUser", time.Now())
+
+	_, err := m.favorites.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete favorites: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) CreateCollection(ctx context.Context, collection recipe.Collection) (string, error) {
+	defer metrics.ObserveStorage("CreateCollection", time.Now())
+
+	result, err := m.collections.InsertOne(ctx, collection)
+	if err != nil {
+		return "", fmt.Errorf("failed to insert collection: %w", err)
+	}
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return "", fmt.Errorf("failed to insert collection: invalid InsertedID")
+	}
+	return id.Hex(), nil
+}
+
+func (m mongoStorage) GetCollection(ctx context.Context, id string) (c recipe.Collection, err error) {
+	defer metrics.ObserveStorage("GetCollection", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return c, fmt.Errorf("wrong id: %w", err)
+	}
+	err = m.collections.FindOne(ctx, bson.M{"_id": oid}).Decode(&c)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c, apperror.ErrNotFound
+		}
+		return c, fmt.Errorf("failed to find collection: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) GetCollectionsByUser(ctx context.Context, userID string) (cs []recipe.Collection, err error) {
+	defer metrics.ObserveStorage("GetCollectionsByUser", time.Now())
+
+	cursor, err := m.collections.Find(ctx, bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find collections: %w", err)
+	}
+	err = cursor.All(ctx, &cs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch collections: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) UpdateCollection(ctx context.Context, collection recipe.Collection) error {
+	defer metrics.ObserveStorage("UpdateCollection", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(collection.ID)
+	if err != nil {
+		return fmt.Errorf("wrong id: %w", err)
+	}
+	result, err := m.collections.UpdateByID(ctx, oid, bson.M{"$set": bson.M{
+		"name":        collection.Name,
+		"description": collection.Description,
+		"visibility":  collection.Visibility,
+		"recipe_ids":  collection.RecipeIDs,
+		"updated_at":  collection.UpdatedAt,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to update collection: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) AddToCollection(ctx context.Context, id string, recipeID string, updatedAt time.Time) error {
+	defer metrics.ObserveStorage("AddToCollection", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("wrong id: %w", err)
+	}
+	// $addToSet does not guarantee position, so recipe is pushed only if it is missing
+	result, err := m.collections.UpdateOne(ctx,
+		bson.M{"_id": oid, "recipe_ids": bson.M{"$ne": recipeID}},
+		bson.M{
+			"$push": bson.M{"recipe_ids": recipeID},
+			"$set":  bson.M{"updated_at": updatedAt},
+		})
+	if err != nil {
+		return fmt.Errorf("failed to add recipe to collection: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		_, err = m.GetCollection(ctx, id)
+		return err
+	}
+	return nil
+}
+
+func (m mongoStorage) RemoveFromCollection(ctx context.Context, id string, recipeID string, updatedAt time.Time) error {
+	defer metrics.ObserveStorage("RemoveFromCollection", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("wrong id: %w", err)
+	}
+	result, err := m.collections.UpdateByID(ctx, oid, bson.M{
+		"$pull": bson.M{"recipe_ids": recipeID},
+		"$set":  bson.M{"updated_at": updatedAt},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove recipe from collection: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) DeleteCollection(ctx context.Context, id string) error {
+	defer metrics.ObserveStorage("DeleteCollection", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("wrong id: %w", err)
+	}
+	result, err := m.collections.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) DeleteCollectionsByUser(ctx context.Context, userID string) error {
+	defer metrics.ObserveStorage("DeleteCollectionsByUser", time.Now())
+
+	_, err := m.collections.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete collections: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) RemoveFromAllCollections(ctx context.Context, recipeID string) error {
+	defer metrics.ObserveStorage("RemoveFromAllCollections", time.Now())
+
+	_, err := m.favorites.DeleteMany(ctx, bson.M{"recipe_id": recipeID})
+	if err != nil {
+		return fmt.Errorf("failed to delete favorites: %w", err)
+	}
+	_, err = m.collections.UpdateMany(ctx,
+		bson.M{"recipe_ids": recipeID},
+		bson.M{"$pull": bson.M{"recipe_ids": recipeID}})
+	if err != nil {
+		return fmt.Errorf("failed to remove recipe from collections: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) UpdateSavedCount(ctx context.Context, recipeID string) error {
+	defer metrics.ObserveStorage("UpdateSavedCount", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(recipeID)
+	if err != nil {
+		return fmt.Errorf("wrong id: %w", err)
+	}
+
+	favorites, err := m.favorites.CountDocuments(ctx, bson.M{"recipe_id": recipeID})
+	if err != nil {
+		return fmt.Errorf("failed to count favorites: %w", err)
+	}
+	collections, err := m.collections.CountDocuments(ctx, bson.M{"recipe_ids": recipeID})
+	if err != nil {
+		return fmt.Errorf("failed to count collections: %w", err)
+	}
+
+	update := bson.M{"$unset": bson.M{"saved_count": ""}}
+	if count := favorites + collections; count > 0 {
+		update = bson.M{"$set": bson.M{"saved_count": count}}
+	}
+	result, err := m.collection.UpdateByID(ctx, oid, update)
+	if err != nil {
+		return fmt.Errorf("failed to update saved count: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) CreateCategory(ctx context.Context, category recipe.Category) (string, error) {
+	defer metrics.ObserveStorage("CreateCategory", time.Now())
+
+	result, err := m.categories.InsertOne(ctx, category)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", apperror.ErrDuplicate
+		}
+		return "", fmt.Errorf("failed to insert category: %w", err)
+	}
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return "", fmt.Errorf("failed to insert category: invalid InsertedID")
+	}
+	return id.Hex(), nil
+}
+
+func (m mongoStorage) GetCategory(ctx context.Context, id string) (c recipe.Category, err error) {
+	defer metrics.ObserveStorage("GetCategory", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return c, fmt.Errorf("wrong id: %w", err)
+	}
+	err = m.categories.FindOne(ctx, bson.M{"_id": oid}).Decode(&c)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c, apperror.ErrNotFound
+		}
+		return c, fmt.Errorf("failed to find category: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) GetCategories(ctx context.Context, kind string) (cs []recipe.Category, err error) {
+	defer metrics.ObserveStorage("GetCategories", time.Now())
+
+	filter := bson.M{}
+	if len(kind) > 0 {
+		filter["kind"] = kind
+	}
+	cursor, err := m.categories.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "kind", Value: 1}, {Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find categories: %w", err)
+	}
+	err = cursor.All(ctx, &cs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) DeleteCategory(ctx context.Context, id string) error {
+	defer metrics.ObserveStorage("DeleteCategory", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("wrong id: %w", err)
+	}
+	// category is removed from recipes first, so that failed attempt can be repeated
+	_, err = m.collection.UpdateMany(ctx,
+		bson.M{"categories": id},
+		bson.M{"$pull": bson.M{"categories": id}})
+	if err != nil {
+		return fmt.Errorf("failed to remove category from recipes: %w", err)
+	}
+	result, err := m.categories.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) CountByCategory(ctx context.Context) (map[string]int64, error) {
+	defer metrics.ObserveStorage("CountByCategory", time.Now())
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"categories.0": bson.M{"$exists": true}}}},
+		{{Key: "$unwind", Value: "$categories"}},
+		{{Key: "$group", Value: bson.M{"_id": "$categories", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count recipes by category: %w", err)
+	}
+	var counts []struct {
+		ID    string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	err = cursor.All(ctx, &counts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recipe counts: %w", err)
+	}
+
+	result := make(map[string]int64, len(counts))
+	for _, c := range counts {
+		result[c.ID] = c.Count
+	}
+	return result, nil
+}
+
+func (m mongoStorage) CreateRevision(ctx context.Context, revision recipe.Revision) (recipe.Revision, error) {
+	defer metrics.ObserveStorage("CreateRevision", time.Now())
+
+	var last recipe.Revision
+	opts := options.FindOne().SetSort(bson.M{"number": -1}).SetProjection(bson.M{"number": 1})
+	err := m.revisions.FindOne(ctx, bson.M{"recipe_id": revision.RecipeID}, opts).Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return revision, fmt.Errorf("failed to find last revision: %w", err)
+	}
+	revision.Number = last.Number + 1
+
+	// number is unique within recipe, so concurrent revisions can not get the same one
+	result, err := m.revisions.InsertOne(ctx, revision)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return revision, apperror.ErrDuplicate
+		}
+		return revision, fmt.Errorf("failed to insert revision: %w", err)
+	}
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return revision, fmt.Errorf("failed to insert revision: invalid InsertedID")
+	}
+	revision.ID = id.Hex()
+	return revision, nil
+}
+
+func (m mongoStorage) GetRevision(ctx context.Context, recipeID string, number int64) (r recipe.Revision, err error) {
+	defer metrics.ObserveStorage("GetRevision", time.Now())
+
+	err = m.revisions.FindOne(ctx, bson.M{"recipe_id": recipeID, "number": number}).Decode(&r)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return r, apperror.ErrNotFound
+		}
+		return r, fmt.Errorf("failed to find revision: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) GetRevisions(ctx context.Context, recipeID string) (rs []recipe.Revision, err error) {
+	defer metrics.ObserveStorage("GetRevisions", time.Now())
+
+	opts := options.Find().SetSort(bson.M{"number": -1})
+	cursor, err := m.revisions.Find(ctx, bson.M{"recipe_id": recipeID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find revisions: %w", err)
+	}
+	err = cursor.All(ctx, &rs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch revisions: %w", err)
+	}
+	return
+}
+
+func (m mongoStorage) DeleteRevisionsByRecipe(ctx context.Context, recipeID string) error {
+	defer metrics.ObserveStorage("DeleteRevisionsByRecipe", time.Now())
+
+	_, err := m.revisions.DeleteMany(ctx, bson.M{"recipe_id": recipeID})
+	if err != nil {
+		return fmt.Errorf("failed to delete revisions: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) AnonymizeRevisionsByUser(ctx context.Context, userID string) error {
+	defer metrics.ObserveStorage("AnonymizeRevisionsByUser", time.Now())
+
+	_, err := m.revisions.UpdateMany(ctx,
+		bson.M{"author": userID},
+		bson.M{"$set": bson.M{"author": recipe.DeletedUser}})
+	if err != nil {
+		return fmt.Errorf("failed to anonymize authors of revisions: %w", err)
+	}
+	_, err = m.revisions.UpdateMany(ctx,
+		bson.M{"recipe.created_by": userID},
+		bson.M{"$set": bson.M{"recipe.created_by": recipe.DeletedUser}})
+	if err != nil {
+		return fmt.Errorf("failed to anonymize recipes of revisions: %w", err)
+	}
+	_, err = m.revisions.UpdateMany(ctx,
+		bson.M{"recipe.fork_of.author_id": userID},
+		bson.M{"$set": bson.M{"recipe.fork_of.author_id": recipe.DeletedUser}})
+	if err != nil {
+		return fmt.Errorf("failed to anonymize forks of revisions: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, nil)
+}
@@ -3,6 +3,7 @@ package mongodb
 import (
 	"context"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -100,7 +101,7 @@ func TestStorage(t *testing.T) {
 		}
 
 		{
-			rs, err := s.GetAll(ctx, 0, 2)
+			rs, err := s.GetAll(ctx, recipe.Filter{}, recipe.SortNewest, nil, 2)
 			require.NoError(t, err)
 			assert.Condition(t, func() (success bool) {
 				return len(rs) <= 2
@@ -108,6 +109,67 @@ func TestStorage(t *testing.T) {
 		}
 	})
 
+	t.Run("get all by pages", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		author := "639673eb2c5bcae361a8ad55"
+		loaded := []recipe.Recipe{
+			{Name: "Пагинация Б", CreatedBy: author},
+			{Name: "Пагинация А", CreatedBy: author, Steps: []recipe.Step{{Description: "пагинация"}}},
+			{Name: "Пагинация Б", CreatedBy: author},
+			{Name: "Пагинация В", CreatedBy: author},
+			{Name: "Пагинация А", CreatedBy: author, Steps: []recipe.Step{{Description: "пагинация"}}},
+		}
+		for _, r := range loaded {
+			_, err := s.Create(ctx, r)
+			require.NoError(t, err)
+		}
+
+		for _, sort := range []string{recipe.SortNewest, recipe.SortName, recipe.SortRelevance} {
+			filter := recipe.Filter{CreatedBy: author}
+			if sort == recipe.SortRelevance {
+				filter.Query = "пагинация"
+			}
+			// recipes with equal sort keys are neither repeated nor skipped between pages
+			seen := make(map[string]bool)
+			var after *recipe.Cursor
+			var listed []recipe.Recipe
+			for {
+				rs, err := s.GetAll(ctx, filter, sort, after, 2)
+				require.NoError(t, err)
+				if len(rs) == 0 {
+					break
+				}
+				for _, r := range rs {
+					assert.False(t, seen[r.ID], "recipe %s is listed twice in order %s", r.ID, sort)
+					seen[r.ID] = true
+				}
+				listed = append(listed, rs...)
+				last := rs[len(rs)-1]
+				after = &recipe.Cursor{Sort: sort, ID: last.ID}
+				switch sort {
+				case recipe.SortName:
+					after.Value = last.Name
+				case recipe.SortRelevance:
+					after.Value = last.TextScore
+				}
+			}
+			require.Len(t, listed, len(loaded), "order %s", sort)
+
+			switch sort {
+			case recipe.SortName:
+				assert.Equal(t, "Пагинация А", listed[0].Name)
+				assert.Equal(t, "Пагинация В", listed[len(listed)-1].Name)
+			case recipe.SortRelevance:
+				// match in steps adds to relevance
+				assert.NotEmpty(t, listed[0].Steps)
+				assert.NotEmpty(t, listed[1].Steps)
+				assert.Empty(t, listed[2].Steps)
+			}
+		}
+	})
+
 	t.Run("create recipe and get all by user", func(t *testing.T) {
 		loaded := []recipe.Recipe{
 			{
@@ -152,6 +214,8 @@ func TestStorage(t *testing.T) {
 			CreatedBy:   "639673eb2c5bcae361a8ad4a",
 			Ingredients: nil,
 			Steps:       nil,
+			Tags:        []string{"суп"},
+			Servings:    4,
 		}
 
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -164,13 +228,48 @@ func TestStorage(t *testing.T) {
 		r.ID = id
 		updated := r
 		updated.Name = "Тестовый рецепт 9 (ред.)"
+		updated.Tags = nil
+		updated.Servings = 0
+
+		err = s.Update(ctx, updated)
+		require.NoError(t, err)
 
+		got, err := s.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, updated.Name, got.Name)
+		// empty fields are cleared by update
+		assert.Empty(t, got.Tags)
+		assert.Zero(t, got.Servings)
+	})
+
+	t.Run("update outdated version", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		r := recipe.Recipe{Name: "Тестовый рецепт 13", CreatedBy: "639673eb2c5bcae361a8ad4a", Version: 1}
+		id, err := s.Create(ctx, r)
+		require.NoError(t, err)
+		r.ID = id
+
+		updated := r
+		updated.Name = "Тестовый рецепт 13 (ред.)"
 		err = s.Update(ctx, updated)
 		require.NoError(t, err)
 
+		// both changes are based on version 1, which is not current anymore
+		err = s.Update(ctx, updated)
+		assert.ErrorIs(t, err, apperror.ErrConflict)
+		err = s.RestoreContent(ctx, id, r.Version, r)
+		assert.ErrorIs(t, err, apperror.ErrConflict)
+
 		got, err := s.GetByID(ctx, id)
 		require.NoError(t, err)
 		assert.Equal(t, updated.Name, got.Name)
+		assert.Equal(t, int64(2), got.Version)
+
+		updated.ID = "639673eb2c5bcae361a8ad56"
+		err = s.Update(ctx, updated)
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
 	})
 
 	t.Run("create recipe and delete", func(t *testing.T) {
@@ -195,4 +294,83 @@ func TestStorage(t *testing.T) {
 		assert.EqualError(t, err, apperror.ErrNotFound.Error())
 		assert.Empty(t, got)
 	})
+
+	t.Run("rate recipe concurrently", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		id, err := s.Create(ctx, recipe.Recipe{Name: "Тестовый рецепт 11", CreatedBy: "639673eb2c5bcae361a8ad4a"})
+		require.NoError(t, err)
+
+		users := []string{"639673eb2c5bcae361a8ad51", "639673eb2c5bcae361a8ad52", "639673eb2c5bcae361a8ad53", "639673eb2c5bcae361a8ad54"}
+		var wg sync.WaitGroup
+		for i, userID := range users {
+			wg.Add(1)
+			go func(userID string, score int) {
+				defer wg.Done()
+				_, err := s.SaveRating(ctx, recipe.Rating{RecipeID: id, UserID: userID, Score: score})
+				assert.NoError(t, err)
+			}(userID, i+1)
+		}
+		wg.Wait()
+
+		got, err := s.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), got.RatingCount)
+		assert.InDelta(t, 2.5, got.RatingAverage, 0.001)
+
+		// replaced rating changes only the score
+		_, err = s.SaveRating(ctx, recipe.Rating{RecipeID: id, UserID: users[0], Score: 5})
+		require.NoError(t, err)
+		err = s.DeleteRatingsByUser(ctx, users[1])
+		require.NoError(t, err)
+
+		got, err = s.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), got.RatingCount)
+		assert.InDelta(t, 4, got.RatingAverage, 0.001)
+	})
+
+	t.Run("create revisions concurrently", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		r := recipe.Recipe{Name: "Тестовый рецепт 12", CreatedBy: "639673eb2c5bcae361a8ad4a"}
+		id, err := s.Create(ctx, r)
+		require.NoError(t, err)
+		r.ID = id
+
+		// revisions get the same number when saved concurrently, only one of them is stored
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		created := 0
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := s.CreateRevision(ctx, recipe.Revision{RecipeID: id, CreatedAt: time.Now().UTC(), Recipe: r})
+				if err != nil {
+					assert.ErrorIs(t, err, apperror.ErrDuplicate)
+					return
+				}
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		revisions, err := s.GetRevisions(ctx, id)
+		require.NoError(t, err)
+		require.Len(t, revisions, created)
+		numbers := make(map[int64]bool)
+		for _, revision := range revisions {
+			assert.False(t, numbers[revision.Number], "revision number %d is repeated", revision.Number)
+			numbers[revision.Number] = true
+		}
+
+		revision, err := s.CreateRevision(ctx, recipe.Revision{RecipeID: id, CreatedAt: time.Now().UTC(), Recipe: r})
+		require.NoError(t, err)
+		assert.Equal(t, int64(created+1), revision.Number)
+	})
 }
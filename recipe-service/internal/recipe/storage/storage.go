@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/tony-spark/recipetor-backend/recipe-service/internal/recipe"
 )
@@ -9,8 +10,82 @@ import (
 type Storage interface {
 	Create(ctx context.Context, recipe recipe.Recipe) (string, error)
 	GetByID(ctx context.Context, id string) (recipe.Recipe, error)
-	GetAll(ctx context.Context, start int64, limit int64) ([]recipe.Recipe, error)
+	// GetAll returns up to limit recipes matching filter in sort order, starting after cursor if it is not nil
+	GetAll(ctx context.Context, filter recipe.Filter, sort string, after *recipe.Cursor, limit int64) ([]recipe.Recipe, error)
 	GetAllByUser(ctx context.Context, userID string) ([]recipe.Recipe, error)
+	// Update changes recipe if it is still of recipe.Version and increments version, ErrConflict is returned otherwise
 	Update(ctx context.Context, recipe recipe.Recipe) error
+	// RestoreContent replaces fields of recipe edited by users with ones of content, empty fields are removed.
+	// Like Update, it changes recipe only if it is still of version
+	RestoreContent(ctx context.Context, id string, version int64, content recipe.Recipe) error
+	// UpdateNutritionFacts sets nutrition facts and ingredient names (unless they are empty) without changing
+	// anything else, version of recipe is kept
+	UpdateNutritionFacts(ctx context.Context, id string, facts recipe.NutritionFacts, ingredientNames []string) error
+	// SetAuthor replaces author of recipe, version of recipe is kept
+	SetAuthor(ctx context.Context, id string, author string) error
+	// Publish makes draft recipe visible to everyone
+	Publish(ctx context.Context, id string) error
+	// AnonymizeForksOf replaces user with recipe.DeletedUser as author of original recipe of forks
+	AnonymizeForksOf(ctx context.Context, userID string) error
 	Delete(ctx context.Context, id string) error
+	// GetTopRated returns rated recipes with the highest average rating first
+	GetTopRated(ctx context.Context, limit int64) ([]recipe.Recipe, error)
+	// SaveRating creates rating or replaces rating of the same user for the same recipe and updates rating
+	// summary of recipe
+	SaveRating(ctx context.Context, rating recipe.Rating) (recipe.Rating, error)
+	GetRating(ctx context.Context, recipeID string, userID string) (recipe.Rating, error)
+	GetRatings(ctx context.Context, recipeID string) ([]recipe.Rating, error)
+	GetRatingsByUser(ctx context.Context, userID string) ([]recipe.Rating, error)
+	// DeleteRatingsByUser deletes ratings of user and removes them from rating summaries of rated recipes
+	DeleteRatingsByUser(ctx context.Context, userID string) error
+	DeleteRatingsByRecipe(ctx context.Context, recipeID string) error
+	// CreateComment creates comment and increments reply count of parent comment
+	CreateComment(ctx context.Context, comment recipe.Comment) (string, error)
+	GetComment(ctx context.Context, id string) (recipe.Comment, error)
+	// GetComments returns comments of recipe replying to parentID in order they were created
+	GetComments(ctx context.Context, recipeID string, parentID string, start int64, limit int64) ([]recipe.Comment, error)
+	GetCommentsByUser(ctx context.Context, userID string) ([]recipe.Comment, error)
+	UpdateCommentText(ctx context.Context, id string, text string, updatedAt time.Time) error
+	// DeleteComment removes text of comment and marks it deleted
+	DeleteComment(ctx context.Context, id string, deletedBy string, deletedAt time.Time) error
+	// AnonymizeCommentsByUser deletes comments of user and replaces author with recipe.DeletedUser
+	AnonymizeCommentsByUser(ctx context.Context, userID string, deletedAt time.Time) (int64, error)
+	DeleteCommentsByRecipe(ctx context.Context, recipeID string) error
+	// AddFavorite saves recipe to favorites of user, saving it again has no effect
+	AddFavorite(ctx context.Context, favorite recipe.Favorite) error
+	RemoveFavorite(ctx context.Context, userID string, recipeID string) error
+	GetFavorites(ctx context.Context, userID string) ([]recipe.Favorite, error)
+	DeleteFavoritesByUser(ctx context.Context, userID string) error
+	CreateCollection(ctx context.Context, collection recipe.Collection) (string, error)
+	GetCollection(ctx context.Context, id string) (recipe.Collection, error)
+	GetCollectionsByUser(ctx context.Context, userID string) ([]recipe.Collection, error)
+	UpdateCollection(ctx context.Context, collection recipe.Collection) error
+	// AddToCollection appends recipe to collection unless it is there already
+	AddToCollection(ctx context.Context, id string, recipeID string, updatedAt time.Time) error
+	RemoveFromCollection(ctx context.Context, id string, recipeID string, updatedAt time.Time) error
+	DeleteCollection(ctx context.Context, id string) error
+	DeleteCollectionsByUser(ctx context.Context, userID string) error
+	// RemoveFromAllCollections removes recipe from favorites and collections of all users
+	RemoveFromAllCollections(ctx context.Context, recipeID string) error
+	// UpdateSavedCount recalculates number of favorites and collections recipe is saved to
+	UpdateSavedCount(ctx context.Context, recipeID string) error
+	CreateCategory(ctx context.Context, category recipe.Category) (string, error)
+	GetCategory(ctx context.Context, id string) (recipe.Category, error)
+	// GetCategories returns categories of kind, or all categories if kind is empty
+	GetCategories(ctx context.Context, kind string) ([]recipe.Category, error)
+	// DeleteCategory deletes category and removes it from recipes
+	DeleteCategory(ctx context.Context, id string) error
+	// CountByCategory returns number of recipes in each category having recipes
+	CountByCategory(ctx context.Context) (map[string]int64, error)
+	// CreateRevision saves revision numbered after the last revision of recipe. ErrDuplicate is returned if another
+	// revision of recipe is saved concurrently
+	CreateRevision(ctx context.Context, revision recipe.Revision) (recipe.Revision, error)
+	GetRevision(ctx context.Context, recipeID string, number int64) (recipe.Revision, error)
+	// GetRevisions returns revisions of recipe, the latest first
+	GetRevisions(ctx context.Context, recipeID string) ([]recipe.Revision, error)
+	DeleteRevisionsByRecipe(ctx context.Context, recipeID string) error
+	// AnonymizeRevisionsByUser replaces user with recipe.DeletedUser as author of revisions, of recipe snapshots
+	// and of originals of forks in snapshots
+	AnonymizeRevisionsByUser(ctx context.Context, userID string) error
+	Ping(ctx context.Context) error
 }
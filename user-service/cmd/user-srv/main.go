@@ -6,12 +6,17 @@ import (
 	"github.com/tony-spark/recipetor-backend/user-service/internal/controller/kafka"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/config"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/controller/http"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/health"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/notifier"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/tracing"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/user/service"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/user/storage/mongodb"
 )
@@ -30,13 +35,77 @@ func main() {
 	}
 	log.Logger = log.Logger.Level(logLevel)
 
+	shutdownTracing, err := tracing.Init(config.Config.Tracing.Exporter, config.Config.Tracing.OTLPEndpoint, config.Config.Tracing.File)
+	if err != nil {
+		log.Fatal().Err(err).Msg("could not initialize tracing")
+	}
+
 	stor, err := mongodb.NewStorage(config.Config.Mongo.DSN, config.Config.Mongo.DB)
 	if err != nil {
 		log.Fatal().Err(err).Msg("could not initialize storage")
 	}
 	log.Info().Msg("connected to MongoDB")
 
-	userService := service.NewService(stor)
+	notif, err := notifier.New(config.Config.Notifier.Sender, config.Config.Notifier.File, notifier.SMTPConfig{
+		Host:     config.Config.Notifier.SMTP.Host,
+		Port:     config.Config.Notifier.SMTP.Port,
+		Username: config.Config.Notifier.SMTP.Username,
+		Password: config.Config.Notifier.SMTP.Password,
+		From:     config.Config.Notifier.SMTP.From,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("could not initialize notifier")
+	}
+
+	passwordPolicy := service.PasswordPolicy{
+		MinLength: config.Config.Password.MinLength,
+		MaxLength: config.Config.Password.MaxLength,
+	}
+	if len(config.Config.Password.BreachedList) > 0 {
+		passwordPolicy.Breached, err = service.LoadBreachedPasswords(config.Config.Password.BreachedList)
+		if err != nil {
+			log.Fatal().Err(err).Msg("could not load breached passwords")
+		}
+		log.Info().Msgf("loaded %d breached passwords", len(passwordPolicy.Breached))
+	}
+	passwordHashing := service.PasswordHashing{
+		Algorithm:     config.Config.Password.Hash,
+		BcryptCost:    config.Config.Password.BcryptCost,
+		Argon2Time:    uint32(config.Config.Password.Argon2Time),
+		Argon2Memory:  uint32(config.Config.Password.Argon2Memory),
+		Argon2Threads: uint8(config.Config.Password.Argon2Threads),
+	}
+	err = passwordHashing.Validate()
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid password hashing settings")
+	}
+
+	userService := service.NewService(stor, notif, service.Config{
+		Login: service.LoginPolicy{
+			FreeAttempts:      config.Config.Login.FreeAttempts,
+			Delay:             config.Config.Login.Delay,
+			MaxDelay:          config.Config.Login.MaxDelay,
+			MaxAttempts:       config.Config.Login.MaxAttempts,
+			ClientMaxAttempts: config.Config.Login.ClientMaxAttempts,
+			Lockout:           config.Config.Login.Lockout,
+		},
+		Password:             passwordPolicy,
+		Hashing:              passwordHashing,
+		PasswordResetTTL:     config.Config.PasswordReset.TTL,
+		EmailVerificationTTL: config.Config.EmailVerification.TTL,
+		AdminEmails:          splitList(config.Config.AdminEmails),
+		DeletionServices:     splitList(config.Config.Deletion.Services),
+		ExportServices:       splitList(config.Config.Export.Services),
+		ExportTTL:            config.Config.Export.TTL,
+		ExportDir:            config.Config.Export.Dir,
+	})
+
+	promoteCtx, promoteCancel := context.WithTimeout(context.Background(), time.Minute)
+	err = userService.PromoteAdmins(promoteCtx)
+	promoteCancel()
+	if err != nil {
+		log.Fatal().Err(err).Msg("could not grant admin role to admin emails")
+	}
 
 	controller, err := kafka.NewController(userService, config.Config.Kafka.Brokers)
 	if err != nil {
@@ -52,6 +121,38 @@ func main() {
 		}
 	}()
 
+	go func() {
+		ticker := time.NewTicker(config.Config.EmailVerification.CleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			cleanupCtx, cleanupCancel := context.WithTimeout(ctx, time.Minute)
+			_, err := userService.DeleteExpiredRegistrations(cleanupCtx)
+			cleanupCancel()
+			if err != nil {
+				log.Error().Err(err).Msg("failed to clean up expired registrations")
+			}
+		}
+	}()
+
+	checker := health.NewChecker()
+	checker.AddLivenessCheck("kafka", controller.Live)
+	checker.AddReadinessCheck("kafka", controller.Ready)
+	checker.AddReadinessCheck("mongodb", stor.Ping)
+
+	httpController := http.NewController(config.Config.HTTP.Address, checker)
+	go func() {
+		err := httpController.Run(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error running http server")
+		}
+	}()
+	log.Info().Msgf("serving metrics and health checks on %s", config.Config.HTTP.Address)
+
 	terminateSignal := make(chan os.Signal, 1)
 	signal.Notify(terminateSignal, syscall.SIGINT, syscall.SIGTERM)
 
@@ -61,6 +162,27 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("controller failed to stop properly")
 	}
+	err = httpController.Stop()
+	if err != nil {
+		log.Fatal().Err(err).Msg("http server failed to stop properly")
+	}
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	err = shutdownTracing(shutdownCtx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to flush traces")
+	}
 
 	log.Info().Msg("user service interrupted via system signal")
 }
+
+// splitList splits comma separated list skipping empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"flag"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 	"github.com/rs/zerolog/log"
@@ -17,16 +18,102 @@ type config struct {
 		DSN string `env:"MONGO_DSN"`
 		DB  string `env:"MONGO_DB"`
 	}
+	Login struct {
+		FreeAttempts      int           `env:"LOGIN_FREE_ATTEMPTS"`
+		Delay             time.Duration `env:"LOGIN_DELAY"`
+		MaxDelay          time.Duration `env:"LOGIN_MAX_DELAY"`
+		MaxAttempts       int           `env:"LOGIN_MAX_ATTEMPTS"`
+		ClientMaxAttempts int           `env:"LOGIN_CLIENT_MAX_ATTEMPTS"`
+		Lockout           time.Duration `env:"LOGIN_LOCKOUT"`
+	}
+	Password struct {
+		MinLength     int    `env:"PASSWORD_MIN_LENGTH"`
+		MaxLength     int    `env:"PASSWORD_MAX_LENGTH"`
+		BreachedList  string `env:"PASSWORD_BREACHED_LIST"`
+		Hash          string `env:"PASSWORD_HASH"`
+		BcryptCost    int    `env:"BCRYPT_COST"`
+		Argon2Time    uint   `env:"ARGON2_TIME"`
+		Argon2Memory  uint   `env:"ARGON2_MEMORY"`
+		Argon2Threads uint   `env:"ARGON2_THREADS"`
+	}
+	PasswordReset struct {
+		TTL time.Duration `env:"PASSWORD_RESET_TTL"`
+	}
+	EmailVerification struct {
+		TTL             time.Duration `env:"EMAIL_VERIFICATION_TTL"`
+		CleanupInterval time.Duration `env:"EMAIL_VERIFICATION_CLEANUP_INTERVAL"`
+	}
+	AdminEmails string `env:"ADMIN_EMAILS"`
+	Deletion    struct {
+		Services string `env:"DELETION_SERVICES"`
+	}
+	Export struct {
+		Services string        `env:"EXPORT_SERVICES"`
+		TTL      time.Duration `env:"EXPORT_TTL"`
+		Dir      string        `env:"EXPORT_DIR"`
+	}
+	Notifier struct {
+		Sender string `env:"NOTIFIER_SENDER"`
+		File   string `env:"NOTIFIER_FILE"`
+		SMTP   struct {
+			Host     string `env:"SMTP_HOST"`
+			Port     int    `env:"SMTP_PORT"`
+			Username string `env:"SMTP_USERNAME"`
+			Password string `env:"SMTP_PASSWORD"`
+			From     string `env:"SMTP_FROM"`
+		}
+	}
 	Kafka struct {
 		Brokers string `env:"KAFKA_BROKERS"`
 	}
+	HTTP struct {
+		Address string `env:"HTTP_ADDRESS"`
+	}
+	Tracing struct {
+		Exporter     string `env:"TRACING_EXPORTER"`
+		OTLPEndpoint string `env:"TRACING_OTLP_ENDPOINT"`
+		File         string `env:"TRACING_FILE"`
+	}
 }
 
 func Parse() error {
 	flag.StringVar(&Config.LogLevel, "log-level", "debug", "application log level")
 	flag.StringVar(&Config.Mongo.DSN, "mongo-dsn", "", "mongodb connection string")
 	flag.StringVar(&Config.Mongo.DB, "mongo-db", "", "mongodb database name")
+	flag.IntVar(&Config.Login.FreeAttempts, "login-free-attempts", 3, "failed logins allowed without delay")
+	flag.DurationVar(&Config.Login.Delay, "login-delay", time.Second, "initial delay between login attempts after free attempts are used up")
+	flag.DurationVar(&Config.Login.MaxDelay, "login-max-delay", 30*time.Second, "maximum delay between login attempts")
+	flag.IntVar(&Config.Login.MaxAttempts, "login-max-attempts", 10, "failed logins after which account is locked")
+	flag.IntVar(&Config.Login.ClientMaxAttempts, "login-client-max-attempts", 50, "failed logins after which client is locked")
+	flag.DurationVar(&Config.Login.Lockout, "login-lockout", 15*time.Minute, "lockout duration")
+	flag.IntVar(&Config.Password.MinLength, "password-min-length", 8, "minimum password length in characters")
+	flag.IntVar(&Config.Password.MaxLength, "password-max-length", 72, "maximum password length in bytes")
+	flag.StringVar(&Config.Password.BreachedList, "password-breached-list", "", "file with leaked passwords to reject, one per line")
+	flag.StringVar(&Config.Password.Hash, "password-hash", "bcrypt", "password hashing algorithm: bcrypt or argon2id")
+	flag.IntVar(&Config.Password.BcryptCost, "bcrypt-cost", 10, "bcrypt cost")
+	flag.UintVar(&Config.Password.Argon2Time, "argon2-time", 1, "argon2id number of passes")
+	flag.UintVar(&Config.Password.Argon2Memory, "argon2-memory", 64*1024, "argon2id memory in KiB")
+	flag.UintVar(&Config.Password.Argon2Threads, "argon2-threads", 4, "argon2id parallelism")
+	flag.DurationVar(&Config.PasswordReset.TTL, "password-reset-ttl", time.Hour, "password reset token lifetime")
+	flag.DurationVar(&Config.EmailVerification.TTL, "email-verification-ttl", 24*time.Hour, "time to verify email before registration is deleted")
+	flag.DurationVar(&Config.EmailVerification.CleanupInterval, "email-verification-cleanup-interval", 10*time.Minute, "interval of expired registrations cleanup")
+	flag.StringVar(&Config.AdminEmails, "admin-emails", "", "comma separated emails of users granted admin role once verified")
+	flag.StringVar(&Config.Deletion.Services, "deletion-services", "recipe-service", "comma separated services which must delete user data before account deletion is completed")
+	flag.StringVar(&Config.Export.Services, "export-services", "recipe-service", "comma separated services which contribute to user data export")
+	flag.DurationVar(&Config.Export.TTL, "export-ttl", time.Hour, "how long user data export waits for services")
+	flag.StringVar(&Config.Export.Dir, "export-dir", "", "directory to write user data archives to, archives are sent in reply if empty")
+	flag.StringVar(&Config.Notifier.Sender, "notifier-sender", "log", "notification sender: log, file or smtp")
+	flag.StringVar(&Config.Notifier.File, "notifier-file", "notifications.jsonl", "file to write notifications to with file sender")
+	flag.StringVar(&Config.Notifier.SMTP.Host, "smtp-host", "localhost", "SMTP server host")
+	flag.IntVar(&Config.Notifier.SMTP.Port, "smtp-port", 25, "SMTP server port")
+	flag.StringVar(&Config.Notifier.SMTP.Username, "smtp-username", "", "SMTP username, authentication is disabled if empty")
+	flag.StringVar(&Config.Notifier.SMTP.Password, "smtp-password", "", "SMTP password")
+	flag.StringVar(&Config.Notifier.SMTP.From, "smtp-from", "noreply@recipetor.local", "sender address of emails")
 	flag.StringVar(&Config.Kafka.Brokers, "kafka-brokers", "", "kafka broker list")
+	flag.StringVar(&Config.HTTP.Address, "http-address", ":8080", "address of HTTP server exposing metrics and health checks")
+	flag.StringVar(&Config.Tracing.Exporter, "tracing-exporter", "", "trace exporter: otlp, file or empty to disable")
+	flag.StringVar(&Config.Tracing.OTLPEndpoint, "tracing-otlp-endpoint", "localhost:4317", "OTLP gRPC collector endpoint")
+	flag.StringVar(&Config.Tracing.File, "tracing-file", "traces.json", "file to write traces to with file exporter")
 	flag.Parse()
 
 	err := env.Parse(&Config)
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tony-spark/recipetor-backend/user-service/internal/controller"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/health"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/metrics"
+)
+
+type httpController struct {
+	server *http.Server
+}
+
+func NewController(address string, checker *health.Checker) controller.Controller {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/health/live", checker.LiveHandler())
+	mux.Handle("/health/ready", checker.ReadyHandler())
+
+	return httpController{
+		server: &http.Server{
+			Addr:              address,
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+	}
+}
+
+func (c httpController) Run(ctx context.Context) error {
+	err := c.server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (c httpController) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return c.server.Shutdown(ctx)
+}
@@ -0,0 +1,176 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user/service"
+)
+
+type AccountDeletionWorker struct {
+	userService       service.Service
+	deletionReqReader *kafka.Reader
+	userDeletedWriter *kafka.Writer
+	deletionsWriter   *kafka.Writer
+}
+
+func NewAccountDeletionWorker(userService service.Service, brokers []string) (Worker, error) {
+	deletionReqReader, err := newReader(brokers, "user-service-deletion", TopicAccountDeletionReq)
+	if err != nil {
+		return nil, err
+	}
+	userDeletedWriter := newWriter(brokers, TopicUserDeleted)
+	deletionsWriter := newWriter(brokers, TopicDeletions)
+	return AccountDeletionWorker{
+		userService:       userService,
+		deletionReqReader: deletionReqReader,
+		userDeletedWriter: userDeletedWriter,
+		deletionsWriter:   deletionsWriter,
+	}, nil
+}
+
+func (w AccountDeletionWorker) Name() string {
+	return workerName(w.deletionReqReader)
+}
+
+func (w AccountDeletionWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.AccountDeletionDTO
+		msgCtx, corID, err := readDTO(ctx, w.deletionReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		// password is a secret, so DTO is not logged
+		log.Info().Msgf("got AccountDeletionDTO for user %s", dto.UserID)
+
+		handleMessage(msgCtx, w.deletionReqReader, func() error {
+			var completed bool
+			var err error
+			// users can delete only their own account
+			if !ownRequest(msgCtx, dto.UserID) {
+				err = apperror.ErrForbidden
+			} else {
+				cntx, cancel := context.WithTimeout(msgCtx, 10*time.Second)
+				completed, err = w.userService.DeleteAccount(cntx, dto.UserID, dto.Password)
+				cancel()
+			}
+			deletionDTO := user.UserDeletionDTO{
+				UserID:    dto.UserID,
+				Completed: completed,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to delete account")
+				deletionDTO.Error = err.Error()
+			} else if !completed {
+				// correlation ID is passed along, so final confirmation can be matched with the request
+				write(msgCtx, w.userDeletedWriter, dto.UserID, user.UserDeletedDTO{UserID: dto.UserID}, corID)
+				log.Info().Msgf("sent UserDeletedDTO for user %s", dto.UserID)
+			}
+
+			write(msgCtx, w.deletionsWriter, dto.UserID, deletionDTO, corID)
+			log.Info().Msgf("sent UserDeletionDTO: %+v", deletionDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.deletionsWriter, dto.UserID, user.UserDeletionDTO{
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w AccountDeletionWorker) Stop() error {
+	return closeAll(w.deletionReqReader, w.userDeletedWriter, w.deletionsWriter)
+}
+
+type DeletionAckWorker struct {
+	userService     service.Service
+	acksReader      *kafka.Reader
+	deletionsWriter *kafka.Writer
+}
+
+func NewDeletionAckWorker(userService service.Service, brokers []string) (Worker, error) {
+	acksReader, err := newReader(brokers, "user-service-deletion-ack", TopicDeletionAcks)
+	if err != nil {
+		return nil, err
+	}
+	deletionsWriter := newWriter(brokers, TopicDeletions)
+	return DeletionAckWorker{
+		userService:     userService,
+		acksReader:      acksReader,
+		deletionsWriter: deletionsWriter,
+	}, nil
+}
+
+func (w DeletionAckWorker) Name() string {
+	return workerName(w.acksReader)
+}
+
+func (w DeletionAckWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.UserDeletionAckDTO
+		msgCtx, corID, err := readDTO(ctx, w.acksReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got UserDeletionAckDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.acksReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 10*time.Second)
+			completed, err := w.userService.ConfirmDeletion(cntx, dto)
+			cancel()
+			if err != nil {
+				log.Error().Err(err).Msg("failed to confirm deletion")
+				deletionDTO := user.UserDeletionDTO{
+					UserID: dto.UserID,
+					Error:  err.Error(),
+				}
+				write(msgCtx, w.deletionsWriter, dto.UserID, deletionDTO, corID)
+				log.Info().Msgf("sent UserDeletionDTO: %+v", deletionDTO)
+				return err
+			}
+			if completed {
+				deletionDTO := user.UserDeletionDTO{
+					UserID:    dto.UserID,
+					Completed: true,
+				}
+				write(msgCtx, w.deletionsWriter, dto.UserID, deletionDTO, corID)
+				log.Info().Msgf("sent UserDeletionDTO: %+v", deletionDTO)
+			}
+			return nil
+		}, func(err error) {
+			write(msgCtx, w.deletionsWriter, dto.UserID, user.UserDeletionDTO{
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w DeletionAckWorker) Stop() error {
+	return closeAll(w.acksReader, w.deletionsWriter)
+}
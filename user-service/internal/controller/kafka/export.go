@@ -0,0 +1,179 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user/service"
+)
+
+type ExportWorker struct {
+	userService         service.Service
+	exportReqReader     *kafka.Reader
+	exportStartedWriter *kafka.Writer
+	exportsWriter       *kafka.Writer
+}
+
+func NewExportWorker(userService service.Service, brokers []string) (Worker, error) {
+	exportReqReader, err := newReader(brokers, "user-service-export", TopicExportReq)
+	if err != nil {
+		return nil, err
+	}
+	exportStartedWriter := newWriter(brokers, TopicExportStarted)
+	exportsWriter := newWriter(brokers, TopicExports)
+	return ExportWorker{
+		userService:         userService,
+		exportReqReader:     exportReqReader,
+		exportStartedWriter: exportStartedWriter,
+		exportsWriter:       exportsWriter,
+	}, nil
+}
+
+func (w ExportWorker) Name() string {
+	return workerName(w.exportReqReader)
+}
+
+func (w ExportWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.ExportRequestDTO
+		msgCtx, corID, err := readDTO(ctx, w.exportReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got ExportRequestDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.exportReqReader, func() error {
+			var accepted user.ExportDTO
+			var result *user.ExportDTO
+			var err error
+			// users can export only their own data
+			if !ownRequest(msgCtx, dto.UserID) {
+				err = apperror.ErrForbidden
+			} else {
+				cntx, cancel := context.WithTimeout(msgCtx, 10*time.Second)
+				accepted, result, err = w.userService.StartExport(cntx, dto.UserID)
+				cancel()
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to start export")
+				accepted = user.ExportDTO{
+					UserID: dto.UserID,
+					Error:  err.Error(),
+				}
+			} else if result == nil {
+				// correlation ID is passed along, so archive can be matched with the request
+				write(msgCtx, w.exportStartedWriter, dto.UserID, user.ExportStartedDTO{
+					ExportID: accepted.ExportID,
+					UserID:   dto.UserID,
+				}, corID)
+				log.Info().Msgf("sent ExportStartedDTO for export %s", accepted.ExportID)
+			}
+
+			write(msgCtx, w.exportsWriter, dto.UserID, accepted, corID)
+			log.Info().Msgf("sent ExportDTO: %+v", accepted)
+			if result != nil {
+				write(msgCtx, w.exportsWriter, dto.UserID, *result, corID)
+				// archive holds personal data, so it is not logged
+				log.Info().Msgf("sent archive of export %s", result.ExportID)
+			}
+			return err
+		}, func(err error) {
+			write(msgCtx, w.exportsWriter, dto.UserID, user.ExportDTO{
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w ExportWorker) Stop() error {
+	return closeAll(w.exportReqReader, w.exportStartedWriter, w.exportsWriter)
+}
+
+type ExportPartWorker struct {
+	userService   service.Service
+	partsReader   *kafka.Reader
+	exportsWriter *kafka.Writer
+}
+
+func NewExportPartWorker(userService service.Service, brokers []string) (Worker, error) {
+	partsReader, err := newReader(brokers, "user-service-export-part", TopicExportParts)
+	if err != nil {
+		return nil, err
+	}
+	exportsWriter := newWriter(brokers, TopicExports)
+	return ExportPartWorker{
+		userService:   userService,
+		partsReader:   partsReader,
+		exportsWriter: exportsWriter,
+	}, nil
+}
+
+func (w ExportPartWorker) Name() string {
+	return workerName(w.partsReader)
+}
+
+func (w ExportPartWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.ExportPartDTO
+		msgCtx, corID, err := readDTO(ctx, w.partsReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		// data is personal, so only its source is logged
+		log.Info().Msgf("got ExportPartDTO of export %s from %s", dto.ExportID, dto.Service)
+
+		handleMessage(msgCtx, w.partsReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 10*time.Second)
+			result, err := w.userService.AddExportPart(cntx, dto)
+			cancel()
+			if err != nil {
+				log.Error().Err(err).Msg("failed to add export part")
+				result = &user.ExportDTO{
+					ExportID: dto.ExportID,
+					UserID:   dto.UserID,
+					Error:    err.Error(),
+				}
+			}
+			if result != nil {
+				write(msgCtx, w.exportsWriter, result.UserID, *result, corID)
+				log.Info().Msgf("sent result of export %s", result.ExportID)
+			}
+			return err
+		}, func(err error) {
+			write(msgCtx, w.exportsWriter, dto.UserID, user.ExportDTO{
+				ExportID: dto.ExportID,
+				UserID:   dto.UserID,
+				Error:    err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w ExportPartWorker) Stop() error {
+	return closeAll(w.partsReader, w.exportsWriter)
+}
@@ -2,8 +2,8 @@ package kafka
 
 import (
 	"context"
-	"golang.org/x/sync/errgroup"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/controller"
@@ -12,14 +12,20 @@ import (
 
 type kafkaController struct {
 	workers []Worker
+	brokers []string
+	states  *workerStates
+	// stop is closed when controller is stopped, so failed workers are not restarted
+	stop     chan struct{}
+	stopOnce *sync.Once
 }
 
 type Worker interface {
+	Name() string
 	Process(ctx context.Context) error
 	Stop() error
 }
 
-func NewController(userService service.Service, kafkaBrokerURLs string) (controller.Controller, error) {
+func NewController(userService service.Service, kafkaBrokerURLs string) (controller.MonitoredController, error) {
 	brokers := strings.Split(kafkaBrokerURLs, ",")
 
 	var workers []Worker
@@ -35,24 +41,105 @@ func NewController(userService service.Service, kafkaBrokerURLs string) (control
 	}
 	workers = append(workers, loginWorker)
 
+	passwordResetWorker, err := NewPasswordResetWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, passwordResetWorker)
+
+	passwordResetConfirmWorker, err := NewPasswordResetConfirmWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, passwordResetConfirmWorker)
+
+	passwordChangeWorker, err := NewPasswordChangeWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, passwordChangeWorker)
+
+	emailVerificationWorker, err := NewEmailVerificationWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, emailVerificationWorker)
+
+	emailVerificationConfirmWorker, err := NewEmailVerificationConfirmWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, emailVerificationConfirmWorker)
+
+	roleWorker, err := NewRoleWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, roleWorker)
+
+	accountDeletionWorker, err := NewAccountDeletionWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, accountDeletionWorker)
+
+	deletionAckWorker, err := NewDeletionAckWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, deletionAckWorker)
+
+	exportWorker, err := NewExportWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, exportWorker)
+
+	exportPartWorker, err := NewExportPartWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, exportPartWorker)
+
+	findProfileWorker, err := NewFindProfileWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, findProfileWorker)
+
+	updateProfileWorker, err := NewUpdateProfileWorker(userService, brokers)
+	if err != nil {
+		return nil, err
+	}
+	workers = append(workers, updateProfileWorker)
+
 	return kafkaController{
-		workers: workers,
+		workers:  workers,
+		brokers:  brokers,
+		states:   newWorkerStates(workers),
+		stop:     make(chan struct{}),
+		stopOnce: &sync.Once{},
 	}, nil
 }
 
+// Run runs every worker under supervision, failed workers are restarted without affecting others
 func (k kafkaController) Run(ctx context.Context) error {
-	group, ctx := errgroup.WithContext(ctx)
-
+	var wg sync.WaitGroup
 	for _, w := range k.workers {
-		worker := w
-		group.Go(func() error {
-			return worker.Process(ctx)
-		})
+		wg.Add(1)
+		go func(worker Worker) {
+			defer wg.Done()
+			k.supervise(ctx, worker)
+		}(w)
 	}
-	return group.Wait()
+	wg.Wait()
+	return nil
 }
 
 func (k kafkaController) Stop() error {
+	k.stopOnce.Do(func() {
+		close(k.stop)
+	})
 	var result error
 	for _, w := range k.workers {
 		err := w.Stop()
@@ -62,3 +149,22 @@ func (k kafkaController) Stop() error {
 	}
 	return result
 }
+
+// Live fails if any worker has stopped processing messages and will not be restarted
+func (k kafkaController) Live(ctx context.Context) error {
+	return k.states.check(WorkerStarting, WorkerRunning, WorkerRestarting)
+}
+
+// Ready fails if any worker is not running or none of kafka brokers is reachable
+func (k kafkaController) Ready(ctx context.Context) error {
+	var result error
+	err := k.states.check(WorkerRunning)
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
+	err = pingBrokers(ctx, k.brokers)
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
+	return result
+}
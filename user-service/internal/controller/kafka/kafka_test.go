@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/controller"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/notifier"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/random"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/user/service"
@@ -41,7 +42,7 @@ func (suite *ControllerTestSuite) TestController() {
 	suite.Run("user registration and login", func() {
 		registerDTO := suite.randomCreateUser()
 		corID := generateCorrelationID()
-		write(suite.registrationsWriter, registerDTO.Email, registerDTO, corID)
+		write(context.Background(), suite.registrationsWriter, registerDTO.Email, registerDTO, corID)
 
 		{
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -62,7 +63,7 @@ func (suite *ControllerTestSuite) TestController() {
 		}
 
 		var loginDTO = registerDTO
-		write(suite.loginsWriter, loginDTO.Email, loginDTO, corID)
+		write(context.Background(), suite.loginsWriter, loginDTO.Email, loginDTO, corID)
 
 		{
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -106,7 +107,7 @@ func (suite *ControllerTestSuite) SetupSuite() {
 		stor, suite.cleanupFunc, err = mongodb.NewTestStorage(dsn, "test")
 		suite.Require().NoError(err)
 
-		suite.controller, err = NewController(service.NewService(stor), kafkaBroker)
+		suite.controller, err = NewController(service.NewService(stor, notifier.NewLogNotifier(), service.DefaultConfig), kafkaBroker)
 		suite.Require().NoError(err)
 	}
 
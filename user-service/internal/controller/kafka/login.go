@@ -30,6 +30,10 @@ func NewLoginWorker(userService service.Service, brokers []string) (Worker, erro
 	}, nil
 }
 
+func (w LoginWorker) Name() string {
+	return workerName(w.loginReqReader)
+}
+
 func (w LoginWorker) Process(ctx context.Context) error {
 	for {
 		select {
@@ -39,7 +43,7 @@ func (w LoginWorker) Process(ctx context.Context) error {
 		}
 
 		var loginDTO user.LoginDTO
-		corID, err := readDTO(ctx, w.loginReqReader, &loginDTO)
+		msgCtx, corID, err := readDTO(ctx, w.loginReqReader, &loginDTO)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				return err
@@ -48,20 +52,28 @@ func (w LoginWorker) Process(ctx context.Context) error {
 		}
 		log.Info().Msgf("got LoginDTO: %+v", loginDTO)
 
-		cntx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		usr, err := w.userService.GetByEmailAndPassword(cntx, loginDTO.Email, loginDTO.Password)
-		userLoginDTO := user.UserLoginDTO{
-			User:  usr,
-			Email: loginDTO.Email,
-		}
-		if err != nil {
-			log.Error().Err(err).Msg("failed to login user")
-			userLoginDTO.Error = err.Error()
-		}
-		cancel()
+		handleMessage(msgCtx, w.loginReqReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			usr, err := w.userService.GetByEmailAndPassword(cntx, loginDTO.Email, loginDTO.Password, loginDTO.ClientID)
+			cancel()
+			userLoginDTO := user.UserLoginDTO{
+				User:  usr,
+				Email: loginDTO.Email,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to login user")
+				userLoginDTO.Error = err.Error()
+			}
 
-		write(w.loginsWriter, loginDTO.Email, userLoginDTO, corID)
-		log.Info().Msgf("sent UserLoginDTO: %+v", userLoginDTO)
+			write(msgCtx, w.loginsWriter, loginDTO.Email, userLoginDTO, corID)
+			log.Info().Msgf("sent UserLoginDTO: %+v", userLoginDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.loginsWriter, loginDTO.Email, user.UserLoginDTO{
+				Email: loginDTO.Email,
+				Error: err.Error(),
+			}, corID)
+		})
 	}
 }
 
@@ -0,0 +1,219 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user/service"
+)
+
+type PasswordResetWorker struct {
+	userService          service.Service
+	resetReqReader       *kafka.Reader
+	passwordResetsWriter *kafka.Writer
+}
+
+func NewPasswordResetWorker(userService service.Service, brokers []string) (Worker, error) {
+	resetReqReader, err := newReader(brokers, "user-service-password-reset", TopicPasswordResetReq)
+	if err != nil {
+		return nil, err
+	}
+	passwordResetsWriter := newWriter(brokers, TopicPasswordResets)
+	return PasswordResetWorker{
+		userService:          userService,
+		resetReqReader:       resetReqReader,
+		passwordResetsWriter: passwordResetsWriter,
+	}, nil
+}
+
+func (w PasswordResetWorker) Name() string {
+	return workerName(w.resetReqReader)
+}
+
+func (w PasswordResetWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.PasswordResetRequestDTO
+		msgCtx, corID, err := readDTO(ctx, w.resetReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got PasswordResetRequestDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.resetReqReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 10*time.Second)
+			err := w.userService.RequestPasswordReset(cntx, dto.Email)
+			cancel()
+			resetDTO := user.PasswordResetDTO{
+				Email: dto.Email,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to request password reset")
+				resetDTO.Error = err.Error()
+			}
+
+			write(msgCtx, w.passwordResetsWriter, dto.Email, resetDTO, corID)
+			log.Info().Msgf("sent PasswordResetDTO: %+v", resetDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.passwordResetsWriter, dto.Email, user.PasswordResetDTO{
+				Email: dto.Email,
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w PasswordResetWorker) Stop() error {
+	return closeAll(w.resetReqReader, w.passwordResetsWriter)
+}
+
+type PasswordResetConfirmWorker struct {
+	userService          service.Service
+	confirmReqReader     *kafka.Reader
+	passwordResetsWriter *kafka.Writer
+}
+
+func NewPasswordResetConfirmWorker(userService service.Service, brokers []string) (Worker, error) {
+	confirmReqReader, err := newReader(brokers, "user-service-password-reset-confirm", TopicPasswordResetConfirmReq)
+	if err != nil {
+		return nil, err
+	}
+	passwordResetsWriter := newWriter(brokers, TopicPasswordResets)
+	return PasswordResetConfirmWorker{
+		userService:          userService,
+		confirmReqReader:     confirmReqReader,
+		passwordResetsWriter: passwordResetsWriter,
+	}, nil
+}
+
+func (w PasswordResetConfirmWorker) Name() string {
+	return workerName(w.confirmReqReader)
+}
+
+func (w PasswordResetConfirmWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.PasswordResetConfirmDTO
+		msgCtx, corID, err := readDTO(ctx, w.confirmReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		// token and password are secrets, so DTO is not logged
+		log.Info().Msg("got PasswordResetConfirmDTO")
+
+		handleMessage(msgCtx, w.confirmReqReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			err := w.userService.ResetPassword(cntx, dto.Token, dto.Password)
+			cancel()
+			resetDTO := user.PasswordResetDTO{}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to reset password")
+				resetDTO.Error = err.Error()
+			}
+
+			write(msgCtx, w.passwordResetsWriter, "", resetDTO, corID)
+			log.Info().Msgf("sent PasswordResetDTO: %+v", resetDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.passwordResetsWriter, "", user.PasswordResetDTO{
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w PasswordResetConfirmWorker) Stop() error {
+	return closeAll(w.confirmReqReader, w.passwordResetsWriter)
+}
+
+type PasswordChangeWorker struct {
+	userService           service.Service
+	changeReqReader       *kafka.Reader
+	passwordChangesWriter *kafka.Writer
+}
+
+func NewPasswordChangeWorker(userService service.Service, brokers []string) (Worker, error) {
+	changeReqReader, err := newReader(brokers, "user-service-password-change", TopicPasswordChangeReq)
+	if err != nil {
+		return nil, err
+	}
+	passwordChangesWriter := newWriter(brokers, TopicPasswordChanges)
+	return PasswordChangeWorker{
+		userService:           userService,
+		changeReqReader:       changeReqReader,
+		passwordChangesWriter: passwordChangesWriter,
+	}, nil
+}
+
+func (w PasswordChangeWorker) Name() string {
+	return workerName(w.changeReqReader)
+}
+
+func (w PasswordChangeWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.PasswordChangeDTO
+		msgCtx, corID, err := readDTO(ctx, w.changeReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		// passwords are secrets, so DTO is not logged
+		log.Info().Msgf("got PasswordChangeDTO for user %s", dto.UserID)
+
+		handleMessage(msgCtx, w.changeReqReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			err := w.userService.ChangePassword(cntx, dto.UserID, dto.OldPassword, dto.NewPassword)
+			cancel()
+			changedDTO := user.PasswordChangedDTO{
+				UserID: dto.UserID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to change password")
+				changedDTO.Error = err.Error()
+			}
+
+			write(msgCtx, w.passwordChangesWriter, dto.UserID, changedDTO, corID)
+			log.Info().Msgf("sent PasswordChangedDTO: %+v", changedDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.passwordChangesWriter, dto.UserID, user.PasswordChangedDTO{
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w PasswordChangeWorker) Stop() error {
+	return closeAll(w.changeReqReader, w.passwordChangesWriter)
+}
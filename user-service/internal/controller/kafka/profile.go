@@ -0,0 +1,173 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user/service"
+)
+
+type FindProfileWorker struct {
+	userService      service.Service
+	profileReqReader *kafka.Reader
+	profilesWriter   *kafka.Writer
+}
+
+func NewFindProfileWorker(userService service.Service, brokers []string) (Worker, error) {
+	profileReqReader, err := newReader(brokers, "user-service-profile", TopicProfileReq)
+	if err != nil {
+		return nil, err
+	}
+	profilesWriter := newWriter(brokers, TopicProfiles)
+	return FindProfileWorker{
+		userService:      userService,
+		profileReqReader: profileReqReader,
+		profilesWriter:   profilesWriter,
+	}, nil
+}
+
+func (w FindProfileWorker) Name() string {
+	return workerName(w.profileReqReader)
+}
+
+func (w FindProfileWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.FindProfileDTO
+		msgCtx, corID, err := readDTO(ctx, w.profileReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got FindProfileDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.profileReqReader, func() error {
+			profileDTO := user.ProfileDTO{
+				UserID: dto.UserID,
+				Handle: dto.Handle,
+			}
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			var err error
+			if len(dto.UserID) > 0 {
+				profileDTO.Profile, err = w.userService.GetProfile(cntx, dto.UserID)
+			} else {
+				var u user.User
+				u, err = w.userService.GetByHandle(cntx, dto.Handle)
+				profileDTO.UserID = u.ID
+				profileDTO.Profile = u.Profile
+			}
+			cancel()
+			if err != nil {
+				log.Error().Err(err).Msg("failed to find profile")
+				profileDTO.Profile = user.Profile{}
+				profileDTO.Error = err.Error()
+			}
+
+			write(msgCtx, w.profilesWriter, profileDTO.UserID, profileDTO, corID)
+			log.Info().Msgf("sent ProfileDTO: %+v", profileDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.profilesWriter, dto.UserID, user.ProfileDTO{
+				UserID: dto.UserID,
+				Handle: dto.Handle,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w FindProfileWorker) Stop() error {
+	return closeAll(w.profileReqReader, w.profilesWriter)
+}
+
+type UpdateProfileWorker struct {
+	userService     service.Service
+	updateReqReader *kafka.Reader
+	profilesWriter  *kafka.Writer
+}
+
+func NewUpdateProfileWorker(userService service.Service, brokers []string) (Worker, error) {
+	updateReqReader, err := newReader(brokers, "user-service-profile-update", TopicProfileUpdateReq)
+	if err != nil {
+		return nil, err
+	}
+	profilesWriter := newWriter(brokers, TopicProfiles)
+	return UpdateProfileWorker{
+		userService:     userService,
+		updateReqReader: updateReqReader,
+		profilesWriter:  profilesWriter,
+	}, nil
+}
+
+func (w UpdateProfileWorker) Name() string {
+	return workerName(w.updateReqReader)
+}
+
+func (w UpdateProfileWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.UpdateProfileDTO
+		msgCtx, corID, err := readDTO(ctx, w.updateReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got UpdateProfileDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.updateReqReader, func() error {
+			var profile user.Profile
+			var err error
+			// users can edit only their own profile
+			if !ownRequest(msgCtx, dto.UserID) {
+				err = apperror.ErrForbidden
+			} else {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				profile, err = w.userService.UpdateProfile(cntx, dto.UserID, dto.Profile)
+				cancel()
+			}
+			profileDTO := user.ProfileDTO{
+				UserID: dto.UserID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to update profile")
+				profileDTO.Error = err.Error()
+			} else {
+				profileDTO.Handle = profile.Handle
+				profileDTO.Profile = profile
+			}
+
+			write(msgCtx, w.profilesWriter, dto.UserID, profileDTO, corID)
+			log.Info().Msgf("sent ProfileDTO: %+v", profileDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.profilesWriter, dto.UserID, user.ProfileDTO{
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w UpdateProfileWorker) Stop() error {
+	return closeAll(w.updateReqReader, w.profilesWriter)
+}
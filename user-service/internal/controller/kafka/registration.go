@@ -31,6 +31,10 @@ func NewRegistrationWorker(userService service.Service, brokers []string) (Worke
 	}, nil
 }
 
+func (w RegistrationWorker) Name() string {
+	return workerName(w.regReqReader)
+}
+
 func (w RegistrationWorker) Process(ctx context.Context) error {
 	for {
 		select {
@@ -40,7 +44,7 @@ func (w RegistrationWorker) Process(ctx context.Context) error {
 		}
 
 		var dto user.CreateUserDTO
-		corID, err := readDTO(ctx, w.regReqReader, &dto)
+		msgCtx, corID, err := readDTO(ctx, w.regReqReader, &dto)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				return err
@@ -49,20 +53,28 @@ func (w RegistrationWorker) Process(ctx context.Context) error {
 		}
 		log.Info().Msgf("got CreateUserDTO: %+v", dto)
 
-		cntx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		id, err := w.userService.Create(cntx, dto)
-		registrationDTO := user.UserRegistrationDTO{
-			ID:    id,
-			Email: dto.Email,
-		}
-		if err != nil {
-			log.Error().Err(err).Msg("failed to create user")
-			registrationDTO.Error = err.Error()
-		}
-		cancel()
+		handleMessage(msgCtx, w.regReqReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			id, err := w.userService.Create(cntx, dto)
+			cancel()
+			registrationDTO := user.UserRegistrationDTO{
+				ID:    id,
+				Email: dto.Email,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to create user")
+				registrationDTO.Error = err.Error()
+			}
 
-		write(w.registrationsWriter, dto.Email, registrationDTO, corID)
-		log.Info().Msgf("sent UserRegistrationDTO: %+v", registrationDTO)
+			write(msgCtx, w.registrationsWriter, dto.Email, registrationDTO, corID)
+			log.Info().Msgf("sent UserRegistrationDTO: %+v", registrationDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.registrationsWriter, dto.Email, user.UserRegistrationDTO{
+				Email: dto.Email,
+				Error: err.Error(),
+			}, corID)
+		})
 	}
 }
 
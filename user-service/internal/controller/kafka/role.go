@@ -0,0 +1,91 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user/service"
+)
+
+type RoleWorker struct {
+	userService   service.Service
+	roleReqReader *kafka.Reader
+	rolesWriter   *kafka.Writer
+}
+
+func NewRoleWorker(userService service.Service, brokers []string) (Worker, error) {
+	roleReqReader, err := newReader(brokers, "user-service-role", TopicRoleReq)
+	if err != nil {
+		return nil, err
+	}
+	rolesWriter := newWriter(brokers, TopicRoles)
+	return RoleWorker{
+		userService:   userService,
+		roleReqReader: roleReqReader,
+		rolesWriter:   rolesWriter,
+	}, nil
+}
+
+func (w RoleWorker) Name() string {
+	return workerName(w.roleReqReader)
+}
+
+func (w RoleWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.RoleChangeDTO
+		msgCtx, corID, err := readDTO(ctx, w.roleReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got RoleChangeDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.roleReqReader, func() error {
+			var err error
+			// the first administrator is appointed by configuration, not by request
+			if role, _ := header(msgCtx, KeyUserRole); role != user.RoleAdmin {
+				err = apperror.ErrForbidden
+			} else {
+				cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+				err = w.userService.SetRole(cntx, dto.UserID, dto.Role)
+				cancel()
+			}
+			roleDTO := user.UserRoleDTO{
+				UserID: dto.UserID,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to set role")
+				roleDTO.Error = err.Error()
+			} else {
+				roleDTO.Role = dto.Role
+			}
+
+			write(msgCtx, w.rolesWriter, dto.UserID, roleDTO, corID)
+			log.Info().Msgf("sent UserRoleDTO: %+v", roleDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.rolesWriter, dto.UserID, user.UserRoleDTO{
+				UserID: dto.UserID,
+				Error:  err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w RoleWorker) Stop() error {
+	return closeAll(w.roleReqReader, w.rolesWriter)
+}
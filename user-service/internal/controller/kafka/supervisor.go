@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/metrics"
+)
+
+const (
+	restartBackoffMin = time.Second
+	restartBackoffMax = time.Minute
+	// restartBackoffReset is how long worker has to run after restart for backoff to start over
+	restartBackoffReset = time.Minute
+)
+
+var errWorkerExited = errors.New("worker exited unexpectedly")
+
+// supervise runs worker until ctx is cancelled or controller is stopped, restarting it with
+// exponential backoff whenever it fails or panics
+func (k kafkaController) supervise(ctx context.Context, worker Worker) {
+	name := worker.Name()
+	backoff := restartBackoffMin
+	for {
+		started := time.Now()
+		k.states.set(name, WorkerRunning, nil)
+		err := recoverPanic(name, func() error {
+			return worker.Process(ctx)
+		})
+		if ctx.Err() != nil || k.stopping() {
+			k.states.set(name, WorkerStopped, err)
+			log.Info().Str("worker", name).Msg("worker stopped")
+			return
+		}
+		if err == nil {
+			err = errWorkerExited
+		}
+
+		if time.Since(started) > restartBackoffReset {
+			backoff = restartBackoffMin
+		}
+		k.states.set(name, WorkerRestarting, err)
+		metrics.WorkerRestarted(name)
+		log.Error().Err(err).Str("worker", name).Msgf("worker failed, restarting in %s", backoff)
+
+		select {
+		case <-ctx.Done():
+			k.states.set(name, WorkerStopped, err)
+			return
+		case <-k.stop:
+			k.states.set(name, WorkerStopped, err)
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > restartBackoffMax {
+			backoff = restartBackoffMax
+		}
+	}
+}
+
+func (k kafkaController) stopping() bool {
+	select {
+	case <-k.stop:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,8 +1,29 @@
 package kafka
 
 const (
-	TopicRegistrationReq = "user.registration.req"
-	TopicLoginReq        = "user.login.req"
-	TopicRegistrations   = "user.registrations"
-	TopicLogins          = "user.logins"
+	TopicRegistrationReq             = "user.registration.req"
+	TopicLoginReq                    = "user.login.req"
+	TopicPasswordResetReq            = "user.password.reset.req"
+	TopicPasswordResetConfirmReq     = "user.password.reset.confirm.req"
+	TopicPasswordChangeReq           = "user.password.change.req"
+	TopicEmailVerificationReq        = "user.verification.req"
+	TopicEmailVerificationConfirmReq = "user.verification.confirm.req"
+	TopicRoleReq                     = "user.role.req"
+	TopicAccountDeletionReq          = "user.deletion.req"
+	TopicDeletionAcks                = "user.deletion.acks"
+	TopicExportReq                   = "user.export.req"
+	TopicExportParts                 = "user.export.parts"
+	TopicProfileReq                  = "user.profile.req"
+	TopicProfileUpdateReq            = "user.profile.update.req"
+	TopicRegistrations               = "user.registrations"
+	TopicLogins                      = "user.logins"
+	TopicEmailVerifications          = "user.verifications"
+	TopicPasswordChanges             = "user.password.changes"
+	TopicPasswordResets              = "user.password.resets"
+	TopicRoles                       = "user.roles"
+	TopicUserDeleted                 = "user.deleted"
+	TopicDeletions                   = "user.deletions"
+	TopicExportStarted               = "user.export.started"
+	TopicExports                     = "user.exports"
+	TopicProfiles                    = "user.profiles"
 )
@@ -0,0 +1,149 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user/service"
+)
+
+type EmailVerificationWorker struct {
+	userService           service.Service
+	verificationReqReader *kafka.Reader
+	verificationsWriter   *kafka.Writer
+}
+
+func NewEmailVerificationWorker(userService service.Service, brokers []string) (Worker, error) {
+	verificationReqReader, err := newReader(brokers, "user-service-email-verification", TopicEmailVerificationReq)
+	if err != nil {
+		return nil, err
+	}
+	verificationsWriter := newWriter(brokers, TopicEmailVerifications)
+	return EmailVerificationWorker{
+		userService:           userService,
+		verificationReqReader: verificationReqReader,
+		verificationsWriter:   verificationsWriter,
+	}, nil
+}
+
+func (w EmailVerificationWorker) Name() string {
+	return workerName(w.verificationReqReader)
+}
+
+func (w EmailVerificationWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.EmailVerificationRequestDTO
+		msgCtx, corID, err := readDTO(ctx, w.verificationReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		log.Info().Msgf("got EmailVerificationRequestDTO: %+v", dto)
+
+		handleMessage(msgCtx, w.verificationReqReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 10*time.Second)
+			err := w.userService.RequestEmailVerification(cntx, dto.Email)
+			cancel()
+			verificationDTO := user.EmailVerificationDTO{
+				Email: dto.Email,
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to request email verification")
+				verificationDTO.Error = err.Error()
+			}
+
+			write(msgCtx, w.verificationsWriter, dto.Email, verificationDTO, corID)
+			log.Info().Msgf("sent EmailVerificationDTO: %+v", verificationDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.verificationsWriter, dto.Email, user.EmailVerificationDTO{
+				Email: dto.Email,
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w EmailVerificationWorker) Stop() error {
+	return closeAll(w.verificationReqReader, w.verificationsWriter)
+}
+
+type EmailVerificationConfirmWorker struct {
+	userService         service.Service
+	confirmReqReader    *kafka.Reader
+	verificationsWriter *kafka.Writer
+}
+
+func NewEmailVerificationConfirmWorker(userService service.Service, brokers []string) (Worker, error) {
+	confirmReqReader, err := newReader(brokers, "user-service-email-verification-confirm", TopicEmailVerificationConfirmReq)
+	if err != nil {
+		return nil, err
+	}
+	verificationsWriter := newWriter(brokers, TopicEmailVerifications)
+	return EmailVerificationConfirmWorker{
+		userService:         userService,
+		confirmReqReader:    confirmReqReader,
+		verificationsWriter: verificationsWriter,
+	}, nil
+}
+
+func (w EmailVerificationConfirmWorker) Name() string {
+	return workerName(w.confirmReqReader)
+}
+
+func (w EmailVerificationConfirmWorker) Process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var dto user.EmailVerificationConfirmDTO
+		msgCtx, corID, err := readDTO(ctx, w.confirmReqReader, &dto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		// token is a secret, so DTO is not logged
+		log.Info().Msg("got EmailVerificationConfirmDTO")
+
+		handleMessage(msgCtx, w.confirmReqReader, func() error {
+			cntx, cancel := context.WithTimeout(msgCtx, 5*time.Second)
+			err := w.userService.VerifyEmail(cntx, dto.Token)
+			cancel()
+			verificationDTO := user.EmailVerificationDTO{}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to verify email")
+				verificationDTO.Error = err.Error()
+			}
+
+			write(msgCtx, w.verificationsWriter, "", verificationDTO, corID)
+			log.Info().Msgf("sent EmailVerificationDTO: %+v", verificationDTO)
+			return err
+		}, func(err error) {
+			write(msgCtx, w.verificationsWriter, "", user.EmailVerificationDTO{
+				Error: err.Error(),
+			}, corID)
+		})
+	}
+}
+
+func (w EmailVerificationConfirmWorker) Stop() error {
+	return closeAll(w.confirmReqReader, w.verificationsWriter)
+}
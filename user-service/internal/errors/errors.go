@@ -5,5 +5,16 @@ import (
 )
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("duplicate")
+	// ErrInvalidCredentials is returned for any failed login, so it does not reveal whether account exists
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrInvalidToken is returned for unknown, used or expired token
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrWeakPassword is returned for password rejected by password policy
+	ErrWeakPassword = errors.New("weak password")
+	// ErrForbidden is returned when user role does not allow the request
+	ErrForbidden = errors.New("forbidden")
+	// ErrInvalidProfile is returned for profile with invalid field values
+	ErrInvalidProfile = errors.New("invalid profile")
 )
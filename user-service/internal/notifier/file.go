@@ -0,0 +1,59 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+type logNotifier struct {
+}
+
+// NewLogNotifier creates notifier writing messages to log, meant for local testing
+func NewLogNotifier() Notifier {
+	return logNotifier{}
+}
+
+func (n logNotifier) Send(ctx context.Context, msg Message) error {
+	log.Info().Msgf("notification to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+type fileNotifier struct {
+	mu   *sync.Mutex
+	path string
+}
+
+// NewFileNotifier creates notifier appending messages to file as JSON lines, meant for local testing
+func NewFileNotifier(path string) Notifier {
+	return fileNotifier{
+		mu:   &sync.Mutex{},
+		path: path,
+	}
+}
+
+func (n fileNotifier) Send(ctx context.Context, msg Message) error {
+	bs, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("could not marshal notification: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open notifications file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(bs, '\n'))
+	if err != nil {
+		return fmt.Errorf("could not write notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	SenderLog  = "log"
+	SenderFile = "file"
+	SenderSMTP = "smtp"
+)
+
+// Message is a notification sent to user
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPConfig holds settings of SMTP sender
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// New creates notifier using given sender: SenderLog, SenderFile or SenderSMTP
+func New(sender string, file string, smtpConfig SMTPConfig) (Notifier, error) {
+	switch sender {
+	case SenderLog:
+		return NewLogNotifier(), nil
+	case SenderFile:
+		return NewFileNotifier(file), nil
+	case SenderSMTP:
+		return NewSMTPNotifier(smtpConfig), nil
+	default:
+		return nil, fmt.Errorf("unknown notification sender %q", sender)
+	}
+}
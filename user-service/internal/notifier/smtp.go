@@ -0,0 +1,55 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+type smtpNotifier struct {
+	config SMTPConfig
+}
+
+// NewSMTPNotifier creates notifier sending messages by email
+func NewSMTPNotifier(config SMTPConfig) Notifier {
+	return smtpNotifier{
+		config: config,
+	}
+}
+
+func (n smtpNotifier) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port))
+
+	var auth smtp.Auth
+	if len(n.config.Username) > 0 {
+		auth = smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + n.config.From + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	// net/smtp does not support context, so sending is abandoned rather than interrupted on cancel
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(addr, auth, n.config.From, []string{msg.To}, []byte(b.String()))
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("could not send email: %w", err)
+		}
+		return nil
+	}
+}
@@ -1,12 +1,55 @@
 package user
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
 	ID           string    `json:"id" bson:"_id,omitempty"`
 	Email        string    `json:"email" bson:"email,omitempty"`
 	Password     string    `json:"-" bson:"password,omitempty"`
 	RegisteredAt time.Time `json:"registered_at" bson:"registered_at,omitempty"`
+	// Verified is set once user confirms owning email, unverified users can not publish recipes
+	Verified bool    `json:"verified" bson:"verified"`
+	Role     string  `json:"role" bson:"role,omitempty"`
+	Profile  Profile `json:"profile" bson:"profile,omitempty"`
+}
+
+// Profile is public information about user, editable by user
+type Profile struct {
+	// Handle is unique public name of user, case-insensitive
+	Handle      string `json:"handle,omitempty" bson:"handle,omitempty"`
+	DisplayName string `json:"display_name,omitempty" bson:"display_name,omitempty"`
+	Bio         string `json:"bio,omitempty" bson:"bio,omitempty"`
+	// Avatar is reference to avatar image, e.g. its URL
+	Avatar string `json:"avatar,omitempty" bson:"avatar,omitempty"`
+	// Language is preferred language as ISO 639-1 code with optional region, e.g. ru or en-US
+	Language string `json:"language,omitempty" bson:"language,omitempty"`
+	// Units is preferred unit system, one of Units* constants
+	Units string `json:"units,omitempty" bson:"units,omitempty"`
+}
+
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// Roles grant permissions in other services, they are passed along with requests in user_role header
+const (
+	RoleUser = "user"
+	// RoleEditor can curate ingredient catalog
+	RoleEditor = "editor"
+	// RoleAdmin can also assign roles
+	RoleAdmin = "admin"
+)
+
+func ValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleEditor, RoleAdmin:
+		return true
+	}
+	return false
 }
 
 type CreateUserDTO struct {
@@ -14,7 +57,66 @@ type CreateUserDTO struct {
 	Password string `json:"password"`
 }
 
-type LoginDTO = CreateUserDTO
+type LoginDTO struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// ClientID identifies client making login attempts, e.g. its IP address
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// LoginAttempts tracks failed logins by account or client
+type LoginAttempts struct {
+	Key         string    `bson:"_id"`
+	Failures    int       `bson:"failures"`
+	LastFailure time.Time `bson:"last_failure"`
+	LockedUntil time.Time `bson:"locked_until,omitempty"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
+
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+)
+
+// Token is a single-use secret sent to user, only its hash is stored
+type Token struct {
+	Hash      string    `bson:"_id"`
+	UserID    string    `bson:"user_id"`
+	Purpose   string    `bson:"purpose"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// Deletion tracks deletion of user account until every service holding user data confirms it,
+// email is kept only to send the final confirmation
+type Deletion struct {
+	UserID      string    `bson:"_id"`
+	Email       string    `bson:"email,omitempty"`
+	RequestedAt time.Time `bson:"requested_at"`
+	// Pending lists services which have not confirmed deletion yet
+	Pending     []string  `bson:"pending"`
+	Error       string    `bson:"error,omitempty"`
+	CompletedAt time.Time `bson:"completed_at,omitempty"`
+}
+
+// Export collects parts of user data export contributed by services until all of them are received
+type Export struct {
+	ID          string    `bson:"_id,omitempty"`
+	UserID      string    `bson:"user_id"`
+	RequestedAt time.Time `bson:"requested_at"`
+	// Pending lists services which have not sent their part yet
+	Pending []string `bson:"pending"`
+	// Parts holds JSON data sent by services
+	Parts     map[string]string `bson:"parts,omitempty"`
+	ExpiresAt time.Time         `bson:"expires_at"`
+}
+
+// Archive is the exported data of user
+type Archive struct {
+	ExportedAt time.Time `json:"exported_at"`
+	User       User      `json:"user"`
+	// Services holds data of user by service which holds it
+	Services map[string]json.RawMessage `json:"services"`
+}
 
 type UserRegistrationDTO struct {
 	ID    string `json:"user_id,omitempty"`
@@ -27,3 +129,124 @@ type UserLoginDTO struct {
 	Email string `json:"email"`
 	Error string `json:"error,omitempty"`
 }
+
+type PasswordResetRequestDTO struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirmDTO struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type PasswordResetDTO struct {
+	Email string `json:"email,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type EmailVerificationRequestDTO struct {
+	Email string `json:"email"`
+}
+
+type EmailVerificationConfirmDTO struct {
+	Token string `json:"token"`
+}
+
+type EmailVerificationDTO struct {
+	Email string `json:"email,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type PasswordChangeDTO struct {
+	UserID      string `json:"user_id"`
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type PasswordChangedDTO struct {
+	UserID string `json:"user_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type RoleChangeDTO struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+type UserRoleDTO struct {
+	UserID string `json:"user_id,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type AccountDeletionDTO struct {
+	UserID   string `json:"user_id"`
+	Password string `json:"password"`
+}
+
+// UserDeletedDTO is published once user is deleted, services holding user data must remove it
+// and reply with UserDeletionAckDTO
+type UserDeletedDTO struct {
+	UserID string `json:"user_id"`
+}
+
+type UserDeletionAckDTO struct {
+	UserID  string `json:"user_id"`
+	Service string `json:"service"`
+	Error   string `json:"error,omitempty"`
+}
+
+// UserDeletionDTO is sent when deletion is accepted and again when every service has deleted user data
+type UserDeletionDTO struct {
+	UserID    string `json:"user_id,omitempty"`
+	Completed bool   `json:"completed"`
+	Error     string `json:"error,omitempty"`
+}
+
+type ExportRequestDTO struct {
+	UserID string `json:"user_id"`
+}
+
+// ExportStartedDTO is published when export is requested, services holding user data must reply
+// with ExportPartDTO
+type ExportStartedDTO struct {
+	ExportID string `json:"export_id"`
+	UserID   string `json:"user_id"`
+}
+
+type ExportPartDTO struct {
+	ExportID string          `json:"export_id"`
+	UserID   string          `json:"user_id"`
+	Service  string          `json:"service"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// ExportDTO is sent when export is accepted and again when archive is ready, archive is either
+// sent in Data or written to File
+type ExportDTO struct {
+	ExportID  string          `json:"export_id,omitempty"`
+	UserID    string          `json:"user_id,omitempty"`
+	Completed bool            `json:"completed"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	File      string          `json:"file,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// FindProfileDTO requests profile by user ID or handle
+type FindProfileDTO struct {
+	UserID string `json:"user_id,omitempty"`
+	Handle string `json:"handle,omitempty"`
+}
+
+type UpdateProfileDTO struct {
+	UserID  string  `json:"user_id"`
+	Profile Profile `json:"profile"`
+}
+
+type ProfileDTO struct {
+	UserID  string  `json:"user_id,omitempty"`
+	Handle  string  `json:"handle,omitempty"`
+	Profile Profile `json:"profile,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/notifier"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+)
+
+func (s service) DeleteAccount(ctx context.Context, userID string, password string) (bool, error) {
+	u, err := s.storage.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return false, apperror.ErrInvalidCredentials
+		}
+		return false, fmt.Errorf("could not get user: %w", err)
+	}
+	err = s.checkPassword(ctx, u, password)
+	if err != nil {
+		return false, err
+	}
+
+	deletion := user.Deletion{
+		UserID:      userID,
+		Email:       u.Email,
+		RequestedAt: time.Now(),
+		Pending:     append([]string{}, s.config.DeletionServices...),
+	}
+	// deletion is saved first, so confirmations of services are not lost
+	err = s.storage.CreateDeletion(ctx, deletion)
+	if err != nil {
+		return false, fmt.Errorf("could not start deletion: %w", err)
+	}
+	err = s.storage.Delete(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("could not delete user: %w", err)
+	}
+	for _, purpose := range []string{user.TokenPasswordReset, user.TokenEmailVerification} {
+		err = s.storage.DeleteTokens(ctx, userID, purpose)
+		if err != nil {
+			log.Error().Err(err).Msgf("could not delete tokens of deleted user %s", userID)
+		}
+	}
+	err = s.storage.ResetLoginAttempts(ctx, s.loginKeys(u.Email, "")[0].key)
+	if err != nil {
+		log.Error().Err(err).Msgf("could not delete login attempts of deleted user %s", userID)
+	}
+
+	if len(deletion.Pending) > 0 {
+		return false, nil
+	}
+	return s.finishDeletion(ctx, userID)
+}
+
+func (s service) ConfirmDeletion(ctx context.Context, ack user.UserDeletionAckDTO) (bool, error) {
+	if len(ack.Error) > 0 {
+		err := s.storage.FailDeletion(ctx, ack.UserID, ack.Service, ack.Error)
+		if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+			return false, fmt.Errorf("could not save deletion failure: %w", err)
+		}
+		return false, fmt.Errorf("%s failed to delete user data: %s", ack.Service, ack.Error)
+	}
+
+	deletion, err := s.storage.ConfirmDeletion(ctx, ack.UserID, ack.Service)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return false, err
+		}
+		return false, fmt.Errorf("could not confirm deletion: %w", err)
+	}
+	if len(deletion.Pending) > 0 || !deletion.CompletedAt.IsZero() {
+		return false, nil
+	}
+	return s.finishDeletion(ctx, ack.UserID)
+}
+
+// finishDeletion completes deletion without pending services and sends final confirmation to user,
+// false is returned if deletion has been completed already
+func (s service) finishDeletion(ctx context.Context, userID string) (bool, error) {
+	deletion, err := s.storage.FinishDeletion(ctx, userID, time.Now())
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not finish deletion: %w", err)
+	}
+
+	err = s.notifier.Send(ctx, notifier.Message{
+		To:      deletion.Email,
+		Subject: "Удаление аккаунта",
+		Body:    "Ваш аккаунт и все его данные удалены.",
+	})
+	if err != nil {
+		// account is deleted anyway
+		log.Error().Err(err).Msgf("could not send deletion confirmation to user %s", userID)
+	}
+	log.Info().Msgf("deletion of user %s completed", userID)
+	return true, nil
+}
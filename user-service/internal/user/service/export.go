@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+)
+
+// StartExport returns accepted export and, if there are no services to wait for, its result
+func (s service) StartExport(ctx context.Context, userID string) (user.ExportDTO, *user.ExportDTO, error) {
+	_, err := s.storage.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return user.ExportDTO{}, nil, err
+		}
+		return user.ExportDTO{}, nil, fmt.Errorf("could not get user: %w", err)
+	}
+
+	now := time.Now()
+	id, err := s.storage.CreateExport(ctx, user.Export{
+		UserID:      userID,
+		RequestedAt: now,
+		Pending:     append([]string{}, s.config.ExportServices...),
+		ExpiresAt:   now.Add(s.config.ExportTTL),
+	})
+	if err != nil {
+		return user.ExportDTO{}, nil, fmt.Errorf("could not start export: %w", err)
+	}
+	accepted := user.ExportDTO{
+		ExportID: id,
+		UserID:   userID,
+	}
+	if len(s.config.ExportServices) > 0 {
+		return accepted, nil, nil
+	}
+	result, err := s.finishExport(ctx, id)
+	return accepted, result, err
+}
+
+func (s service) AddExportPart(ctx context.Context, part user.ExportPartDTO) (*user.ExportDTO, error) {
+	if len(part.Error) > 0 {
+		export, err := s.storage.DeleteExport(ctx, part.ExportID)
+		if err != nil {
+			// export has already failed or expired
+			if errors.Is(err, apperror.ErrNotFound) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("could not cancel export: %w", err)
+		}
+		return &user.ExportDTO{
+			ExportID: part.ExportID,
+			UserID:   export.UserID,
+			Error:    fmt.Sprintf("%s failed to export user data: %s", part.Service, part.Error),
+		}, nil
+	}
+
+	export, err := s.storage.AddExportPart(ctx, part.ExportID, part.Service, string(part.Data))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not add export part: %w", err)
+	}
+	if len(export.Pending) > 0 {
+		return nil, nil
+	}
+	return s.finishExport(ctx, part.ExportID)
+}
+
+// finishExport builds archive of export without pending services, nil is returned if it is finished already
+func (s service) finishExport(ctx context.Context, id string) (*user.ExportDTO, error) {
+	export, err := s.storage.FinishExport(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not finish export: %w", err)
+	}
+	result := &user.ExportDTO{
+		ExportID: id,
+		UserID:   export.UserID,
+	}
+
+	u, err := s.storage.FindByID(ctx, export.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get user: %w", err)
+	}
+	archive := user.Archive{
+		ExportedAt: time.Now(),
+		User:       u,
+		Services:   make(map[string]json.RawMessage, len(export.Parts)),
+	}
+	for service, data := range export.Parts {
+		archive.Services[service] = json.RawMessage(data)
+	}
+	bs, err := json.Marshal(archive)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode archive: %w", err)
+	}
+
+	if len(s.config.ExportDir) == 0 {
+		result.Data = bs
+	} else {
+		result.File = filepath.Join(s.config.ExportDir, fmt.Sprintf("%s-%s.json", export.UserID, id))
+		// archive holds personal data, so it is readable by service only
+		err = os.WriteFile(result.File, bs, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("could not write archive: %w", err)
+		}
+	}
+	result.Completed = true
+	return result, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// PasswordHashing defines how new password hashes are made. Hashes made with other algorithm or
+// parameters are still verified and replaced on next successful login.
+type PasswordHashing struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2Time is number of passes, Argon2Memory is in KiB
+	Argon2Time    uint32
+	Argon2Memory  uint32
+	Argon2Threads uint8
+}
+
+var DefaultPasswordHashing = PasswordHashing{
+	Algorithm:     HashBcrypt,
+	BcryptCost:    bcrypt.DefaultCost,
+	Argon2Time:    1,
+	Argon2Memory:  64 * 1024,
+	Argon2Threads: 4,
+}
+
+func (h PasswordHashing) Validate() error {
+	switch h.Algorithm {
+	case HashBcrypt:
+		if h.BcryptCost < bcrypt.MinCost || h.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be in [%d, %d]", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case HashArgon2id:
+		if h.Argon2Time < 1 || h.Argon2Memory < 8*uint32(h.Argon2Threads) || h.Argon2Threads < 1 {
+			return fmt.Errorf("invalid argon2id parameters")
+		}
+	default:
+		return fmt.Errorf("unknown password hashing algorithm %q", h.Algorithm)
+	}
+	return nil
+}
+
+func (h PasswordHashing) hash(password string) (string, error) {
+	if h.Algorithm == HashArgon2id {
+		salt := make([]byte, argon2SaltLength)
+		_, err := rand.Read(salt)
+		if err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, h.Argon2Time, h.Argon2Memory, h.Argon2Threads, argon2KeyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Argon2Memory, h.Argon2Time,
+			h.Argon2Threads, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// needsRehash reports whether hashed was made with other algorithm or parameters than configured
+func (h PasswordHashing) needsRehash(hashed string) bool {
+	if strings.HasPrefix(hashed, "$argon2id$") {
+		params, _, _, err := decodeArgon2id(hashed)
+		if err != nil {
+			return true
+		}
+		return h.Algorithm != HashArgon2id || params.Argon2Time != h.Argon2Time ||
+			params.Argon2Memory != h.Argon2Memory || params.Argon2Threads != h.Argon2Threads
+	}
+	if h.Algorithm != HashBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost != h.BcryptCost
+}
+
+func verifyPassword(hashed string, password string) bool {
+	if strings.HasPrefix(hashed, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hashed)
+		if err != nil {
+			return false
+		}
+		other := argon2.IDKey([]byte(password), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+	return err == nil
+}
+
+// decodeArgon2id parses hash in PHC string format
+func decodeArgon2id(hashed string) (params PasswordHashing, salt []byte, key []byte, err error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 {
+		err = fmt.Errorf("invalid argon2id hash")
+		return
+	}
+	var version int
+	_, err = fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil {
+		return
+	}
+	if version != argon2.Version {
+		err = fmt.Errorf("unsupported argon2 version %d", version)
+		return
+	}
+	params.Algorithm = HashArgon2id
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Time, &params.Argon2Threads)
+	if err != nil {
+		return
+	}
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	return
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+)
+
+// LoginPolicy limits failed login attempts by account and by client
+type LoginPolicy struct {
+	// FreeAttempts is number of failed logins allowed without delay
+	FreeAttempts int
+	// Delay is required between attempts after FreeAttempts failures, doubled with every next failure up to MaxDelay
+	Delay    time.Duration
+	MaxDelay time.Duration
+	// MaxAttempts is number of failed logins to an account after which it is locked
+	MaxAttempts int
+	// ClientMaxAttempts is number of failed logins from a client after which it is locked
+	ClientMaxAttempts int
+	// Lockout is how long account or client stays locked, failed logins are forgotten after the same period of inactivity
+	Lockout time.Duration
+}
+
+var DefaultLoginPolicy = LoginPolicy{
+	FreeAttempts:      3,
+	Delay:             time.Second,
+	MaxDelay:          30 * time.Second,
+	MaxAttempts:       10,
+	ClientMaxAttempts: 50,
+	Lockout:           15 * time.Minute,
+}
+
+// newDummyHash makes hash compared against when account does not exist. It is made with configured hashing,
+// so response time does not reveal whether account exists
+func newDummyHash(hashing PasswordHashing) string {
+	hash, err := hashing.hash("dummy password")
+	if err != nil {
+		log.Error().Err(err).Msg("could not make dummy password hash with configured hashing")
+		hash, _ = DefaultPasswordHashing.hash("dummy password")
+	}
+	return hash
+}
+
+type loginKey struct {
+	key         string
+	maxAttempts int
+}
+
+func (s service) loginKeys(email string, clientID string) []loginKey {
+	keys := []loginKey{
+		{key: "account:" + email, maxAttempts: s.config.Login.MaxAttempts},
+	}
+	if len(clientID) > 0 {
+		keys = append(keys, loginKey{key: "client:" + clientID, maxAttempts: s.config.Login.ClientMaxAttempts})
+	}
+	return keys
+}
+
+// loginAllowed checks that key is neither locked nor has to wait after recent failures
+func (s service) loginAllowed(ctx context.Context, key string, now time.Time) (bool, error) {
+	attempts, err := s.storage.FindLoginAttempts(ctx, key)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+	if !attempts.ExpiresAt.After(now) {
+		return true, nil
+	}
+	if attempts.LockedUntil.After(now) {
+		log.Info().Msgf("login attempt to %s rejected: locked until %s", key, attempts.LockedUntil)
+		return false, nil
+	}
+	delay := s.loginDelay(attempts.Failures)
+	if now.Before(attempts.LastFailure.Add(delay)) {
+		log.Info().Msgf("login attempt to %s rejected: %d failures, next attempt allowed in %s", key, attempts.Failures, delay)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s service) loginDelay(failures int) time.Duration {
+	if failures < s.config.Login.FreeAttempts {
+		return 0
+	}
+	delay := s.config.Login.Delay
+	for i := s.config.Login.FreeAttempts; i < failures && delay < s.config.Login.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.config.Login.MaxDelay {
+		delay = s.config.Login.MaxDelay
+	}
+	return delay
+}
+
+// loginFailed counts failed login for every key, locking keys which exceeded their limit
+func (s service) loginFailed(ctx context.Context, keys []loginKey, now time.Time) {
+	for _, k := range keys {
+		attempts, err := s.storage.AddFailedLogin(ctx, k.key, now, now.Add(s.config.Login.Lockout))
+		if err != nil {
+			log.Error().Err(err).Msgf("could not count failed login to %s", k.key)
+			continue
+		}
+		if attempts.Failures < k.maxAttempts {
+			continue
+		}
+		err = s.storage.LockLogin(ctx, k.key, now.Add(s.config.Login.Lockout))
+		if err != nil {
+			log.Error().Err(err).Msgf("could not lock %s", k.key)
+			continue
+		}
+		log.Warn().Msgf("%s locked after %d failed logins", k.key, attempts.Failures)
+	}
+}
+
+func (s service) GetByEmailAndPassword(ctx context.Context, email string, password string, clientID string) (user.User, error) {
+	email = normalizeEmail(email)
+	keys := s.loginKeys(email, clientID)
+	now := time.Now()
+
+	for _, k := range keys {
+		allowed, err := s.loginAllowed(ctx, k.key, now)
+		if err != nil {
+			return user.User{}, fmt.Errorf("could not check login attempts: %w", err)
+		}
+		if !allowed {
+			return user.User{}, apperror.ErrInvalidCredentials
+		}
+	}
+
+	u, err := s.storage.FindByEmail(ctx, email)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return user.User{}, fmt.Errorf("could not get user: %w", err)
+	}
+	hash := u.Password
+	if err != nil {
+		hash = s.dummyHash
+	}
+	if !verifyPassword(hash, password) || err != nil {
+		s.loginFailed(ctx, keys, now)
+		return user.User{}, apperror.ErrInvalidCredentials
+	}
+
+	err = s.storage.ResetLoginAttempts(ctx, keys[0].key)
+	if err != nil {
+		log.Error().Err(err).Msgf("could not reset login attempts of %s", keys[0].key)
+	}
+	s.rehash(ctx, u, password)
+	return u, nil
+}
+
+// rehash replaces password hash of user if hashing settings changed, password is known on login only
+func (s service) rehash(ctx context.Context, u user.User, password string) {
+	if !s.config.Hashing.needsRehash(u.Password) {
+		return
+	}
+	hash, err := s.config.Hashing.hash(password)
+	if err != nil {
+		log.Error().Err(err).Msgf("could not rehash password of user %s", u.ID)
+		return
+	}
+	err = s.storage.UpdatePassword(ctx, u.ID, hash)
+	if err != nil {
+		log.Error().Err(err).Msgf("could not rehash password of user %s", u.ID)
+		return
+	}
+	log.Info().Msgf("rehashed password of user %s with %s", u.ID, s.config.Hashing.Algorithm)
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/notifier"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+)
+
+// generateToken returns random token to be sent to user and its hash to be stored
+func generateToken() (token string, hash string, err error) {
+	bs := make([]byte, 32)
+	_, err = rand.Read(bs)
+	if err != nil {
+		return
+	}
+	token = base64.RawURLEncoding.EncodeToString(bs)
+	hash = hashToken(token)
+	return
+}
+
+// hashToken hashes token with SHA-256, which is enough for random tokens of high entropy
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueToken replaces user's tokens of given purpose with a new one
+func (s service) issueToken(ctx context.Context, userID string, purpose string, ttl time.Duration) (string, error) {
+	err := s.storage.DeleteTokens(ctx, userID, purpose)
+	if err != nil {
+		return "", err
+	}
+	token, hash, err := generateToken()
+	if err != nil {
+		return "", fmt.Errorf("could not generate token: %w", err)
+	}
+	err = s.storage.CreateToken(ctx, user.Token{
+		Hash:      hash,
+		UserID:    userID,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeToken returns ID of user token was issued to, token can not be used again
+func (s service) consumeToken(ctx context.Context, token string, purpose string) (string, error) {
+	t, err := s.storage.ConsumeToken(ctx, hashToken(token), purpose, time.Now())
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return "", apperror.ErrInvalidToken
+		}
+		return "", fmt.Errorf("could not check token: %w", err)
+	}
+	return t.UserID, nil
+}
+
+// findToken returns ID of user token was issued to, token stays valid
+func (s service) findToken(ctx context.Context, token string, purpose string) (string, error) {
+	t, err := s.storage.FindToken(ctx, hashToken(token), purpose, time.Now())
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return "", apperror.ErrInvalidToken
+		}
+		return "", fmt.Errorf("could not check token: %w", err)
+	}
+	return t.UserID, nil
+}
+
+func (s service) RequestPasswordReset(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+	u, err := s.storage.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			// requester is not told whether email is registered
+			return nil
+		}
+		return fmt.Errorf("could not get user: %w", err)
+	}
+
+	token, err := s.issueToken(ctx, u.ID, user.TokenPasswordReset, s.config.PasswordResetTTL)
+	if err != nil {
+		return fmt.Errorf("could not issue password reset token: %w", err)
+	}
+
+	err = s.notifier.Send(ctx, notifier.Message{
+		To:      u.Email,
+		Subject: "Восстановление пароля",
+		Body: fmt.Sprintf("Для смены пароля используйте код: %s\n\nКод действителен %s. "+
+			"Если вы не запрашивали смену пароля, проигнорируйте это письмо.", token, s.config.PasswordResetTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("could not send password reset token: %w", err)
+	}
+	return nil
+}
+
+func (s service) ResetPassword(ctx context.Context, token string, password string) error {
+	userID, err := s.findToken(ctx, token, user.TokenPasswordReset)
+	if err != nil {
+		return err
+	}
+	u, err := s.storage.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("could not get user: %w", err)
+	}
+	// token is used only when password is accepted, so user may retry with another password
+	err = s.config.Password.check(password, u.Email)
+	if err != nil {
+		return err
+	}
+	hash, err := s.config.Hashing.hash(password)
+	if err != nil {
+		return fmt.Errorf("could not hash password: %w", err)
+	}
+	// token could be used by concurrent request since it was found
+	_, err = s.consumeToken(ctx, token, user.TokenPasswordReset)
+	if err != nil {
+		return err
+	}
+	err = s.storage.UpdatePassword(ctx, userID, hash)
+	if err != nil {
+		return fmt.Errorf("could not update password: %w", err)
+	}
+	// user proved owning the account, so it is unlocked
+	err = s.storage.ResetLoginAttempts(ctx, s.loginKeys(u.Email, "")[0].key)
+	if err != nil {
+		return fmt.Errorf("could not reset login attempts: %w", err)
+	}
+	return nil
+}
+
+// checkPassword verifies password of logged in user the same way as on login, so it can not be guessed here instead
+func (s service) checkPassword(ctx context.Context, u user.User, password string) error {
+	keys := s.loginKeys(u.Email, "")
+	now := time.Now()
+	allowed, err := s.loginAllowed(ctx, keys[0].key, now)
+	if err != nil {
+		return fmt.Errorf("could not check login attempts: %w", err)
+	}
+	if !allowed || !verifyPassword(u.Password, password) {
+		if allowed {
+			s.loginFailed(ctx, keys, now)
+		}
+		return apperror.ErrInvalidCredentials
+	}
+	return nil
+}
+
+func (s service) ChangePassword(ctx context.Context, userID string, oldPassword string, newPassword string) error {
+	u, err := s.storage.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.ErrInvalidCredentials
+		}
+		return fmt.Errorf("could not get user: %w", err)
+	}
+
+	err = s.checkPassword(ctx, u, oldPassword)
+	if err != nil {
+		return err
+	}
+
+	err = s.config.Password.check(newPassword, u.Email)
+	if err != nil {
+		return err
+	}
+	hash, err := s.config.Hashing.hash(newPassword)
+	if err != nil {
+		return fmt.Errorf("could not hash password: %w", err)
+	}
+	err = s.storage.UpdatePassword(ctx, userID, hash)
+	if err != nil {
+		return fmt.Errorf("could not update password: %w", err)
+	}
+	// reset tokens issued for old password are no longer needed
+	err = s.storage.DeleteTokens(ctx, userID, user.TokenPasswordReset)
+	if err != nil {
+		return fmt.Errorf("could not delete password reset tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+)
+
+// PasswordPolicy defines which passwords are accepted on registration and password change
+type PasswordPolicy struct {
+	MinLength int
+	// MaxLength limits hashing cost, bcrypt ignores everything past 72 bytes anyway
+	MaxLength int
+	// Breached holds known leaked passwords which are rejected
+	Breached map[string]struct{}
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength: 8,
+	MaxLength: 72,
+}
+
+// LoadBreachedPasswords reads list of leaked passwords, one per line
+func LoadBreachedPasswords(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open breached passwords list: %w", err)
+	}
+	defer f.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) > 0 {
+			breached[line] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read breached passwords list: %w", err)
+	}
+	return breached, nil
+}
+
+func (p PasswordPolicy) check(password string, email string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters long", apperror.ErrWeakPassword, p.MinLength)
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		return fmt.Errorf("%w: must be at most %d bytes long", apperror.ErrWeakPassword, p.MaxLength)
+	}
+	if strings.EqualFold(password, email) {
+		return fmt.Errorf("%w: must differ from email", apperror.ErrWeakPassword)
+	}
+	_, breached := p.Breached[password]
+	_, breachedLower := p.Breached[strings.ToLower(password)]
+	if breached || breachedLower {
+		return fmt.Errorf("%w: found in list of leaked passwords", apperror.ErrWeakPassword)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 500
+	maxAvatarLength      = 500
+)
+
+var (
+	handlePattern   = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+	languagePattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+	// reservedHandles could be mistaken for service accounts or placeholders
+	reservedHandles = map[string]struct{}{
+		"admin":   {},
+		"deleted": {},
+		"editor":  {},
+		"support": {},
+		"system":  {},
+	}
+)
+
+// normalizeProfile trims profile fields and checks them
+func normalizeProfile(profile user.Profile) (user.Profile, error) {
+	profile.Handle = strings.ToLower(strings.TrimSpace(profile.Handle))
+	profile.DisplayName = strings.TrimSpace(profile.DisplayName)
+	profile.Bio = strings.TrimSpace(profile.Bio)
+	profile.Avatar = strings.TrimSpace(profile.Avatar)
+	profile.Language = strings.TrimSpace(profile.Language)
+	profile.Units = strings.TrimSpace(profile.Units)
+
+	if len(profile.Handle) > 0 {
+		if !handlePattern.MatchString(profile.Handle) {
+			return profile, fmt.Errorf("%w: handle must be 3 to 30 latin letters, digits or underscores", apperror.ErrInvalidProfile)
+		}
+		if _, ok := reservedHandles[profile.Handle]; ok {
+			return profile, fmt.Errorf("%w: handle is reserved", apperror.ErrInvalidProfile)
+		}
+	}
+	if utf8.RuneCountInString(profile.DisplayName) > maxDisplayNameLength {
+		return profile, fmt.Errorf("%w: display name is longer than %d characters", apperror.ErrInvalidProfile, maxDisplayNameLength)
+	}
+	if utf8.RuneCountInString(profile.Bio) > maxBioLength {
+		return profile, fmt.Errorf("%w: bio is longer than %d characters", apperror.ErrInvalidProfile, maxBioLength)
+	}
+	if len(profile.Avatar) > maxAvatarLength {
+		return profile, fmt.Errorf("%w: avatar reference is longer than %d bytes", apperror.ErrInvalidProfile, maxAvatarLength)
+	}
+	if len(profile.Language) > 0 && !languagePattern.MatchString(profile.Language) {
+		return profile, fmt.Errorf("%w: unknown language %q", apperror.ErrInvalidProfile, profile.Language)
+	}
+	switch profile.Units {
+	case "", user.UnitsMetric, user.UnitsImperial:
+	default:
+		return profile, fmt.Errorf("%w: unknown unit system %q", apperror.ErrInvalidProfile, profile.Units)
+	}
+	return profile, nil
+}
+
+func (s service) GetProfile(ctx context.Context, userID string) (user.Profile, error) {
+	u, err := s.storage.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return user.Profile{}, err
+		}
+		return user.Profile{}, fmt.Errorf("could not get user: %w", err)
+	}
+	return u.Profile, nil
+}
+
+func (s service) GetByHandle(ctx context.Context, handle string) (user.User, error) {
+	u, err := s.storage.FindByHandle(ctx, strings.ToLower(strings.TrimSpace(handle)))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return u, err
+		}
+		return u, fmt.Errorf("could not get user: %w", err)
+	}
+	return u, nil
+}
+
+func (s service) UpdateProfile(ctx context.Context, userID string, profile user.Profile) (user.Profile, error) {
+	profile, err := normalizeProfile(profile)
+	if err != nil {
+		return profile, err
+	}
+	err = s.storage.UpdateProfile(ctx, userID, profile)
+	if err != nil {
+		if errors.Is(err, apperror.ErrDuplicate) || errors.Is(err, apperror.ErrNotFound) {
+			return profile, err
+		}
+		return profile, fmt.Errorf("could not update profile: %w", err)
+	}
+	return profile, nil
+}
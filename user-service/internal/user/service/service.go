@@ -2,28 +2,102 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/mail"
+	"strings"
 	"time"
 
+	"github.com/rs/zerolog/log"
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/notifier"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/user/storage"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Service interface {
 	Create(ctx context.Context, dto user.CreateUserDTO) (string, error)
-	GetByEmailAndPassword(ctx context.Context, email string, password string) (user.User, error)
+	// GetByEmailAndPassword logs user in, failed attempts are limited by LoginPolicy
+	GetByEmailAndPassword(ctx context.Context, email string, password string, clientID string) (user.User, error)
 	GetByID(ctx context.Context, id string) (user.User, error)
+	// RequestPasswordReset sends password reset token to user, unknown email is not reported
+	RequestPasswordReset(ctx context.Context, email string) error
+	// ResetPassword sets new password of user password reset token was issued to
+	ResetPassword(ctx context.Context, token string, password string) error
+	// ChangePassword sets new password of user who knows the old one
+	ChangePassword(ctx context.Context, userID string, oldPassword string, newPassword string) error
+	// RequestEmailVerification sends new email verification token to unverified user
+	RequestEmailVerification(ctx context.Context, email string) error
+	VerifyEmail(ctx context.Context, token string) error
+	GetProfile(ctx context.Context, userID string) (user.Profile, error)
+	// GetByHandle finds user by public handle, case-insensitive
+	GetByHandle(ctx context.Context, handle string) (user.User, error)
+	// UpdateProfile validates and replaces profile of user, ErrDuplicate is returned if handle is taken
+	UpdateProfile(ctx context.Context, userID string, profile user.Profile) (user.Profile, error)
+	// SetRole assigns one of user.Role* roles to user
+	SetRole(ctx context.Context, userID string, role string) error
+	// PromoteAdmins grants admin role to verified users with AdminEmails
+	PromoteAdmins(ctx context.Context) error
+	// DeleteAccount deletes user and starts deletion of user data in DeletionServices,
+	// true is returned if there is nothing left to delete
+	DeleteAccount(ctx context.Context, userID string, password string) (bool, error)
+	// ConfirmDeletion records that service has deleted user data, true is returned once every service has done it
+	// and user is notified
+	ConfirmDeletion(ctx context.Context, ack user.UserDeletionAckDTO) (bool, error)
+	// StartExport starts collecting data of user from ExportServices
+	StartExport(ctx context.Context, userID string) (user.ExportDTO, *user.ExportDTO, error)
+	// AddExportPart saves part of export sent by service, result is returned once export is completed or failed
+	AddExportPart(ctx context.Context, part user.ExportPartDTO) (*user.ExportDTO, error)
+	// DeleteExpiredRegistrations deletes users who have not verified email in EmailVerificationTTL
+	DeleteExpiredRegistrations(ctx context.Context) (int64, error)
+}
+
+// Config holds service settings
+type Config struct {
+	Login    LoginPolicy
+	Password PasswordPolicy
+	Hashing  PasswordHashing
+	// PasswordResetTTL is how long password reset token is valid
+	PasswordResetTTL time.Duration
+	// EmailVerificationTTL is how long user has to verify email before registration is deleted
+	EmailVerificationTTL time.Duration
+	// AdminEmails are granted admin role once verified, that is how the first administrator is appointed
+	AdminEmails []string
+	// DeletionServices must confirm deletion of user data before account deletion is completed
+	DeletionServices []string
+	// ExportServices must send their part of user data export before archive is ready
+	ExportServices []string
+	// ExportTTL is how long export waits for parts from services
+	ExportTTL time.Duration
+	// ExportDir is directory archives are written to, archives are sent in reply if it is empty
+	ExportDir string
+}
+
+var DefaultConfig = Config{
+	Login:                DefaultLoginPolicy,
+	Password:             DefaultPasswordPolicy,
+	Hashing:              DefaultPasswordHashing,
+	PasswordResetTTL:     time.Hour,
+	EmailVerificationTTL: 24 * time.Hour,
+	DeletionServices:     []string{"recipe-service"},
+	ExportServices:       []string{"recipe-service"},
+	ExportTTL:            time.Hour,
 }
 
 type service struct {
-	storage storage.Storage
+	storage  storage.Storage
+	notifier notifier.Notifier
+	config   Config
+	// dummyHash is verified instead of password hash of account which does not exist
+	dummyHash string
 }
 
-func NewService(storage storage.Storage) Service {
+func NewService(storage storage.Storage, notifier notifier.Notifier, config Config) Service {
 	return service{
-		storage: storage,
+		storage:   storage,
+		notifier:  notifier,
+		config:    config,
+		dummyHash: newDummyHash(config.Hashing),
 	}
 }
 
@@ -32,52 +106,91 @@ func emailValid(email string) bool {
 	return err == nil
 }
 
-func hashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hash), nil
+// normalizeEmail brings email to the form it is stored in
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 func (s service) Create(ctx context.Context, dto user.CreateUserDTO) (string, error) {
-	if !emailValid(dto.Email) {
+	email := normalizeEmail(dto.Email)
+	if !emailValid(email) {
 		return "", fmt.Errorf("invalid email address")
 	}
-	hash, err := hashPassword(dto.Password)
+	err := s.config.Password.check(dto.Password, email)
+	if err != nil {
+		return "", err
+	}
+	hash, err := s.config.Hashing.hash(dto.Password)
 	if err != nil {
 		return "", fmt.Errorf("could not hash password: %w", err)
 	}
 	u := user.User{
-		Email:        dto.Email,
+		Email:        email,
 		Password:     hash,
 		RegisteredAt: time.Now(),
+		Role:         user.RoleUser,
 	}
 	id, err := s.storage.Create(ctx, u)
 	if err != nil {
+		if errors.Is(err, apperror.ErrDuplicate) {
+			return "", err
+		}
 		return "", fmt.Errorf("could not create user: %w", err)
 	}
+	// user is registered anyway, token can be requested again
+	err = s.sendVerification(ctx, id, email)
+	if err != nil {
+		log.Error().Err(err).Msgf("could not send verification to user %s", id)
+	}
 	return id, nil
 }
 
-func verifyPassword(hashed string, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
-	return err == nil
+func (s service) GetByID(ctx context.Context, id string) (u user.User, err error) {
+	u, err = s.storage.FindByID(ctx, id)
+	return
 }
 
-func (s service) GetByEmailAndPassword(ctx context.Context, email string, password string) (u user.User, err error) {
-	u, err = s.storage.FindByEmail(ctx, email)
+func (s service) SetRole(ctx context.Context, userID string, role string) error {
+	if !user.ValidRole(role) {
+		return fmt.Errorf("invalid role %q", role)
+	}
+	err := s.storage.SetRole(ctx, userID, role)
 	if err != nil {
-		return
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("could not set role: %w", err)
 	}
-	if !verifyPassword(u.Password, password) {
-		err = fmt.Errorf("wrong password")
-		return
+	return nil
+}
+
+func (s service) PromoteAdmins(ctx context.Context) error {
+	for _, email := range s.config.AdminEmails {
+		u, err := s.storage.FindByEmail(ctx, normalizeEmail(email))
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				continue
+			}
+			return fmt.Errorf("could not get user: %w", err)
+		}
+		err = s.promoteAdmin(ctx, u)
+		if err != nil {
+			return err
+		}
 	}
-	return
+	return nil
 }
 
-func (s service) GetByID(ctx context.Context, id string) (u user.User, err error) {
-	u, err = s.storage.FindByID(ctx, id)
-	return
+// promoteAdmin grants admin role to u if it is verified user with one of AdminEmails
+func (s service) promoteAdmin(ctx context.Context, u user.User) error {
+	if !u.Verified || u.Role == user.RoleAdmin {
+		return nil
+	}
+	for _, email := range s.config.AdminEmails {
+		if normalizeEmail(email) == u.Email {
+			log.Info().Msgf("granting admin role to user %s", u.ID)
+			return s.SetRole(ctx, u.ID, user.RoleAdmin)
+		}
+	}
+	return nil
 }
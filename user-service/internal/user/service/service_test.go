@@ -2,11 +2,17 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/notifier"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/user/storage/mongodb"
 )
@@ -31,7 +37,8 @@ func TestService(t *testing.T) {
 		}
 	}()
 
-	serv := NewService(stor)
+	notif := &testNotifier{}
+	serv := NewService(stor, notif, DefaultConfig)
 
 	t.Run("create user wrong email", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -50,7 +57,7 @@ func TestService(t *testing.T) {
 
 		dto := user.CreateUserDTO{
 			Email:    "user1@test.com",
-			Password: "12345",
+			Password: "secret-12345",
 		}
 		id, err := serv.Create(ctx, dto)
 		assert.NoError(t, err)
@@ -62,13 +69,13 @@ func TestService(t *testing.T) {
 
 		dto := user.CreateUserDTO{
 			Email:    "user2@test.com",
-			Password: "12345",
+			Password: "secret-12345",
 		}
 		createdID, err := serv.Create(ctx, dto)
 		assert.NoError(t, err)
 		assert.NotEmpty(t, createdID)
 
-		u, err := serv.GetByEmailAndPassword(ctx, dto.Email, dto.Password)
+		u, err := serv.GetByEmailAndPassword(ctx, dto.Email, dto.Password, "")
 		assert.NoError(t, err)
 		assert.Equal(t, createdID, u.ID)
 	})
@@ -78,7 +85,7 @@ func TestService(t *testing.T) {
 
 		dto := user.CreateUserDTO{
 			Email:    "user3@test.com",
-			Password: "12345",
+			Password: "secret-12345",
 		}
 		createdID, err := serv.Create(ctx, dto)
 		assert.NoError(t, err)
@@ -88,4 +95,575 @@ func TestService(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, u.Email, dto.Email)
 	})
+	t.Run("create user with same email in other case", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dto := user.CreateUserDTO{
+			Email:    "User4@Test.com",
+			Password: "secret-12345",
+		}
+		createdID, err := serv.Create(ctx, dto)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, createdID)
+
+		u, err := serv.GetByEmailAndPassword(ctx, "user4@test.com ", dto.Password, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "user4@test.com", u.Email)
+
+		dto.Email = "user4@test.com"
+		_, err = serv.Create(ctx, dto)
+		assert.ErrorIs(t, err, apperror.ErrDuplicate)
+	})
+	t.Run("wrong password and unknown email give same error", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dto := user.CreateUserDTO{
+			Email:    "user5@test.com",
+			Password: "secret-12345",
+		}
+		_, err := serv.Create(ctx, dto)
+		require.NoError(t, err)
+
+		_, err = serv.GetByEmailAndPassword(ctx, dto.Email, "wrong", "")
+		assert.ErrorIs(t, err, apperror.ErrInvalidCredentials)
+
+		_, err = serv.GetByEmailAndPassword(ctx, "unknown@test.com", "wrong", "")
+		assert.ErrorIs(t, err, apperror.ErrInvalidCredentials)
+	})
+	t.Run("account locked after failed logins", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		serv := NewService(stor, notif, Config{
+			Login: LoginPolicy{
+				FreeAttempts:      10,
+				Delay:             time.Second,
+				MaxDelay:          time.Second,
+				MaxAttempts:       3,
+				ClientMaxAttempts: 100,
+				Lockout:           time.Minute,
+			},
+			Password:         DefaultPasswordPolicy,
+			Hashing:          DefaultPasswordHashing,
+			PasswordResetTTL: time.Hour,
+		})
+		dto := user.CreateUserDTO{
+			Email:    "user6@test.com",
+			Password: "secret-12345",
+		}
+		_, err := serv.Create(ctx, dto)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			_, err = serv.GetByEmailAndPassword(ctx, dto.Email, "wrong", "")
+			assert.ErrorIs(t, err, apperror.ErrInvalidCredentials)
+		}
+
+		_, err = serv.GetByEmailAndPassword(ctx, dto.Email, dto.Password, "")
+		assert.ErrorIs(t, err, apperror.ErrInvalidCredentials)
+	})
+	t.Run("login delayed after free attempts", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		serv := NewService(stor, notif, Config{
+			Login: LoginPolicy{
+				FreeAttempts:      1,
+				Delay:             500 * time.Millisecond,
+				MaxDelay:          time.Second,
+				MaxAttempts:       100,
+				ClientMaxAttempts: 100,
+				Lockout:           time.Minute,
+			},
+			Password:         DefaultPasswordPolicy,
+			Hashing:          DefaultPasswordHashing,
+			PasswordResetTTL: time.Hour,
+		})
+		dto := user.CreateUserDTO{
+			Email:    "user7@test.com",
+			Password: "secret-12345",
+		}
+		_, err := serv.Create(ctx, dto)
+		require.NoError(t, err)
+
+		_, err = serv.GetByEmailAndPassword(ctx, dto.Email, "wrong", "client7")
+		assert.ErrorIs(t, err, apperror.ErrInvalidCredentials)
+
+		_, err = serv.GetByEmailAndPassword(ctx, dto.Email, dto.Password, "client7")
+		assert.ErrorIs(t, err, apperror.ErrInvalidCredentials)
+
+		time.Sleep(600 * time.Millisecond)
+		u, err := serv.GetByEmailAndPassword(ctx, dto.Email, dto.Password, "client7")
+		assert.NoError(t, err)
+		assert.Equal(t, dto.Email, u.Email)
+	})
+	t.Run("reset password", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dto := user.CreateUserDTO{
+			Email:    "user8@test.com",
+			Password: "secret-12345",
+		}
+		_, err := serv.Create(ctx, dto)
+		require.NoError(t, err)
+
+		err = serv.RequestPasswordReset(ctx, dto.Email)
+		require.NoError(t, err)
+		msg, ok := notif.last(dto.Email)
+		require.True(t, ok)
+		token := tokenFromMessage(msg)
+
+		// rejected password does not use token up
+		err = serv.ResetPassword(ctx, token, "short")
+		assert.ErrorIs(t, err, apperror.ErrWeakPassword)
+		err = serv.ResetPassword(ctx, token, "secret-54321")
+		require.NoError(t, err)
+
+		_, err = serv.GetByEmailAndPassword(ctx, dto.Email, "secret-54321", "")
+		assert.NoError(t, err)
+
+		err = serv.ResetPassword(ctx, token, "secret-11111")
+		assert.ErrorIs(t, err, apperror.ErrInvalidToken)
+	})
+	t.Run("reset password unknown email", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err := serv.RequestPasswordReset(ctx, "unknown9@test.com")
+		assert.NoError(t, err)
+		_, ok := notif.last("unknown9@test.com")
+		assert.False(t, ok)
+	})
+	t.Run("verify email", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dto := user.CreateUserDTO{
+			Email:    "user10@test.com",
+			Password: "secret-12345",
+		}
+		id, err := serv.Create(ctx, dto)
+		require.NoError(t, err)
+
+		u, err := serv.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.False(t, u.Verified)
+
+		msg, ok := notif.last(dto.Email)
+		require.True(t, ok)
+		err = serv.VerifyEmail(ctx, tokenFromMessage(msg))
+		require.NoError(t, err)
+
+		u, err = serv.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, u.Verified)
+
+		err = serv.VerifyEmail(ctx, tokenFromMessage(msg))
+		assert.ErrorIs(t, err, apperror.ErrInvalidToken)
+	})
+	t.Run("delete expired registrations", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		serv := NewService(stor, notif, Config{
+			Login:                DefaultLoginPolicy,
+			Password:             DefaultPasswordPolicy,
+			Hashing:              DefaultPasswordHashing,
+			PasswordResetTTL:     time.Hour,
+			EmailVerificationTTL: -time.Minute,
+		})
+		dto := user.CreateUserDTO{
+			Email:    "user11@test.com",
+			Password: "secret-12345",
+		}
+		id, err := serv.Create(ctx, dto)
+		require.NoError(t, err)
+
+		deleted, err := serv.DeleteExpiredRegistrations(ctx)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, deleted, int64(1))
+
+		_, err = serv.GetByID(ctx, id)
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+	})
+	t.Run("create user weak password", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		serv := NewService(stor, notif, Config{
+			Login: DefaultLoginPolicy,
+			Password: PasswordPolicy{
+				MinLength: 8,
+				MaxLength: 72,
+				Breached:  map[string]struct{}{"password123": {}},
+			},
+			Hashing:              DefaultPasswordHashing,
+			PasswordResetTTL:     time.Hour,
+			EmailVerificationTTL: time.Hour,
+		})
+		for _, password := range []string{"", "1234567", "Password123", "user12@test.com"} {
+			_, err := serv.Create(ctx, user.CreateUserDTO{
+				Email:    "user12@test.com",
+				Password: password,
+			})
+			assert.ErrorIs(t, err, apperror.ErrWeakPassword, password)
+		}
+	})
+	t.Run("change password", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dto := user.CreateUserDTO{
+			Email:    "user13@test.com",
+			Password: "secret-12345",
+		}
+		id, err := serv.Create(ctx, dto)
+		require.NoError(t, err)
+
+		err = serv.ChangePassword(ctx, id, "wrong-password", "secret-54321")
+		assert.ErrorIs(t, err, apperror.ErrInvalidCredentials)
+
+		err = serv.ChangePassword(ctx, id, dto.Password, "short")
+		assert.ErrorIs(t, err, apperror.ErrWeakPassword)
+
+		err = serv.ChangePassword(ctx, id, dto.Password, "secret-54321")
+		require.NoError(t, err)
+
+		_, err = serv.GetByEmailAndPassword(ctx, dto.Email, "secret-54321", "")
+		assert.NoError(t, err)
+	})
+	t.Run("rehash password on login", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dto := user.CreateUserDTO{
+			Email:    "user14@test.com",
+			Password: "secret-12345",
+		}
+		id, err := serv.Create(ctx, dto)
+		require.NoError(t, err)
+
+		argonConfig := DefaultConfig
+		argonConfig.Hashing.Algorithm = HashArgon2id
+		argonServ := NewService(stor, notif, argonConfig)
+		// unknown accounts are verified with the same hashing as existing ones
+		assert.True(t, strings.HasPrefix(argonServ.(service).dummyHash, "$argon2id$"))
+
+		_, err = argonServ.GetByEmailAndPassword(ctx, dto.Email, dto.Password, "")
+		require.NoError(t, err)
+		u, err := argonServ.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(u.Password, "$argon2id$"))
+
+		_, err = argonServ.GetByEmailAndPassword(ctx, dto.Email, dto.Password, "")
+		assert.NoError(t, err)
+		_, err = serv.GetByEmailAndPassword(ctx, dto.Email, dto.Password, "")
+		assert.NoError(t, err)
+	})
+	t.Run("set role", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		id, err := serv.Create(ctx, user.CreateUserDTO{
+			Email:    "user15@test.com",
+			Password: "secret-12345",
+		})
+		require.NoError(t, err)
+
+		u, err := serv.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, user.RoleUser, u.Role)
+
+		err = serv.SetRole(ctx, id, "superuser")
+		assert.Error(t, err)
+
+		err = serv.SetRole(ctx, id, user.RoleEditor)
+		require.NoError(t, err)
+		u, err = serv.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, user.RoleEditor, u.Role)
+	})
+	t.Run("admin emails are granted admin role once verified", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		adminConfig := DefaultConfig
+		adminConfig.AdminEmails = []string{"Admin30@test.com"}
+		adminServ := NewService(stor, notif, adminConfig)
+
+		dto := user.CreateUserDTO{
+			Email:    "admin30@test.com",
+			Password: "secret-12345",
+		}
+		id, err := adminServ.Create(ctx, dto)
+		require.NoError(t, err)
+
+		// unverified user could have registered someone else's email
+		err = adminServ.PromoteAdmins(ctx)
+		require.NoError(t, err)
+		u, err := adminServ.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, user.RoleUser, u.Role)
+
+		msg, ok := notif.last(dto.Email)
+		require.True(t, ok)
+		err = adminServ.VerifyEmail(ctx, tokenFromMessage(msg))
+		require.NoError(t, err)
+		u, err = adminServ.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, user.RoleAdmin, u.Role)
+	})
+	t.Run("delete account", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dto := user.CreateUserDTO{
+			Email:    "user16@test.com",
+			Password: "secret-12345",
+		}
+		id, err := serv.Create(ctx, dto)
+		require.NoError(t, err)
+
+		_, err = serv.DeleteAccount(ctx, id, "wrong-password")
+		assert.ErrorIs(t, err, apperror.ErrInvalidCredentials)
+
+		completed, err := serv.DeleteAccount(ctx, id, dto.Password)
+		require.NoError(t, err)
+		assert.False(t, completed)
+
+		_, err = serv.GetByID(ctx, id)
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+		_, err = serv.GetByEmailAndPassword(ctx, dto.Email, dto.Password, "")
+		assert.ErrorIs(t, err, apperror.ErrInvalidCredentials)
+
+		_, err = serv.ConfirmDeletion(ctx, user.UserDeletionAckDTO{UserID: id, Service: "recipe-service", Error: "failure"})
+		assert.Error(t, err)
+
+		completed, err = serv.ConfirmDeletion(ctx, user.UserDeletionAckDTO{UserID: id, Service: "recipe-service"})
+		require.NoError(t, err)
+		assert.True(t, completed)
+		msg, ok := notif.last(dto.Email)
+		require.True(t, ok)
+		assert.Equal(t, "Удаление аккаунта", msg.Subject)
+
+		completed, err = serv.ConfirmDeletion(ctx, user.UserDeletionAckDTO{UserID: id, Service: "recipe-service"})
+		require.NoError(t, err)
+		assert.False(t, completed)
+	})
+	t.Run("delete account without dependent services", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		config := DefaultConfig
+		config.DeletionServices = nil
+		serv := NewService(stor, notif, config)
+
+		dto := user.CreateUserDTO{
+			Email:    "user17@test.com",
+			Password: "secret-12345",
+		}
+		id, err := serv.Create(ctx, dto)
+		require.NoError(t, err)
+
+		completed, err := serv.DeleteAccount(ctx, id, dto.Password)
+		require.NoError(t, err)
+		assert.True(t, completed)
+	})
+	t.Run("export user data", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		id, err := serv.Create(ctx, user.CreateUserDTO{
+			Email:    "user18@test.com",
+			Password: "secret-12345",
+		})
+		require.NoError(t, err)
+
+		accepted, result, err := serv.StartExport(ctx, id)
+		require.NoError(t, err)
+		assert.Nil(t, result)
+		assert.NotEmpty(t, accepted.ExportID)
+		assert.False(t, accepted.Completed)
+
+		result, err = serv.AddExportPart(ctx, user.ExportPartDTO{
+			ExportID: accepted.ExportID,
+			UserID:   id,
+			Service:  "recipe-service",
+			Data:     json.RawMessage(`{"recipes":[]}`),
+		})
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.True(t, result.Completed)
+
+		var archive user.Archive
+		err = json.Unmarshal(result.Data, &archive)
+		require.NoError(t, err)
+		assert.Equal(t, id, archive.User.ID)
+		assert.Equal(t, "user18@test.com", archive.User.Email)
+		assert.JSONEq(t, `{"recipes":[]}`, string(archive.Services["recipe-service"]))
+		assert.NotContains(t, string(result.Data), "password")
+
+		result, err = serv.AddExportPart(ctx, user.ExportPartDTO{
+			ExportID: accepted.ExportID,
+			UserID:   id,
+			Service:  "recipe-service",
+			Data:     json.RawMessage(`{"recipes":[]}`),
+		})
+		require.NoError(t, err)
+		assert.Nil(t, result)
+	})
+	t.Run("export user data failed by service", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		id, err := serv.Create(ctx, user.CreateUserDTO{
+			Email:    "user19@test.com",
+			Password: "secret-12345",
+		})
+		require.NoError(t, err)
+
+		accepted, _, err := serv.StartExport(ctx, id)
+		require.NoError(t, err)
+
+		result, err := serv.AddExportPart(ctx, user.ExportPartDTO{
+			ExportID: accepted.ExportID,
+			UserID:   id,
+			Service:  "recipe-service",
+			Error:    "failure",
+		})
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.False(t, result.Completed)
+		assert.NotEmpty(t, result.Error)
+	})
+	t.Run("export user data to directory", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		config := DefaultConfig
+		config.ExportServices = nil
+		config.ExportDir = t.TempDir()
+		serv := NewService(stor, notif, config)
+
+		id, err := serv.Create(ctx, user.CreateUserDTO{
+			Email:    "user20@test.com",
+			Password: "secret-12345",
+		})
+		require.NoError(t, err)
+
+		_, result, err := serv.StartExport(ctx, id)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.True(t, result.Completed)
+		assert.Empty(t, result.Data)
+
+		bs, err := os.ReadFile(result.File)
+		require.NoError(t, err)
+		assert.Contains(t, string(bs), "user20@test.com")
+	})
+	t.Run("update profile and find by handle", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		id, err := serv.Create(ctx, user.CreateUserDTO{
+			Email:    "user21@test.com",
+			Password: "secret-12345",
+		})
+		require.NoError(t, err)
+
+		profile, err := serv.UpdateProfile(ctx, id, user.Profile{
+			Handle:      " Chef_21 ",
+			DisplayName: "Шеф",
+			Bio:         "Готовлю по выходным",
+			Language:    "ru",
+			Units:       user.UnitsMetric,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "chef_21", profile.Handle)
+
+		got, err := serv.GetProfile(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, profile, got)
+
+		u, err := serv.GetByHandle(ctx, "CHEF_21")
+		require.NoError(t, err)
+		assert.Equal(t, id, u.ID)
+	})
+	t.Run("profile handle is unique", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		first, err := serv.Create(ctx, user.CreateUserDTO{
+			Email:    "user22@test.com",
+			Password: "secret-12345",
+		})
+		require.NoError(t, err)
+		second, err := serv.Create(ctx, user.CreateUserDTO{
+			Email:    "user23@test.com",
+			Password: "secret-12345",
+		})
+		require.NoError(t, err)
+
+		_, err = serv.UpdateProfile(ctx, first, user.Profile{Handle: "baker"})
+		require.NoError(t, err)
+		_, err = serv.UpdateProfile(ctx, second, user.Profile{Handle: "Baker"})
+		assert.ErrorIs(t, err, apperror.ErrDuplicate)
+
+		// profiles without handle do not conflict
+		_, err = serv.UpdateProfile(ctx, second, user.Profile{DisplayName: "Пекарь"})
+		assert.NoError(t, err)
+	})
+	t.Run("invalid profile", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		id, err := serv.Create(ctx, user.CreateUserDTO{
+			Email:    "user24@test.com",
+			Password: "secret-12345",
+		})
+		require.NoError(t, err)
+
+		for _, profile := range []user.Profile{
+			{Handle: "a"},
+			{Handle: "with space"},
+			{Handle: "admin"},
+			{Language: "russian"},
+			{Units: "parsecs"},
+			{DisplayName: strings.Repeat("я", 51)},
+		} {
+			_, err = serv.UpdateProfile(ctx, id, profile)
+			assert.ErrorIs(t, err, apperror.ErrInvalidProfile, profile)
+		}
+	})
+}
+
+// tokenFromMessage extracts token following first colon of notification body
+func tokenFromMessage(msg notifier.Message) string {
+	return msg.Body[strings.Index(msg.Body, ": ")+2 : strings.Index(msg.Body, "\n")]
+}
+
+type testNotifier struct {
+	mu       sync.Mutex
+	messages []notifier.Message
+}
+
+func (n *testNotifier) Send(ctx context.Context, msg notifier.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+func (n *testNotifier) last(to string) (notifier.Message, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for i := len(n.messages) - 1; i >= 0; i-- {
+		if n.messages[i].To == to {
+			return n.messages[i], true
+		}
+	}
+	return notifier.Message{}, false
 }
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/notifier"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
+)
+
+func (s service) sendVerification(ctx context.Context, userID string, email string) error {
+	token, err := s.issueToken(ctx, userID, user.TokenEmailVerification, s.config.EmailVerificationTTL)
+	if err != nil {
+		return fmt.Errorf("could not issue email verification token: %w", err)
+	}
+
+	err = s.notifier.Send(ctx, notifier.Message{
+		To:      email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf("Для подтверждения email используйте код: %s\n\nКод действителен %s, "+
+			"после этого неподтверждённая учётная запись будет удалена.", token, s.config.EmailVerificationTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("could not send email verification token: %w", err)
+	}
+	return nil
+}
+
+func (s service) RequestEmailVerification(ctx context.Context, email string) error {
+	u, err := s.storage.FindByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			// requester is not told whether email is registered
+			return nil
+		}
+		return fmt.Errorf("could not get user: %w", err)
+	}
+	if u.Verified {
+		return nil
+	}
+	return s.sendVerification(ctx, u.ID, u.Email)
+}
+
+func (s service) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.consumeToken(ctx, token, user.TokenEmailVerification)
+	if err != nil {
+		return err
+	}
+	err = s.storage.SetVerified(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("could not verify email: %w", err)
+	}
+	u, err := s.storage.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("could not get user: %w", err)
+	}
+	return s.promoteAdmin(ctx, u)
+}
+
+func (s service) DeleteExpiredRegistrations(ctx context.Context) (int64, error) {
+	deleted, err := s.storage.DeleteUnverified(ctx, time.Now().Add(-s.config.EmailVerificationTTL))
+	if err != nil {
+		return 0, fmt.Errorf("could not delete expired registrations: %w", err)
+	}
+	if deleted > 0 {
+		log.Info().Msgf("deleted %d expired unverified registrations", deleted)
+	}
+	return deleted, nil
+}
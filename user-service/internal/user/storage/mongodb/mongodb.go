@@ -4,12 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 
+	edb "github.com/tony-spark/recipetor-backend/user-service/db"
 	apperror "github.com/tony-spark/recipetor-backend/user-service/internal/errors"
+	"github.com/tony-spark/recipetor-backend/user-service/internal/metrics"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
 	"github.com/tony-spark/recipetor-backend/user-service/internal/user/storage"
 	"go.mongodb.org/mongo-driver/bson"
@@ -20,10 +27,30 @@ import (
 type mongoStorage struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	attempts   *mongo.Collection
+	tokens     *mongo.Collection
+	deletions  *mongo.Collection
+	exports    *mongo.Collection
+}
+
+// caseInsensitive matches collation of unique_email and unique_handle indexes, so lookups can use them
+var caseInsensitive = &options.Collation{
+	Locale:   "en",
+	Strength: 2,
 }
 
 func NewStorage(dsn string, dbname string) (storage.Storage, error) {
-	client, err := mongo.NewClient(options.Client().ApplyURI(dsn))
+	driver, err := iofs.New(edb.EmbeddedDBFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("could not open migrations: %w", err)
+	}
+
+	migrator, err := migrate.NewWithSourceInstance("iofs", driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize migrator: %w", err)
+	}
+
+	client, err := mongo.NewClient(options.Client().ApplyURI(dsn).SetMonitor(otelmongo.NewMonitor()))
 	if err != nil {
 		return nil, fmt.Errorf("could not create connection to test DB: %w", err)
 	}
@@ -42,13 +69,74 @@ func NewStorage(dsn string, dbname string) (storage.Storage, error) {
 
 	db := client.Database(dbname)
 
+	err = checkDuplicateEmails(ctx, migrator, db.Collection("users"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = migrator.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return nil, fmt.Errorf("could not execute db migrations: %w", err)
+	}
+
 	collection := db.Collection("users")
+	attempts := db.Collection("login_attempts")
+	tokens := db.Collection("tokens")
+	deletions := db.Collection("deletions")
+	exports := db.Collection("exports")
 	return mongoStorage{
 		client:     client,
 		collection: collection,
+		attempts:   attempts,
+		tokens:     tokens,
+		deletions:  deletions,
+		exports:    exports,
 	}, nil
 }
 
+// uniqueEmailVersion is version of migration creating unique_email index
+const uniqueEmailVersion = 2
+
+// checkDuplicateEmails fails if unique_email index is not created yet and some emails differ only in case or
+// surrounding spaces. Index could not be built over such emails, leaving migrations dirty
+func checkDuplicateEmails(ctx context.Context, migrator *migrate.Migrate, users *mongo.Collection) error {
+	version, _, err := migrator.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("could not get db migrations version: %w", err)
+	}
+	if version >= uniqueEmailVersion {
+		return nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+	cursor, err := users.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to check duplicate emails: %w", err)
+	}
+	var duplicates []struct {
+		Email string `bson:"_id"`
+	}
+	err = cursor.All(ctx, &duplicates)
+	if err != nil {
+		return fmt.Errorf("failed to fetch duplicate emails: %w", err)
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+	emails := make([]string, 0, len(duplicates))
+	for _, d := range duplicates {
+		emails = append(emails, d.Email)
+	}
+	return fmt.Errorf("users with the same emails must be merged before db migrations (see README): %s",
+		strings.Join(emails, ", "))
+}
+
 func NewTestStorage(dsn string, dbname string) (storage.Storage, func(ctx context.Context) error, error) {
 	stor, err := NewStorage(dsn, dbname)
 	if err != nil {
@@ -65,8 +153,13 @@ func NewTestStorage(dsn string, dbname string) (storage.Storage, func(ctx contex
 }
 
 func (m mongoStorage) Create(ctx context.Context, user user.User) (string, error) {
+	defer metrics.ObserveStorage("Create", time.Now())
+
 	result, err := m.collection.InsertOne(ctx, user)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", apperror.ErrDuplicate
+		}
 		return "", fmt.Errorf("failed to insert user: %w", err)
 	}
 
@@ -79,6 +172,8 @@ func (m mongoStorage) Create(ctx context.Context, user user.User) (string, error
 }
 
 func (m mongoStorage) FindByID(ctx context.Context, id string) (user user.User, err error) {
+	defer metrics.ObserveStorage("FindByID", time.Now())
+
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return
@@ -96,7 +191,9 @@ func (m mongoStorage) FindByID(ctx context.Context, id string) (user user.User,
 }
 
 func (m mongoStorage) FindByEmail(ctx context.Context, email string) (user user.User, err error) {
-	result := m.collection.FindOne(ctx, bson.M{"email": email})
+	defer metrics.ObserveStorage("FindByEmail", time.Now())
+
+	result := m.collection.FindOne(ctx, bson.M{"email": email}, options.FindOne().SetCollation(caseInsensitive))
 	if result.Err() != nil {
 		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
 			return user, apperror.ErrNotFound
@@ -107,3 +204,372 @@ func (m mongoStorage) FindByEmail(ctx context.Context, email string) (user user.
 	err = result.Decode(&user)
 	return
 }
+
+func (m mongoStorage) UpdatePassword(ctx context.Context, id string, password string) error {
+	defer metrics.ObserveStorage("UpdatePassword", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	result, err := m.collection.UpdateByID(ctx, oid, bson.M{"$set": bson.M{"password": password}})
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) SetVerified(ctx context.Context, id string) error {
+	defer metrics.ObserveStorage("SetVerified", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	result, err := m.collection.UpdateByID(ctx, oid, bson.M{"$set": bson.M{"verified": true}})
+	if err != nil {
+		return fmt.Errorf("failed to verify user: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) SetRole(ctx context.Context, id string, role string) error {
+	defer metrics.ObserveStorage("SetRole", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	result, err := m.collection.UpdateByID(ctx, oid, bson.M{"$set": bson.M{"role": role}})
+	if err != nil {
+		return fmt.Errorf("failed to set user role: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) FindByHandle(ctx context.Context, handle string) (user user.User, err error) {
+	defer metrics.ObserveStorage("FindByHandle", time.Now())
+
+	result := m.collection.FindOne(ctx, bson.M{"profile.handle": handle}, options.FindOne().SetCollation(caseInsensitive))
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return user, apperror.ErrNotFound
+		}
+		err = result.Err()
+		return
+	}
+	err = result.Decode(&user)
+	return
+}
+
+func (m mongoStorage) UpdateProfile(ctx context.Context, id string, profile user.Profile) error {
+	defer metrics.ObserveStorage("UpdateProfile", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	result, err := m.collection.UpdateByID(ctx, oid, bson.M{"$set": bson.M{"profile": profile}})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return apperror.ErrDuplicate
+		}
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveStorage("Delete", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	result, err := m.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) DeleteUnverified(ctx context.Context, registeredBefore time.Time) (int64, error) {
+	defer metrics.ObserveStorage("DeleteUnverified", time.Now())
+
+	result, err := m.collection.DeleteMany(ctx, bson.M{
+		"verified":      false,
+		"registered_at": bson.M{"$lt": registeredBefore},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete unverified users: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+func (m mongoStorage) CreateToken(ctx context.Context, token user.Token) error {
+	defer metrics.ObserveStorage("CreateToken", time.Now())
+
+	_, err := m.tokens.InsertOne(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to insert token: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) FindToken(ctx context.Context, hash string, purpose string, now time.Time) (token user.Token, err error) {
+	defer metrics.ObserveStorage("FindToken", time.Now())
+
+	result := m.tokens.FindOne(ctx, bson.M{
+		"_id":        hash,
+		"purpose":    purpose,
+		"expires_at": bson.M{"$gt": now},
+	})
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return token, apperror.ErrNotFound
+		}
+		err = result.Err()
+		return
+	}
+	err = result.Decode(&token)
+	return
+}
+
+func (m mongoStorage) ConsumeToken(ctx context.Context, hash string, purpose string, now time.Time) (token user.Token, err error) {
+	defer metrics.ObserveStorage("ConsumeToken", time.Now())
+
+	result := m.tokens.FindOneAndDelete(ctx, bson.M{
+		"_id":        hash,
+		"purpose":    purpose,
+		"expires_at": bson.M{"$gt": now},
+	})
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return token, apperror.ErrNotFound
+		}
+		err = result.Err()
+		return
+	}
+	err = result.Decode(&token)
+	return
+}
+
+func (m mongoStorage) DeleteTokens(ctx context.Context, userID string, purpose string) error {
+	defer metrics.ObserveStorage("DeleteTokens", time.Now())
+
+	_, err := m.tokens.DeleteMany(ctx, bson.M{"user_id": userID, "purpose": purpose})
+	if err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) FindLoginAttempts(ctx context.Context, key string) (attempts user.LoginAttempts, err error) {
+	defer metrics.ObserveStorage("FindLoginAttempts", time.Now())
+
+	result := m.attempts.FindOne(ctx, bson.M{"_id": key})
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return attempts, apperror.ErrNotFound
+		}
+		err = result.Err()
+		return
+	}
+	err = result.Decode(&attempts)
+	return
+}
+
+func (m mongoStorage) AddFailedLogin(ctx context.Context, key string, at time.Time, expiresAt time.Time) (attempts user.LoginAttempts, err error) {
+	defer metrics.ObserveStorage("AddFailedLogin", time.Now())
+
+	// single pipeline update, so concurrent failures from several replicas are all counted
+	active := bson.D{{Key: "$gt", Value: bson.A{"$expires_at", at}}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "failures", Value: bson.D{{Key: "$cond", Value: bson.A{active, bson.D{{Key: "$add", Value: bson.A{"$failures", 1}}}, 1}}}},
+			{Key: "locked_until", Value: bson.D{{Key: "$cond", Value: bson.A{active, "$locked_until", "$$REMOVE"}}}},
+			{Key: "last_failure", Value: at},
+			{Key: "expires_at", Value: bson.D{{Key: "$max", Value: bson.A{"$locked_until", expiresAt}}}},
+		}}},
+	}
+	result := m.attempts.FindOneAndUpdate(ctx, bson.M{"_id": key}, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After))
+	if result.Err() != nil {
+		err = fmt.Errorf("failed to update login attempts: %w", result.Err())
+		return
+	}
+	err = result.Decode(&attempts)
+	return
+}
+
+func (m mongoStorage) LockLogin(ctx context.Context, key string, until time.Time) error {
+	defer metrics.ObserveStorage("LockLogin", time.Now())
+
+	_, err := m.attempts.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": bson.M{
+		"locked_until": until,
+		"expires_at":   until,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) ResetLoginAttempts(ctx context.Context, key string) error {
+	defer metrics.ObserveStorage("ResetLoginAttempts", time.Now())
+
+	_, err := m.attempts.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) CreateDeletion(ctx context.Context, deletion user.Deletion) error {
+	defer metrics.ObserveStorage("CreateDeletion", time.Now())
+
+	_, err := m.deletions.InsertOne(ctx, deletion)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return apperror.ErrDuplicate
+		}
+		return fmt.Errorf("failed to insert deletion: %w", err)
+	}
+	return nil
+}
+
+func (m mongoStorage) ConfirmDeletion(ctx context.Context, userID string, service string) (deletion user.Deletion, err error) {
+	defer metrics.ObserveStorage("ConfirmDeletion", time.Now())
+
+	result := m.deletions.FindOneAndUpdate(ctx, bson.M{"_id": userID},
+		bson.M{"$pull": bson.M{"pending": service}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return deletion, apperror.ErrNotFound
+		}
+		err = fmt.Errorf("failed to confirm deletion: %w", result.Err())
+		return
+	}
+	err = result.Decode(&deletion)
+	return
+}
+
+func (m mongoStorage) FailDeletion(ctx context.Context, userID string, service string, reason string) error {
+	defer metrics.ObserveStorage("FailDeletion", time.Now())
+
+	result, err := m.deletions.UpdateByID(ctx, userID, bson.M{"$set": bson.M{"error": service + ": " + reason}})
+	if err != nil {
+		return fmt.Errorf("failed to update deletion: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m mongoStorage) FinishDeletion(ctx context.Context, userID string, at time.Time) (deletion user.Deletion, err error) {
+	defer metrics.ObserveStorage("FinishDeletion", time.Now())
+
+	// only one of concurrent confirmations finishes deletion, so user is notified once
+	result := m.deletions.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID, "pending": bson.M{"$size": 0}, "completed_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"completed_at": at}, "$unset": bson.M{"email": "", "error": ""}},
+	)
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return deletion, apperror.ErrNotFound
+		}
+		err = fmt.Errorf("failed to finish deletion: %w", result.Err())
+		return
+	}
+	err = result.Decode(&deletion)
+	return
+}
+
+func (m mongoStorage) CreateExport(ctx context.Context, export user.Export) (string, error) {
+	defer metrics.ObserveStorage("CreateExport", time.Now())
+
+	result, err := m.exports.InsertOne(ctx, export)
+	if err != nil {
+		return "", fmt.Errorf("failed to insert export: %w", err)
+	}
+
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return "", fmt.Errorf("failed to insert export: invalid IntertedID")
+	}
+
+	return id.Hex(), nil
+}
+
+func (m mongoStorage) AddExportPart(ctx context.Context, id string, service string, data string) (export user.Export, err error) {
+	defer metrics.ObserveStorage("AddExportPart", time.Now())
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return
+	}
+	result := m.exports.FindOneAndUpdate(ctx, bson.M{"_id": oid, "pending": service},
+		bson.M{"$set": bson.M{"parts." + service: data}, "$pull": bson.M{"pending": service}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return export, apperror.ErrNotFound
+		}
+		err = fmt.Errorf("failed to add export part: %w", result.Err())
+		return
+	}
+	err = result.Decode(&export)
+	return
+}
+
+func (m mongoStorage) FinishExport(ctx context.Context, id string) (user.Export, error) {
+	defer metrics.ObserveStorage("FinishExport", time.Now())
+
+	return m.deleteExport(ctx, id, bson.M{"pending": bson.M{"$size": 0}})
+}
+
+func (m mongoStorage) DeleteExport(ctx context.Context, id string) (user.Export, error) {
+	defer metrics.ObserveStorage("DeleteExport", time.Now())
+
+	return m.deleteExport(ctx, id, bson.M{})
+}
+
+// deleteExport deletes export matching filter, so only one of concurrent callers gets it
+func (m mongoStorage) deleteExport(ctx context.Context, id string, filter bson.M) (export user.Export, err error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return
+	}
+	filter["_id"] = oid
+	result := m.exports.FindOneAndDelete(ctx, filter)
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return export, apperror.ErrNotFound
+		}
+		err = fmt.Errorf("failed to delete export: %w", result.Err())
+		return
+	}
+	err = result.Decode(&export)
+	return
+}
+
+func (m mongoStorage) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, nil)
+}
@@ -93,4 +93,57 @@ func TestStorage(t *testing.T) {
 		_, err := s.FindByEmail(ctx, "notfound@test.com")
 		assert.EqualError(t, err, errors.ErrNotFound.Error())
 	})
+	t.Run("create user duplicate email", func(t *testing.T) {
+		u := user.User{
+			Email:        "test3@test.com",
+			Password:     "",
+			RegisteredAt: time.Now(),
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, err := s.Create(ctx, u)
+		require.NoError(t, err)
+
+		u.Email = "Test3@Test.com"
+		_, err = s.Create(ctx, u)
+		assert.ErrorIs(t, err, errors.ErrDuplicate)
+	})
+	t.Run("count failed logins", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		attempts, err := s.AddFailedLogin(ctx, "account:test4@test.com", now, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempts.Failures)
+
+		attempts, err = s.AddFailedLogin(ctx, "account:test4@test.com", now, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 2, attempts.Failures)
+
+		err = s.LockLogin(ctx, "account:test4@test.com", now.Add(time.Hour))
+		require.NoError(t, err)
+		attempts, err = s.FindLoginAttempts(ctx, "account:test4@test.com")
+		require.NoError(t, err)
+		assert.True(t, attempts.LockedUntil.After(now))
+
+		err = s.ResetLoginAttempts(ctx, "account:test4@test.com")
+		require.NoError(t, err)
+		_, err = s.FindLoginAttempts(ctx, "account:test4@test.com")
+		assert.ErrorIs(t, err, errors.ErrNotFound)
+	})
+	t.Run("failed logins start over after expiration", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		past := time.Now().Add(-time.Hour)
+		_, err := s.AddFailedLogin(ctx, "client:test5", past, past.Add(time.Minute))
+		require.NoError(t, err)
+
+		now := time.Now()
+		attempts, err := s.AddFailedLogin(ctx, "client:test5", now, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempts.Failures)
+	})
 }
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/tony-spark/recipetor-backend/user-service/internal/user"
 )
@@ -10,4 +11,40 @@ type Storage interface {
 	Create(ctx context.Context, user user.User) (string, error)
 	FindByID(ctx context.Context, id string) (user.User, error)
 	FindByEmail(ctx context.Context, email string) (user.User, error)
+	UpdatePassword(ctx context.Context, id string, password string) error
+	SetVerified(ctx context.Context, id string) error
+	SetRole(ctx context.Context, id string, role string) error
+	FindByHandle(ctx context.Context, handle string) (user.User, error)
+	// UpdateProfile replaces profile of user, ErrDuplicate is returned if handle is taken
+	UpdateProfile(ctx context.Context, id string, profile user.Profile) error
+	Delete(ctx context.Context, id string) error
+	// DeleteUnverified deletes users registered before given time who have not verified email
+	DeleteUnverified(ctx context.Context, registeredBefore time.Time) (int64, error)
+	CreateToken(ctx context.Context, token user.Token) error
+	// FindToken returns token with given hash and purpose, if it has not expired by now
+	FindToken(ctx context.Context, hash string, purpose string, now time.Time) (user.Token, error)
+	// ConsumeToken deletes token with given hash and purpose and returns it, if it has not expired by now
+	ConsumeToken(ctx context.Context, hash string, purpose string, now time.Time) (user.Token, error)
+	DeleteTokens(ctx context.Context, userID string, purpose string) error
+	FindLoginAttempts(ctx context.Context, key string) (user.LoginAttempts, error)
+	// AddFailedLogin counts failed login at time at, starting over if attempts expired
+	AddFailedLogin(ctx context.Context, key string, at time.Time, expiresAt time.Time) (user.LoginAttempts, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+	CreateDeletion(ctx context.Context, deletion user.Deletion) error
+	// ConfirmDeletion removes service from pending services of deletion and returns updated deletion
+	ConfirmDeletion(ctx context.Context, userID string, service string) (user.Deletion, error)
+	FailDeletion(ctx context.Context, userID string, service string, reason string) error
+	// FinishDeletion marks deletion without pending services completed and returns it as it was before,
+	// ErrNotFound is returned if it is already completed
+	FinishDeletion(ctx context.Context, userID string, at time.Time) (user.Deletion, error)
+	CreateExport(ctx context.Context, export user.Export) (string, error)
+	// AddExportPart saves data of service and removes it from pending services, updated export is returned
+	AddExportPart(ctx context.Context, id string, service string, data string) (user.Export, error)
+	// FinishExport deletes export without pending services and returns it, ErrNotFound is returned
+	// if it has pending services or is finished already
+	FinishExport(ctx context.Context, id string) (user.Export, error)
+	// DeleteExport deletes export and returns it, ErrNotFound is returned if it is finished already
+	DeleteExport(ctx context.Context, id string) (user.Export, error)
+	Ping(ctx context.Context) error
 }